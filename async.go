@@ -0,0 +1,98 @@
+package remote
+
+import (
+	"context"
+)
+
+// Future is the handle for a transaction that is being sent in the background
+// by SendAsync.
+type Future struct {
+	transaction *Transaction
+	cancel      context.CancelFunc
+	done        chan struct{}
+	err         error
+}
+
+// SendAsync sends the transaction in the background, and returns immediately.
+// Use the returned Future to wait for the result, or to cancel the request.
+// The transaction's Result and Error targets are populated before the Future
+// completes. Do not use or modify the transaction until then.
+func (t *Transaction) SendAsync() *Future {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	future := &Future{
+		transaction: t,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	go func() {
+		defer close(future.done)
+		defer cancel()
+		future.err = t.sendWithin(ctx)
+	}()
+
+	return future
+}
+
+// Wait blocks until the transaction is complete, and returns the same error (or
+// nil) that Send would have returned.
+func (future *Future) Wait() error {
+	<-future.done
+	return future.err
+}
+
+// Done returns a channel that is closed when the transaction is complete.
+func (future *Future) Done() <-chan struct{} {
+	return future.done
+}
+
+// Cancel stops the transaction, if it is still in progress. Wait then returns
+// the resulting error. Cancelling a completed transaction has no effect.
+func (future *Future) Cancel() {
+	future.cancel()
+}
+
+// Transaction returns the transaction being sent. Its response is available
+// once the Future is complete.
+func (future *Future) Transaction() *Transaction {
+	return future.transaction
+}
+
+// sendWithin sends the transaction, and cancels it if ctx is cancelled. The
+// transaction's own context (from WithContext), if any, still applies, and is
+// restored afterward.
+func (t *Transaction) sendWithin(ctx context.Context) error {
+
+	original := t.ctx
+	parent := original
+
+	// Without a context of its own, the transaction keeps its usual time limit:
+	// Timeouts.Total if it is set (which requestContext applies), and the
+	// default otherwise.
+	if parent == nil {
+
+		parent = ctx
+
+		if t.timeouts.Total <= 0 {
+			var cancelTimeout context.CancelFunc
+			parent, cancelTimeout = context.WithTimeout(ctx, defaultRequestTimeout)
+			defer cancelTimeout()
+		}
+	}
+
+	requestCtx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	stop := context.AfterFunc(ctx, func() {
+		cancel(context.Cause(ctx))
+	})
+
+	defer stop()
+
+	t.ctx = requestCtx
+	defer func() { t.ctx = original }()
+
+	return t.Send()
+}
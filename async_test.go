@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendAsync(t *testing.T) {
+
+	ts := jsonServer(200, `{"name":"Sarah"}`)
+	defer ts.Close()
+
+	result := map[string]any{}
+	future := Get(ts.URL).AllowPrivateIPs(true).Result(&result).SendAsync()
+
+	select {
+	case <-future.Done():
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "future did not complete")
+	}
+
+	require.NoError(t, future.Wait())
+	require.Equal(t, "Sarah", result["name"])
+	require.Equal(t, http.StatusOK, future.Transaction().ResponseStatusCode())
+	require.Nil(t, future.Transaction().ctx)
+}
+
+func TestSendAsync_Error(t *testing.T) {
+
+	ts := jsonServer(404, `{"error":"not found"}`)
+	defer ts.Close()
+
+	failure := map[string]any{}
+	future := Get(ts.URL).AllowPrivateIPs(true).Error(&failure).SendAsync()
+
+	require.Error(t, future.Wait())
+	require.Equal(t, "not found", failure["error"])
+}
+
+func TestSendAsync_Several(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	start := time.Now()
+	futures := []*Future{}
+
+	for range 5 {
+		futures = append(futures, Get(ts.URL).AllowPrivateIPs(true).SendAsync())
+	}
+
+	for _, future := range futures {
+		require.NoError(t, future.Wait())
+	}
+
+	// The requests ran at the same time, not one after another.
+	require.Less(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestSendAsync_Cancel(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	future := Get(ts.URL).AllowPrivateIPs(true).SendAsync()
+	future.Cancel()
+
+	err := future.Wait()
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Cancelling a completed future has no effect
+	future.Cancel()
+	require.ErrorIs(t, future.Wait(), context.Canceled)
+}
+
+func TestSendAsync_TransactionContext(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	future := Get(ts.URL).AllowPrivateIPs(true).WithContext(ctx).SendAsync()
+	require.ErrorIs(t, future.Wait(), context.DeadlineExceeded)
+	require.Equal(t, ctx, future.Transaction().ctx)
+}
+
+func TestSendAsync_TotalTimeout(t *testing.T) {
+
+	// deadlineOption records the deadline of the request's context
+	deadlineOption := func(deadlines chan<- time.Time) Option {
+		return handlerOption(func(_ http.ResponseWriter, r *http.Request) {
+			deadline, _ := r.Context().Deadline()
+			deadlines <- deadline
+		})
+	}
+
+	// A Total longer than the default is not cut short...
+	deadlines := make(chan time.Time, 3)
+	transaction := Get("https://example.com/").Timeouts(Timeouts{Total: 5 * time.Minute}).With(deadlineOption(deadlines))
+
+	require.NoError(t, transaction.SendAsync().Wait())
+	require.NoError(t, SendAll(context.Background(), []*Transaction{transaction})[0])
+
+	for _, err := range SendEach(context.Background(), []*Transaction{transaction}) {
+		require.NoError(t, err)
+	}
+
+	for range 3 {
+		require.WithinDuration(t, time.Now().Add(5*time.Minute), <-deadlines, 5*time.Second)
+	}
+
+	// ...and without a Total, the default still applies
+	require.NoError(t, Get("https://example.com/").With(deadlineOption(deadlines)).SendAsync().Wait())
+	require.WithinDuration(t, time.Now().Add(defaultRequestTimeout), <-deadlines, 5*time.Second)
+}
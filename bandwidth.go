@@ -0,0 +1,236 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bandwidthIdleBuckets is the number of per-host buckets that a limiter keeps
+// before it removes the buckets of hosts that are no longer in use.
+const bandwidthIdleBuckets = 1024
+
+// Bandwidth caps how fast a transaction sends its request body and reads its
+// response body. Each direction uses a BandwidthLimiter, which may be shared
+// with other transactions so that they divide a single limit between them.
+type Bandwidth struct {
+	Read  *BandwidthLimiter // (if set) limits how fast the response body is read
+	Write *BandwidthLimiter // (if set) limits how fast the request body is sent
+}
+
+// Bandwidth caps the throughput of this transaction's request and response
+// bodies. Clones share the same limiters, and so share their limits.
+func (t *Transaction) Bandwidth(bandwidth Bandwidth) *Transaction {
+	t.bandwidth = bandwidth
+	return t
+}
+
+// BandwidthLimiter limits throughput to a number of bytes per second. A single
+// limiter can be used by many transactions at once: a limiter from
+// NewBandwidthLimiter divides its limit between all of them, and a limiter
+// from NewHostBandwidthLimiter divides it between the transactions to each host.
+type BandwidthLimiter struct {
+	bytesPerSecond int64
+	perHost        bool
+	mutex          sync.Mutex
+	buckets        map[string]*tokenBucket
+}
+
+// NewBandwidthLimiter returns a limiter that allows bytesPerSecond in total,
+// across every transaction that uses it. To limit a single transaction, give
+// it a limiter of its own. A limit of zero or less allows any throughput.
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		bytesPerSecond: bytesPerSecond,
+		buckets:        map[string]*tokenBucket{},
+	}
+}
+
+// NewHostBandwidthLimiter returns a limiter that allows bytesPerSecond to each
+// host, divided between the transactions to that host. A limit of zero or less
+// allows any throughput.
+func NewHostBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	result := NewBandwidthLimiter(bytesPerSecond)
+	result.perHost = true
+	return result
+}
+
+// bucket returns the token bucket for a host, or nil if there is no limit.
+func (limiter *BandwidthLimiter) bucket(host string) *tokenBucket {
+
+	if (limiter == nil) || (limiter.bytesPerSecond <= 0) {
+		return nil
+	}
+
+	if limiter.perHost {
+		host = strings.ToLower(host)
+	} else {
+		host = ""
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if bucket, exists := limiter.buckets[host]; exists {
+		return bucket
+	}
+
+	// Buckets that have been idle long enough to refill are the same as new
+	// ones, so they can be removed without changing the limit.
+	if len(limiter.buckets) >= bandwidthIdleBuckets {
+		for name, bucket := range limiter.buckets {
+			if bucket.isIdle() {
+				delete(limiter.buckets, name)
+			}
+		}
+	}
+
+	result := newTokenBucket(limiter.bytesPerSecond)
+	limiter.buckets[host] = result
+	return result
+}
+
+// throttleRequest wraps the request body so that it is sent no faster than the Write limit.
+func (t *Transaction) throttleRequest(request *http.Request) {
+
+	bucket := t.bandwidth.Write.bucket(request.URL.Hostname())
+
+	if (bucket == nil) || (request.Body == nil) || (request.Body == http.NoBody) {
+		return
+	}
+
+	ctx := request.Context()
+	request.Body = &throttledReader{ReadCloser: request.Body, ctx: ctx, bucket: bucket}
+
+	// Redirects that resend the body are throttled, too
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+
+			body, err := getBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			return &throttledReader{ReadCloser: body, ctx: ctx, bucket: bucket}, nil
+		}
+	}
+}
+
+// throttleResponse wraps the response body so that it is read no faster than the Read limit.
+func (t *Transaction) throttleResponse(response *http.Response) {
+
+	if (response.Body == nil) || (response.Body == http.NoBody) || (t.request == nil) {
+		return
+	}
+
+	// After a redirect, the body comes from the final host, not the original one
+	request := t.request
+
+	if response.Request != nil {
+		request = response.Request
+	}
+
+	bucket := t.bandwidth.Read.bucket(request.URL.Hostname())
+
+	if bucket == nil {
+		return
+	}
+
+	response.Body = &throttledReader{ReadCloser: response.Body, ctx: t.request.Context(), bucket: bucket}
+}
+
+/******************************************
+ * Token Bucket
+ ******************************************/
+
+// tokenBucket allows a number of bytes per second, plus a small burst. Each
+// read takes its bytes from the bucket, which may go into debt; the reader
+// then waits until the debt is repaid.
+type tokenBucket struct {
+	rate   float64 // bytes per second
+	burst  float64 // most bytes that can be saved up while idle
+	chunk  int     // most bytes to read at once, so that waits stay short
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(bytesPerSecond int64) *tokenBucket {
+
+	// Allow a tenth of a second's worth of bytes at once
+	burst := max(bytesPerSecond/10, 1)
+
+	return &tokenBucket{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(burst),
+		chunk:  int(min(burst, 1<<20)),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take removes count bytes from the bucket, and returns how long to wait
+// before they are allowed.
+func (bucket *tokenBucket) take(count int) time.Duration {
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	now := time.Now()
+	bucket.tokens = min(bucket.tokens+(now.Sub(bucket.last).Seconds()*bucket.rate), bucket.burst)
+	bucket.last = now
+	bucket.tokens -= float64(count)
+
+	if bucket.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+}
+
+// isIdle returns TRUE if the bucket has refilled since it was last used.
+func (bucket *tokenBucket) isIdle() bool {
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	refill := (bucket.burst - bucket.tokens) / bucket.rate
+	return time.Since(bucket.last).Seconds() > refill
+}
+
+// throttledReader waits after each read until its bytes are allowed by the bucket.
+type throttledReader struct {
+	io.ReadCloser
+	ctx    context.Context // NOSONAR(S8242): cancels waits along with the request
+	bucket *tokenBucket
+}
+
+// Read reads no more than a chunk from the underlying body, then waits for the bucket.
+func (reader *throttledReader) Read(p []byte) (int, error) {
+
+	if len(p) > reader.bucket.chunk {
+		p = p[:reader.bucket.chunk]
+	}
+
+	count, err := reader.ReadCloser.Read(p)
+
+	if count > 0 {
+		if wait := reader.bucket.take(count); wait > 0 {
+
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-reader.ctx.Done():
+				return count, context.Cause(reader.ctx)
+			}
+		}
+	}
+
+	return count, err
+}
@@ -0,0 +1,207 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// bandwidthOption returns a response body of the given size, and reads any request body.
+func bandwidthOption(size int) Option {
+	return handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			_, _ = io.Copy(io.Discard, r.Body)
+		}
+		_, _ = w.Write(bytes.Repeat([]byte("x"), size))
+	})
+}
+
+func TestBandwidth_Read(t *testing.T) {
+
+	// 40KB at 100KB/s, after a 10KB burst, takes at least 300ms
+	start := time.Now()
+	body := []byte{}
+	require.NoError(t, Get("https://example.com/").With(bandwidthOption(40_000)).Bandwidth(Bandwidth{Read: NewBandwidthLimiter(100_000)}).Result(&body).Send())
+
+	require.Len(t, body, 40_000)
+	require.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestBandwidth_Write(t *testing.T) {
+
+	start := time.Now()
+	require.NoError(t, Post("https://example.com/").Body(string(bytes.Repeat([]byte("x"), 40_000))).With(bandwidthOption(0)).Bandwidth(Bandwidth{Write: NewBandwidthLimiter(100_000)}).Send())
+	require.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestBandwidth_Shared(t *testing.T) {
+
+	// Two transactions share 100KB/s, so 2x20KB takes as long as 40KB
+	limiter := NewBandwidthLimiter(100_000)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+
+	for range 2 {
+		wg.Go(func() {
+			body := []byte{}
+			require.NoError(t, Get("https://example.com/").With(bandwidthOption(20_000)).Bandwidth(Bandwidth{Read: limiter}).Result(&body).Send())
+			require.Len(t, body, 20_000)
+		})
+	}
+
+	wg.Wait()
+	require.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestBandwidth_PerHost(t *testing.T) {
+
+	limiter := NewHostBandwidthLimiter(100_000)
+
+	// Each host has a bucket of its own
+	require.Same(t, limiter.bucket("example.com"), limiter.bucket("EXAMPLE.com"))
+	require.NotSame(t, limiter.bucket("example.com"), limiter.bucket("example.org"))
+
+	// ...while other limiters share a single bucket
+	global := NewBandwidthLimiter(100_000)
+	require.Same(t, global.bucket("example.com"), global.bucket("example.org"))
+
+	// Transactions to the same host share its limit
+	start := time.Now()
+	var wg sync.WaitGroup
+
+	for range 2 {
+		wg.Go(func() {
+			require.NoError(t, Get("https://example.com/").With(bandwidthOption(20_000)).Bandwidth(Bandwidth{Read: limiter}).Send())
+		})
+	}
+
+	wg.Wait()
+	require.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestBandwidth_Unlimited(t *testing.T) {
+
+	require.Nil(t, NewBandwidthLimiter(0).bucket("example.com"))
+	require.Nil(t, NewHostBandwidthLimiter(-1).bucket("example.com"))
+	require.Nil(t, (*BandwidthLimiter)(nil).bucket("example.com"))
+
+	body := []byte{}
+	require.NoError(t, Get("https://example.com/").With(bandwidthOption(1000)).Bandwidth(Bandwidth{Read: NewBandwidthLimiter(0)}).Result(&body).Send())
+	require.Len(t, body, 1000)
+}
+
+func TestBandwidth_Cancel(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// 1MB at 10KB/s would take almost two minutes
+	start := time.Now()
+	err := Get("https://example.com/").With(bandwidthOption(1 << 20)).Bandwidth(Bandwidth{Read: NewBandwidthLimiter(10_000)}).WithContext(ctx).Send()
+
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestBandwidth_Network(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.Copy(io.Discard, r.Body)
+		_, _ = fmt.Fprint(w, received)
+	}))
+	defer server.Close()
+
+	limiter := NewBandwidthLimiter(200_000)
+	start := time.Now()
+	body := ""
+
+	transaction := Post(server.URL).
+		AllowPrivateIPs(true).
+		Body(string(bytes.Repeat([]byte("x"), 100_000))).
+		Bandwidth(Bandwidth{Read: limiter, Write: limiter}).
+		Result(&body)
+
+	require.NoError(t, transaction.Send())
+	require.Equal(t, "100000", body)
+	require.GreaterOrEqual(t, time.Since(start), 350*time.Millisecond)
+}
+
+func TestBandwidth_Redirect(t *testing.T) {
+
+	final := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 1000))
+	}))
+	defer final.Close()
+
+	// The first server is "127.0.0.1", and it redirects to "localhost"
+	finalURL := strings.Replace(final.URL, "127.0.0.1", "localhost", 1)
+
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, finalURL, http.StatusFound)
+	}))
+	defer first.Close()
+
+	limiter := NewHostBandwidthLimiter(100_000)
+	body := []byte{}
+	require.NoError(t, Get(first.URL).AllowPrivateIPs(true).Bandwidth(Bandwidth{Read: limiter}).Result(&body).Send())
+	require.Len(t, body, 1000)
+
+	// The response body is charged to the host that sent it
+	require.Contains(t, limiter.buckets, "localhost")
+	require.NotContains(t, limiter.buckets, "127.0.0.1")
+}
+
+func TestBandwidth_Clone(t *testing.T) {
+
+	limiter := NewBandwidthLimiter(1000)
+	clone := Get("https://example.com/").Bandwidth(Bandwidth{Read: limiter}).Clone()
+	require.Same(t, limiter, clone.bandwidth.Read)
+}
+
+func TestTokenBucket(t *testing.T) {
+
+	bucket := newTokenBucket(1000)
+	require.Equal(t, 100, bucket.chunk)
+
+	// The burst is allowed at once...
+	require.Zero(t, bucket.take(100))
+
+	// ...and then bytes must wait for the bucket to refill
+	wait := bucket.take(100)
+	require.Greater(t, wait, 90*time.Millisecond)
+	require.LessOrEqual(t, wait, 100*time.Millisecond)
+	require.False(t, bucket.isIdle())
+
+	// Very low limits still allow a byte at a time
+	require.Equal(t, 1, newTokenBucket(5).chunk)
+
+	// Very high limits read at most 1MB at a time
+	require.Equal(t, 1<<20, newTokenBucket(1<<40).chunk)
+}
+
+func TestBandwidthLimiter_RemovesIdleBuckets(t *testing.T) {
+
+	limiter := NewHostBandwidthLimiter(1000)
+
+	for index := range bandwidthIdleBuckets {
+		limiter.bucket(fmt.Sprintf("host%d.example.com", index))
+	}
+
+	// A host that is in use keeps its bucket
+	busy := limiter.bucket("host0.example.com")
+	busy.take(500)
+
+	limiter.bucket("new.example.com")
+	require.Len(t, limiter.buckets, 2)
+	require.Same(t, busy, limiter.bucket("host0.example.com"))
+}
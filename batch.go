@@ -0,0 +1,257 @@
+package remote
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/benpate/derp"
+)
+
+// defaultBatchConcurrency is the number of transactions that SendAll sends at
+// the same time, unless the Concurrency option is used.
+const defaultBatchConcurrency = 10
+
+// BatchOption configures how SendAll and SendEach schedule their transactions.
+type BatchOption func(*batchConfig)
+
+// batchConfig holds the settings for a batch of transactions.
+type batchConfig struct {
+	concurrency     int  // maximum number of transactions in flight
+	hostConcurrency int  // (if set) maximum number of transactions in flight to each host
+	failFast        bool // if TRUE, the first error cancels the rest of the batch
+}
+
+// Concurrency sets the maximum number of transactions that are sent at the
+// same time. The default is 10.
+func Concurrency(n int) BatchOption {
+	return func(config *batchConfig) {
+		if n > 0 {
+			config.concurrency = n
+		}
+	}
+}
+
+// HostConcurrency sets the maximum number of transactions that are sent to
+// each host at the same time, so a large batch does not overwhelm any single
+// server. Transactions for other hosts continue to be sent while one host is
+// at its limit. The default is no per-host limit.
+func HostConcurrency(n int) BatchOption {
+	return func(config *batchConfig) {
+		config.hostConcurrency = max(n, 0)
+	}
+}
+
+// FailFast stops the batch at the first error: transactions in flight are
+// cancelled, and transactions that have not started are never sent. By
+// default, every transaction is sent and every error is collected.
+func FailFast() BatchOption {
+	return func(config *batchConfig) {
+		config.failFast = true
+	}
+}
+
+// SendAll sends a batch of transactions concurrently, and returns the error
+// (or nil) for each one, in the same order as the transactions. Each
+// transaction's Result and Error targets are populated as usual. Cancelling
+// ctx stops scheduling new transactions and cancels those in flight;
+// transactions that were never sent report the cancellation as their error.
+func SendAll(ctx context.Context, transactions []*Transaction, options ...BatchOption) []error {
+
+	result := make([]error, len(transactions))
+
+	for index, err := range SendEach(ctx, transactions, options...) {
+		result[index] = err
+	}
+
+	return result
+}
+
+// SendEach sends a batch of transactions concurrently, like SendAll, and yields
+// the index and error (or nil) of each transaction as it completes, in the
+// order that they complete. Breaking out of the loop cancels the rest of the batch.
+func SendEach(ctx context.Context, transactions []*Transaction, options ...BatchOption) iter.Seq2[int, error] {
+
+	return func(yield func(int, error) bool) {
+
+		config := batchConfig{concurrency: defaultBatchConcurrency}
+
+		for _, option := range options {
+			option(&config)
+		}
+
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		results := make(chan batchResult, len(transactions))
+		go newBatch(ctx, cancel, config, transactions).run(results)
+
+		for result := range results {
+			if !yield(result.index, result.err) {
+				cancel(context.Canceled)
+
+				// Wait for transactions in flight to finish before returning.
+				for range results {
+				}
+
+				return
+			}
+		}
+	}
+}
+
+// batchResult is the outcome of a single transaction in a batch.
+type batchResult struct {
+	index int
+	err   error
+}
+
+// batch schedules the transactions in a SendAll/SendEach call.
+type batch struct {
+	ctx          context.Context
+	cancel       context.CancelCauseFunc
+	config       batchConfig
+	transactions []*Transaction
+	hosts        []string // host of each transaction, for per-host limits
+
+	mutex       sync.Mutex
+	cond        *sync.Cond
+	pending     []int          // indexes of transactions that have not started, in order
+	running     int            // number of transactions in flight
+	hostRunning map[string]int // number of transactions in flight to each host
+}
+
+// newBatch prepares a batch of transactions to be sent.
+func newBatch(ctx context.Context, cancel context.CancelCauseFunc, config batchConfig, transactions []*Transaction) *batch {
+
+	result := &batch{
+		ctx:          ctx,
+		cancel:       cancel,
+		config:       config,
+		transactions: transactions,
+		hosts:        make([]string, len(transactions)),
+		pending:      make([]int, len(transactions)),
+		hostRunning:  map[string]int{},
+	}
+
+	result.cond = sync.NewCond(&result.mutex)
+
+	for index, transaction := range transactions {
+		result.pending[index] = index
+
+		if transaction != nil {
+			if parsed, err := url.Parse(transaction.url); err == nil {
+				result.hosts[index] = strings.ToLower(parsed.Host)
+			}
+		}
+	}
+
+	return result
+}
+
+// run starts each transaction as soon as there is capacity for it, and
+// delivers every result to the results channel, which is closed at the end.
+func (b *batch) run(results chan<- batchResult) {
+
+	const location = "remote.SendAll"
+
+	var wg sync.WaitGroup
+
+	// Wake the scheduler when the batch is cancelled.
+	stop := context.AfterFunc(b.ctx, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.cond.Broadcast()
+	})
+
+	defer stop()
+
+	b.mutex.Lock()
+
+	for len(b.pending) > 0 {
+
+		// Once cancelled, transactions that have not started are never sent.
+		if b.ctx.Err() != nil {
+			cause := context.Cause(b.ctx)
+
+			for _, index := range b.pending {
+				results <- batchResult{index: index, err: derp.Wrap(cause, location, "Batch cancelled before transaction was sent")}
+			}
+
+			b.pending = nil
+			break
+		}
+
+		position := b.next()
+
+		if position < 0 {
+			b.cond.Wait()
+			continue
+		}
+
+		index := b.pending[position]
+		b.pending = append(b.pending[:position], b.pending[position+1:]...)
+		b.running++
+		b.hostRunning[b.hosts[index]]++
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := b.send(index)
+
+			if (err != nil) && b.config.failFast {
+				b.cancel(err)
+			}
+
+			results <- batchResult{index: index, err: err}
+
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+
+			b.running--
+			b.hostRunning[b.hosts[index]]--
+			b.cond.Broadcast()
+		}()
+	}
+
+	b.mutex.Unlock()
+
+	wg.Wait()
+	close(results)
+}
+
+// next returns the position (in b.pending) of the first transaction that can
+// start now, or -1 if every remaining transaction must wait for capacity.
+// The caller must hold b.mutex.
+func (b *batch) next() int {
+
+	if b.running >= b.config.concurrency {
+		return -1
+	}
+
+	for position, index := range b.pending {
+		if (b.config.hostConcurrency == 0) || (b.hostRunning[b.hosts[index]] < b.config.hostConcurrency) {
+			return position
+		}
+	}
+
+	return -1
+}
+
+// send sends a single transaction, which is cancelled along with the batch.
+func (b *batch) send(index int) error {
+
+	const location = "remote.SendAll"
+
+	transaction := b.transactions[index]
+
+	if transaction == nil {
+		return derp.Internal(location, "Transaction is nil", index)
+	}
+
+	return transaction.sendWithin(b.ctx)
+}
@@ -0,0 +1,278 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyServer returns a server that holds each request for a moment,
+// and tracks the largest number of requests it handled at the same time.
+// Requests to "/fail" receive a 500 error.
+func concurrencyServer(delay time.Duration) (*httptest.Server, *atomic.Int64, *atomic.Int64) {
+
+	var current atomic.Int64
+	var peak atomic.Int64
+	var total atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		total.Add(1)
+		now := current.Add(1)
+		defer current.Add(-1)
+
+		for {
+			old := peak.Load()
+			if (now <= old) || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set(ContentType, ContentTypePlain)
+		_, _ = w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+
+	return server, &peak, &total
+}
+
+func TestSendAll_Ordered(t *testing.T) {
+
+	ts, _, _ := concurrencyServer(time.Millisecond)
+	defer ts.Close()
+
+	results := make([]string, 6)
+	transactions := make([]*Transaction, 6)
+
+	for index := range transactions {
+		path := "/ok"
+		if index == 3 {
+			path = "/fail"
+		}
+		transactions[index] = Get(ts.URL+path).Query("id", strconv.Itoa(index)).AllowPrivateIPs(true).Result(&results[index])
+	}
+
+	errs := SendAll(context.Background(), transactions, Concurrency(3))
+	require.Len(t, errs, 6)
+
+	for index, err := range errs {
+		if index == 3 {
+			require.Error(t, err)
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(index), results[index])
+	}
+}
+
+func TestSendAll_Concurrency(t *testing.T) {
+
+	ts, peak, total := concurrencyServer(20 * time.Millisecond)
+	defer ts.Close()
+
+	transactions := make([]*Transaction, 12)
+
+	for index := range transactions {
+		transactions[index] = Get(ts.URL).AllowPrivateIPs(true)
+	}
+
+	for _, err := range SendAll(context.Background(), transactions, Concurrency(3)) {
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, int64(12), total.Load())
+	require.LessOrEqual(t, peak.Load(), int64(3))
+	require.Greater(t, peak.Load(), int64(1))
+}
+
+func TestSendAll_HostConcurrency(t *testing.T) {
+
+	first, firstPeak, _ := concurrencyServer(20 * time.Millisecond)
+	defer first.Close()
+
+	second, secondPeak, _ := concurrencyServer(20 * time.Millisecond)
+	defer second.Close()
+
+	// All of the first host's transactions are at the front of the queue, but the
+	// second host's transactions still run alongside them.
+	transactions := []*Transaction{}
+
+	for range 4 {
+		transactions = append(transactions, Get(first.URL).AllowPrivateIPs(true))
+	}
+
+	for range 4 {
+		transactions = append(transactions, Get(second.URL).AllowPrivateIPs(true))
+	}
+
+	start := time.Now()
+
+	for _, err := range SendAll(context.Background(), transactions, Concurrency(10), HostConcurrency(1)) {
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, int64(1), firstPeak.Load())
+	require.Equal(t, int64(1), secondPeak.Load())
+	require.Less(t, time.Since(start), 8*20*time.Millisecond)
+}
+
+func TestSendAll_FailFast(t *testing.T) {
+
+	ts, _, total := concurrencyServer(10 * time.Millisecond)
+	defer ts.Close()
+
+	transactions := []*Transaction{Get(ts.URL + "/fail").AllowPrivateIPs(true)}
+
+	for range 10 {
+		transactions = append(transactions, Get(ts.URL).AllowPrivateIPs(true))
+	}
+
+	errs := SendAll(context.Background(), transactions, Concurrency(1), FailFast())
+
+	require.Less(t, total.Load(), int64(len(transactions)))
+
+	for _, err := range errs {
+		require.Error(t, err)
+	}
+}
+
+func TestSendAll_CollectAll(t *testing.T) {
+
+	ts, _, total := concurrencyServer(time.Millisecond)
+	defer ts.Close()
+
+	transactions := []*Transaction{
+		Get(ts.URL + "/fail").AllowPrivateIPs(true),
+		Get(ts.URL).AllowPrivateIPs(true),
+		Get(ts.URL + "/fail").AllowPrivateIPs(true),
+		Get(ts.URL).AllowPrivateIPs(true),
+	}
+
+	errs := SendAll(context.Background(), transactions, Concurrency(1))
+
+	require.Equal(t, int64(4), total.Load())
+	require.Error(t, errs[0])
+	require.NoError(t, errs[1])
+	require.Error(t, errs[2])
+	require.NoError(t, errs[3])
+}
+
+func TestSendAll_Cancelled(t *testing.T) {
+
+	ts, _, total := concurrencyServer(time.Millisecond)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	transactions := []*Transaction{Get(ts.URL).AllowPrivateIPs(true), Get(ts.URL).AllowPrivateIPs(true)}
+
+	for _, err := range SendAll(ctx, transactions) {
+		require.ErrorIs(t, err, context.Canceled)
+	}
+
+	require.Zero(t, total.Load())
+}
+
+func TestSendAll_CancelStopsScheduling(t *testing.T) {
+
+	ts, _, total := concurrencyServer(20 * time.Millisecond)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	transactions := make([]*Transaction, 10)
+
+	for index := range transactions {
+		transactions[index] = Get(ts.URL).AllowPrivateIPs(true)
+	}
+
+	time.AfterFunc(30*time.Millisecond, cancel)
+
+	errs := SendAll(ctx, transactions, Concurrency(1))
+
+	require.NoError(t, errs[0])
+	require.Error(t, errs[9])
+	require.Less(t, total.Load(), int64(10))
+
+	// Each transaction's own context is restored afterward
+	for _, transaction := range transactions {
+		require.Nil(t, transaction.ctx)
+	}
+}
+
+func TestSendAll_NilTransaction(t *testing.T) {
+
+	errs := SendAll(context.Background(), []*Transaction{nil})
+	require.Error(t, errs[0])
+}
+
+func TestSendEach(t *testing.T) {
+
+	ts, _, _ := concurrencyServer(time.Millisecond)
+	defer ts.Close()
+
+	transactions := make([]*Transaction, 5)
+
+	for index := range transactions {
+		transactions[index] = Get(ts.URL).AllowPrivateIPs(true)
+	}
+
+	seen := map[int]bool{}
+
+	for index, err := range SendEach(context.Background(), transactions, Concurrency(2)) {
+		require.NoError(t, err)
+		seen[index] = true
+	}
+
+	require.Len(t, seen, 5)
+}
+
+func TestSendEach_Break(t *testing.T) {
+
+	ts, _, total := concurrencyServer(10 * time.Millisecond)
+	defer ts.Close()
+
+	transactions := make([]*Transaction, 20)
+
+	for index := range transactions {
+		transactions[index] = Get(ts.URL).AllowPrivateIPs(true)
+	}
+
+	for range SendEach(context.Background(), transactions, Concurrency(2)) {
+		break
+	}
+
+	require.Less(t, total.Load(), int64(20))
+}
+
+func TestSendAll_TransactionContext(t *testing.T) {
+
+	ts, _, _ := concurrencyServer(50 * time.Millisecond)
+	defer ts.Close()
+
+	// A transaction's own (shorter) deadline still applies within the batch.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	errs := SendAll(context.Background(), []*Transaction{Get(ts.URL).AllowPrivateIPs(true).WithContext(ctx)})
+	require.True(t, errors.Is(errs[0], context.DeadlineExceeded))
+}
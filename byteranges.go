@@ -0,0 +1,149 @@
+package remote
+
+import (
+	"io"
+	"iter"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+// ByteRange is one range of bytes in a 206 Partial Content response.
+type ByteRange struct {
+	Start       int64     // position of the first byte in the range
+	End         int64     // position of the last byte in the range (inclusive)
+	Total       int64     // complete length of the file (-1 if unknown)
+	ContentType string    // content type of the file, if the server sent one
+	Body        io.Reader // contents of the range, which is limited to its length
+}
+
+// Length returns the number of bytes in the range.
+func (byteRange ByteRange) Length() int64 {
+	return byteRange.End - byteRange.Start + 1
+}
+
+// ByteRanges yields each range of bytes in a 206 Partial Content response. A
+// single range is described by the response's Content-Range header. Several
+// ranges arrive as a multipart/byteranges body, which servers may also send
+// when they combine (coalesce) the ranges that were requested. Each range
+// must be read before the next one is yielded.
+func ByteRanges(response *http.Response) iter.Seq2[ByteRange, error] {
+
+	const location = "remote.ByteRanges"
+
+	return func(yield func(ByteRange, error) bool) {
+
+		if (response == nil) || (response.StatusCode != http.StatusPartialContent) {
+			yield(ByteRange{}, derp.BadRequest(location, "Response is not 206 Partial Content"))
+			return
+		}
+
+		contentType := response.Header.Get(ContentType)
+		mediaType, params, err := mime.ParseMediaType(contentType)
+
+		// A single range
+		if (err != nil) || (mediaType != ContentTypeMultipartByteRanges) {
+			yield(newByteRange(response.Header.Get("Content-Range"), contentType, response.Body))
+			return
+		}
+
+		// Several ranges in a multipart body
+		if params["boundary"] == "" {
+			yield(ByteRange{}, derp.BadRequest(location, "Multipart response has no boundary", contentType))
+			return
+		}
+
+		reader := multipart.NewReader(response.Body, params["boundary"])
+
+		for {
+
+			part, err := reader.NextPart()
+
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				yield(ByteRange{}, derp.Wrap(err, location, "Unable to read multipart response"))
+				return
+			}
+
+			byteRange, err := newByteRange(part.Header.Get("Content-Range"), part.Header.Get(ContentType), part)
+
+			if !yield(byteRange, err) || (err != nil) {
+				return
+			}
+		}
+	}
+}
+
+// newByteRange returns a ByteRange described by a Content-Range header.
+func newByteRange(contentRange string, contentType string, body io.Reader) (ByteRange, error) {
+
+	const location = "remote.newByteRange"
+
+	start, end, total, isValid := parseContentRange(contentRange)
+
+	if !isValid {
+		return ByteRange{}, derp.BadRequest(location, "Invalid Content-Range", contentRange)
+	}
+
+	return ByteRange{
+		Start:       start,
+		End:         end,
+		Total:       total,
+		ContentType: contentType,
+		Body:        io.LimitReader(body, end-start+1),
+	}, nil
+}
+
+// parseContentRange parses a Content-Range header, such as "bytes 100-199/1000",
+// returning the first and last byte positions and the complete length (-1 if unknown).
+func parseContentRange(value string) (int64, int64, int64, bool) {
+
+	value, found := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	byteRange, length, found := strings.Cut(value, "/")
+
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	first, last, found := strings.Cut(byteRange, "-")
+
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+
+	if (err != nil) || (start < 0) {
+		return 0, 0, 0, false
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+
+	if (err != nil) || (end < start) {
+		return 0, 0, 0, false
+	}
+
+	if length == "*" {
+		return start, end, -1, true
+	}
+
+	total, err := strconv.ParseInt(length, 10, 64)
+
+	if (err != nil) || (total <= end) {
+		return 0, 0, 0, false
+	}
+
+	return start, end, total, true
+}
@@ -0,0 +1,141 @@
+package remote
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// byteRangesForTest serves content for a Range header, and returns each range in the response.
+func byteRangesForTest(t *testing.T, content string, rangeHeader string) []ByteRange {
+
+	request := httptest.NewRequest(http.MethodGet, "https://example.com/file.txt", nil)
+	request.Header.Set("Range", rangeHeader)
+
+	recorder := httptest.NewRecorder()
+	http.ServeContent(recorder, request, "file.txt", time.Time{}, strings.NewReader(content))
+
+	result := []ByteRange{}
+
+	for byteRange, err := range ByteRanges(recorder.Result()) {
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(byteRange.Body)
+		require.NoError(t, err)
+
+		byteRange.Body = bytes.NewReader(body)
+		result = append(result, byteRange)
+	}
+
+	return result
+}
+
+func TestByteRanges_Single(t *testing.T) {
+
+	ranges := byteRangesForTest(t, "Hello, World!", "bytes=7-11")
+	require.Len(t, ranges, 1)
+
+	require.Equal(t, int64(7), ranges[0].Start)
+	require.Equal(t, int64(11), ranges[0].End)
+	require.Equal(t, int64(13), ranges[0].Total)
+	require.Equal(t, int64(5), ranges[0].Length())
+
+	body, err := io.ReadAll(ranges[0].Body)
+	require.NoError(t, err)
+	require.Equal(t, "World", string(body))
+}
+
+func TestByteRanges_Multipart(t *testing.T) {
+
+	content := strings.Repeat("abcdefghij", 100)
+	ranges := byteRangesForTest(t, content, "bytes=0-9, 500-509, 990-")
+	require.Len(t, ranges, 3)
+
+	expected := [][2]int64{{0, 9}, {500, 509}, {990, 999}}
+
+	for index, byteRange := range ranges {
+		require.Equal(t, expected[index][0], byteRange.Start)
+		require.Equal(t, expected[index][1], byteRange.End)
+		require.Equal(t, int64(1000), byteRange.Total)
+		require.Contains(t, byteRange.ContentType, ContentTypePlain)
+
+		body, err := io.ReadAll(byteRange.Body)
+		require.NoError(t, err)
+		require.Equal(t, content[byteRange.Start:byteRange.End+1], string(body))
+	}
+}
+
+func TestByteRanges_Invalid(t *testing.T) {
+
+	responses := []*http.Response{
+		nil,
+		{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody},
+		{StatusCode: http.StatusPartialContent, Header: http.Header{}, Body: http.NoBody},
+		{StatusCode: http.StatusPartialContent, Header: http.Header{"Content-Range": {"bytes 5-1/10"}}, Body: http.NoBody},
+		{StatusCode: http.StatusPartialContent, Header: http.Header{"Content-Type": {"multipart/byteranges"}}, Body: http.NoBody},
+		{StatusCode: http.StatusPartialContent, Header: http.Header{"Content-Type": {"multipart/byteranges; boundary=XYZ"}}, Body: io.NopCloser(strings.NewReader("--XYZ\r\nContent-Type: text/plain\r\n\r\nno range\r\n--XYZ--\r\n"))},
+	}
+
+	for index, response := range responses {
+
+		errors := 0
+
+		for _, err := range ByteRanges(response) {
+			require.Error(t, err, index)
+			errors++
+		}
+
+		require.Equal(t, 1, errors, index)
+	}
+}
+
+func TestByteRanges_LimitsBody(t *testing.T) {
+
+	response := &http.Response{
+		StatusCode: http.StatusPartialContent,
+		Header:     http.Header{"Content-Range": {"bytes 0-4/100"}},
+		Body:       io.NopCloser(strings.NewReader("Hello, World!")),
+	}
+
+	for byteRange, err := range ByteRanges(response) {
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(byteRange.Body)
+		require.NoError(t, err)
+		require.Equal(t, "Hello", string(body))
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+
+	tests := []struct {
+		value string
+		start int64
+		end   int64
+		total int64
+		valid bool
+	}{
+		{"bytes 0-99/1000", 0, 99, 1000, true},
+		{"bytes 100-999/1000", 100, 999, 1000, true},
+		{"bytes 100-199/*", 100, 199, -1, true},
+		{"bytes 100-1000/1000", 0, 0, 0, false},
+		{"bytes 200-100/1000", 0, 0, 0, false},
+		{"bytes */1000", 0, 0, 0, false},
+		{"items 0-9/10", 0, 0, 0, false},
+		{"", 0, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		start, end, total, valid := parseContentRange(test.value)
+		require.Equal(t, test.valid, valid, test.value)
+		require.Equal(t, test.start, start, test.value)
+		require.Equal(t, test.end, end, test.value)
+		require.Equal(t, test.total, total, test.value)
+	}
+}
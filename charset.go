@@ -0,0 +1,193 @@
+package remote
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/benpate/derp"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// charsetUTF8 is the canonical name of the UTF-8 character encoding.
+const charsetUTF8 = "utf-8"
+
+// charsetSniffSize is how much of a document is searched for an XML
+// declaration or HTML <meta> charset, following the HTML specification.
+const charsetSniffSize = 1024
+
+// charsetBOMs are the byte order marks that identify a document's encoding.
+var charsetBOMs = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte{0xef, 0xbb, 0xbf}, "utf-8"},
+	{[]byte{0xfe, 0xff}, "utf-16be"},
+	{[]byte{0xff, 0xfe}, "utf-16le"},
+}
+
+// charsetXMLDeclaration finds the encoding in an XML declaration, such as
+// <?xml version="1.0" encoding="ISO-8859-1"?>
+var charsetXMLDeclaration = regexp.MustCompile(`^\s*<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// DecodeCharset converts a document to UTF-8. The document's encoding is
+// determined from (in order) its byte order mark, the charset parameter of
+// its Content-Type, its XML declaration, and (for HTML) its <meta> tags. A
+// byte order mark is removed. Documents in an unknown encoding, or with no
+// declared encoding, are returned as they are, except for HTML that is not
+// valid UTF-8, which is decoded with the encoding that a browser would guess.
+//
+// Responses are converted automatically when they are decoded into a
+// Result or Error, or into a *string.
+func DecodeCharset(body []byte, contentType string) ([]byte, error) {
+
+	const location = "remote.DecodeCharset"
+
+	name, bomLength := detectCharset(body, contentType)
+	body = body[bomLength:]
+
+	if name == charsetUTF8 {
+		return body, nil
+	}
+
+	decoding, err := htmlindex.Get(name)
+
+	if err != nil {
+		return body, nil
+	}
+
+	result, _, err := transform.Bytes(decoding.NewDecoder(), body)
+
+	if err != nil {
+		return nil, derp.Wrap(err, location, "Unable to convert document to UTF-8", name)
+	}
+
+	return result, nil
+}
+
+// detectCharset returns the canonical name of a document's encoding, and
+// the length of its byte order mark (if any).
+func detectCharset(body []byte, contentType string) (string, int) {
+
+	// 1. Byte order mark
+	for _, bom := range charsetBOMs {
+		if bytes.HasPrefix(body, bom.bom) {
+			return bom.name, len(bom.bom)
+		}
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+
+	// 2. Content-Type header
+	if name, ok := charsetName(params["charset"]); ok {
+		return name, 0
+	}
+
+	sniff := body[:min(len(body), charsetSniffSize)]
+
+	// 3. XML declaration
+	if match := charsetXMLDeclaration.FindSubmatch(sniff); match != nil {
+		if name, ok := charsetName(string(match[1])); ok {
+			return name, 0
+		}
+	}
+
+	// 4. HTML <meta> tags. Without one, the encoding is only a guess from the
+	// first few bytes, so it is only used if the document is not valid UTF-8.
+	if (mediaType == ContentTypeHTML) || (mediaType == "application/xhtml+xml") {
+		if hasMetaCharset(sniff) || !utf8.Valid(body) {
+			if _, name, _ := charset.DetermineEncoding(sniff, ContentTypeHTML); name != "" {
+				if name, ok := charsetName(name); ok {
+					return name, 0
+				}
+			}
+		}
+	}
+
+	return charsetUTF8, 0
+}
+
+// hasMetaCharset reports whether an HTML document declares its encoding in a
+// <meta charset> or <meta http-equiv="Content-Type"> tag. DetermineEncoding
+// reports these declarations as uncertain, so they are found separately.
+func hasMetaCharset(sniff []byte) bool {
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(sniff))
+
+	for {
+		switch tokenizer.Next() {
+
+		case html.ErrorToken:
+			return false
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+
+			name, hasAttributes := tokenizer.TagName()
+
+			if (string(name) != "meta") || !hasAttributes {
+				continue
+			}
+
+			attributes := map[string]string{}
+
+			for hasAttributes {
+				var key, value []byte
+				key, value, hasAttributes = tokenizer.TagAttr()
+				attributes[string(key)] = string(value)
+			}
+
+			if attributes["charset"] != "" {
+				return true
+			}
+
+			if strings.EqualFold(attributes["http-equiv"], "content-type") {
+				if _, params, err := mime.ParseMediaType(attributes["content"]); (err == nil) && (params["charset"] != "") {
+					return true
+				}
+			}
+		}
+	}
+}
+
+// charsetName returns the canonical name for a charset label, such as
+// "windows-1252" for "ISO-8859-1", and whether it is a known encoding.
+func charsetName(label string) (string, bool) {
+
+	label = strings.Trim(strings.TrimSpace(label), `"'`)
+
+	if label == "" {
+		return "", false
+	}
+
+	decoding, err := htmlindex.Get(label)
+
+	if err != nil {
+		return "", false
+	}
+
+	name, err := htmlindex.Name(decoding)
+
+	if err != nil {
+		return "", false
+	}
+
+	// Some labels (such as "replacement") identify encodings that cannot be decoded
+	if decoding == encoding.Replacement {
+		return "", false
+	}
+
+	return name, true
+}
+
+// utf8CharsetReader is an xml.Decoder CharsetReader for documents that have
+// already been converted to UTF-8, but still declare their original encoding.
+func utf8CharsetReader(_ string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
@@ -0,0 +1,126 @@
+package remote
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCharset(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		expected    string
+	}{
+		{"UTF-8 default", "caf\xc3\xa9", ContentTypeJSON, "café"},
+		{"UTF-8 BOM", "\xef\xbb\xbfcaf\xc3\xa9", ContentTypeJSON, "café"},
+		{"UTF-16LE BOM", "\xff\xfec\x00a\x00f\x00\xe9\x00", ContentTypePlain, "café"},
+		{"UTF-16BE BOM", "\xfe\xff\x00c\x00a\x00f\x00\xe9", ContentTypePlain + "; charset=iso-8859-1", "café"},
+		{"Header ISO-8859-1", "caf\xe9", ContentTypePlain + "; charset=ISO-8859-1", "café"},
+		{"Header Windows-1252", "\x93quoted\x94", ContentTypeJSON + `; charset="windows-1252"`, "“quoted”"},
+		{"Header Shift_JIS", "\x93\xfa\x96\x7b", ContentTypeHTML + "; charset=Shift_JIS", "日本"},
+		{"Header wins over XML", `<?xml version="1.0" encoding="ISO-8859-1"?><a>caf` + "\xc3\xa9</a>", ContentTypeXML + "; charset=utf-8", `<?xml version="1.0" encoding="ISO-8859-1"?><a>café</a>`},
+		{"XML declaration", `<?xml version="1.0" encoding="ISO-8859-1"?><a>caf` + "\xe9</a>", ContentTypeRSSXML, `<?xml version="1.0" encoding="ISO-8859-1"?><a>café</a>`},
+		{"XML declaration single quotes", "<?xml version='1.0' encoding='windows-1251'?><a>\xcf\xf0\xe8</a>", "", "<?xml version='1.0' encoding='windows-1251'?><a>При</a>"},
+		{"HTML meta charset", `<html><head><meta charset="euc-jp"></head><body>` + "\xc6\xfc</body></html>", ContentTypeHTML, `<html><head><meta charset="euc-jp"></head><body>日</body></html>`},
+		{"HTML meta http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-2">` + "\xb1", ContentTypeHTML, `<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-2">ą`},
+		{"HTML meta with valid UTF-8", `<meta charset="windows-1252">` + "\xc3\xa9", ContentTypeHTML, `<meta charset="windows-1252">Ã©`},
+		{"HTML meta http-equiv with valid UTF-8", `<meta http-equiv="content-type" content="text/html; charset=windows-1252">` + "\xc3\xa9", ContentTypeHTML, `<meta http-equiv="content-type" content="text/html; charset=windows-1252">Ã©`},
+		{"HTML guess", "<p>caf\xe9</p>", ContentTypeHTML, "<p>café</p>"},
+		{"HTML UTF-8 without meta", "<html><body>" + strings.Repeat("ascii ", 200) + "<p>café 日本</p></body></html>", ContentTypeHTML, "<html><body>" + strings.Repeat("ascii ", 200) + "<p>café 日本</p></body></html>"},
+		{"Meta ignored outside HTML", `<meta charset="iso-8859-2">caf` + "\xc3\xa9", ContentTypePlain, `<meta charset="iso-8859-2">café`},
+		{"Unknown charset", "caf\xc3\xa9", ContentTypePlain + "; charset=x-unknown", "café"},
+	}
+
+	for _, test := range tests {
+		result, err := DecodeCharset([]byte(test.body), test.contentType)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.expected, string(result), test.name)
+	}
+}
+
+func TestDecodeCharset_UTF8HTMLWithoutMeta(t *testing.T) {
+
+	// The first 1024 bytes are ASCII, so the encoding cannot be guessed from them
+	document := "<html><body>" + strings.Repeat("<p>ascii</p>", 100) + "<p>Café – 日本</p></body></html>"
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(ContentType, ContentTypeHTML)
+		_, _ = w.Write([]byte(document))
+	})
+
+	result := ""
+	require.NoError(t, Get("https://example.com/").With(option).Result(&result).Send())
+	require.Equal(t, document, result)
+}
+
+func TestCharsetName(t *testing.T) {
+
+	name, ok := charsetName(" ISO-8859-1 ")
+	require.True(t, ok)
+	require.Equal(t, "windows-1252", name)
+
+	name, ok = charsetName("UTF8")
+	require.True(t, ok)
+	require.Equal(t, "utf-8", name)
+
+	for _, label := range []string{"", "nonsense", "replacement", "iso-2022-kr"} {
+		_, ok := charsetName(label)
+		require.False(t, ok, label)
+	}
+}
+
+func TestDecodeCharset_Result(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+		switch r.URL.Path {
+
+		case "/json":
+			w.Header().Set(ContentType, ContentTypeJSON+"; charset=iso-8859-1")
+			_, _ = w.Write([]byte("{\"name\":\"Ren\xe9e\"}"))
+
+		case "/xml":
+			w.Header().Set(ContentType, ContentTypeXML)
+			_, _ = w.Write([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><person><name>Ren\xe9e</name></person>"))
+
+		case "/feed":
+			w.Header().Set(ContentType, contentTypeNonStandardXMLText)
+			_, _ = w.Write([]byte("<?xml version=\"1.0\" encoding=\"windows-1252\"?><rss version=\"2.0\"><channel><title>Caf\xe9 \x93News\x94</title></channel></rss>"))
+
+		case "/text":
+			w.Header().Set(ContentType, ContentTypePlain+"; charset=Shift_JIS")
+			_, _ = w.Write([]byte("\x93\xfa\x96\x7b"))
+		}
+	})
+
+	// JSON
+	person := struct{ Name string }{}
+	require.NoError(t, Get("https://example.com/json").With(option).Result(&person).Send())
+	require.Equal(t, "Renée", person.Name)
+
+	// XML, which the standard library rejects when it declares another encoding
+	xmlPerson := struct {
+		Name string `xml:"name"`
+	}{}
+	require.NoError(t, Get("https://example.com/xml").With(option).Result(&xmlPerson).Send())
+	require.Equal(t, "Renée", xmlPerson.Name)
+
+	// Feeds
+	feed := Feed{}
+	require.NoError(t, Get("https://example.com/feed").With(option).Result(&feed).Send())
+	require.Equal(t, "Café “News”", feed.Title)
+
+	// Strings are converted, but byte slices are not
+	text := ""
+	require.NoError(t, Get("https://example.com/text").With(option).Result(&text).Send())
+	require.Equal(t, "日本", text)
+
+	raw := []byte{}
+	require.NoError(t, Get("https://example.com/text").With(option).Result(&raw).Send())
+	require.Equal(t, []byte("\x93\xfa\x96\x7b"), raw)
+}
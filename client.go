@@ -13,7 +13,7 @@ import (
 // defaultTimeout is the default time limit applied to a request and its dialer.
 const defaultTimeout = 1 * time.Minute
 
-// maxRedirects caps how many redirects a request will follow.
+// maxRedirects is the default cap on how many redirects a request will follow.
 const maxRedirects = 5
 
 // dialContextFunc matches the signature of net.Dialer.DialContext.
@@ -29,28 +29,23 @@ var safeTransport = newGuardedTransport()
 // *http.Client but not a remote.Transaction. Like every remote request, it
 // refuses to connect to non-public addresses unless allowPrivateIPs is TRUE.
 // The returned client is fresh, but shares the pooled safeTransport singleton.
+// It follows redirects with the default RedirectPolicy, which caps the chain
+// at maxRedirects; use NewHTTPClientWithPolicy to choose another.
 func NewHTTPClient(allowPrivateIPs bool) *http.Client {
+	return NewHTTPClientWithPolicy(allowPrivateIPs, RedirectPolicy{})
+}
+
+// NewHTTPClientWithPolicy is NewHTTPClient with a RedirectPolicy of the
+// caller's choice. The policy has no host allow-list (standalone clients have
+// none), but the private-IP guard still re-runs on every hop, since it lives
+// in the dialer.
+func NewHTTPClientWithPolicy(allowPrivateIPs bool, policy RedirectPolicy) *http.Client {
 
 	return &http.Client{
 		Timeout:       defaultTimeout,
 		Transport:     baseTransport(allowPrivateIPs),
-		CheckRedirect: guardedRedirect,
-	}
-}
-
-// guardedRedirect is the http.Client CheckRedirect policy used by standalone
-// clients from NewHTTPClient. It caps the redirect chain at maxRedirects. It
-// does NOT enforce a host allow-list (standalone clients have none); the
-// private-IP guard still re-runs on every hop, since it lives in the dialer.
-func guardedRedirect(_ *http.Request, via []*http.Request) error {
-
-	const location = "remote.guardedRedirect"
-
-	if len(via) >= maxRedirects {
-		return derp.BadRequest(location, "Too many redirects")
+		CheckRedirect: policy.CheckRedirect,
 	}
-
-	return nil
 }
 
 // baseTransport picks the round-tripper for a client. The SSRF-hardened
@@ -0,0 +1,279 @@
+package remote
+
+import (
+	"encoding/json"
+	"iter"
+	"net/url"
+
+	"github.com/benpate/derp"
+)
+
+// defaultCollectionMaxItems is the default cap on the number of items that
+// CollectionItems yields from a single collection.
+const defaultCollectionMaxItems = 10_000
+
+// defaultCollectionMaxPages is the default cap on the number of pages that
+// CollectionItems retrieves from a single collection.
+const defaultCollectionMaxPages = 200
+
+// CollectionConfig limits how much of an ActivityPub collection is traversed
+// by CollectionItemsWith, so an enormous (or hostile) collection cannot keep
+// the caller busy forever.
+type CollectionConfig struct {
+	MaxItems int // maximum number of items to yield (default: 10,000)
+	MaxPages int // maximum number of documents to retrieve, including the collection itself (default: 200)
+}
+
+// CollectionItems retrieves an ActivityPub Collection or OrderedCollection
+// (such as an actor's outbox or followers) and yields each of its items,
+// decoded into T (often map[string]any). Items that are only IRIs are
+// decoded as `{"id": "<iri>"}` unless T is a string. Pages are retrieved only
+// as they are needed, whether they are embedded in the collection or
+// referenced by URL. Iteration stops after the last item, at the default
+// limits of CollectionConfig, or after yielding the first error.
+//
+// Each document is requested with a clone of the transaction, so its headers,
+// options, and host policy apply throughout. The Accept header is set to
+// request ActivityPub content.
+func CollectionItems[T any](transaction *Transaction) iter.Seq2[T, error] {
+	return CollectionItemsWith[T](transaction, CollectionConfig{})
+}
+
+// CollectionItemsWith works like CollectionItems, using the provided limits.
+func CollectionItemsWith[T any](transaction *Transaction, config CollectionConfig) iter.Seq2[T, error] {
+
+	const location = "remote.CollectionItems"
+
+	if config.MaxItems <= 0 {
+		config.MaxItems = defaultCollectionMaxItems
+	}
+
+	if config.MaxPages <= 0 {
+		config.MaxPages = defaultCollectionMaxPages
+	}
+
+	return func(yield func(T, error) bool) {
+
+		var zero T
+
+		visited := map[string]bool{}
+		items := 0
+		pages := 0
+
+		// The collection itself is the first document.
+		var reference any = transaction.RequestURL()
+		base := (*url.URL)(nil)
+		isCollection := true
+
+		for reference != nil {
+
+			// Load the next document, unless it is already embedded.
+			document, documentURL, err := collectionDocument(transaction, reference, base)
+
+			if err != nil {
+				yield(zero, derp.Wrap(err, location, "Unable to retrieve collection page"))
+				return
+			}
+
+			if id := collectionID(document, documentURL); id != "" {
+
+				if visited[id] {
+					yield(zero, derp.Internal(location, "Collection loop detected", id))
+					return
+				}
+
+				visited[id] = true
+			}
+
+			if documentURL != nil {
+				base = documentURL
+				pages++
+			}
+
+			// Yield each item on this page
+			for _, item := range collectionList(document) {
+
+				if items >= config.MaxItems {
+					return
+				}
+
+				value, err := decodeCollectionItem[T](item)
+
+				if err != nil {
+					yield(zero, derp.Wrap(err, location, "Unable to decode collection item", item))
+					return
+				}
+
+				items++
+
+				if !yield(value, nil) {
+					return
+				}
+			}
+
+			// Find the next page. A collection points to its first page, and
+			// each page points to the next.
+			reference = document["next"]
+
+			if isCollection && (reference == nil) {
+				reference = document["first"]
+			}
+
+			isCollection = false
+
+			// Stop before retrieving more pages than allowed.
+			if _, isEmbedded := reference.(map[string]any); !isEmbedded && (pages >= config.MaxPages) {
+				return
+			}
+		}
+	}
+}
+
+// collectionDocument returns the document for a page reference. Embedded pages
+// are returned as is, while IRIs (or link objects with only an id/href) are
+// retrieved. The returned URL is non-nil only for retrieved documents.
+func collectionDocument(transaction *Transaction, reference any, base *url.URL) (map[string]any, *url.URL, error) {
+
+	const location = "remote.collectionDocument"
+
+	var target string
+
+	switch typed := reference.(type) {
+
+	case string:
+		target = typed
+
+	case map[string]any:
+
+		// An object with items (or a next page) is an embedded page.
+		if _, ok := typed["orderedItems"]; ok {
+			return typed, nil, nil
+		}
+
+		if _, ok := typed["items"]; ok {
+			return typed, nil, nil
+		}
+
+		if _, ok := typed["next"]; ok {
+			return typed, nil, nil
+		}
+
+		// Otherwise, it is a reference to a page elsewhere.
+		target, _ = typed["id"].(string)
+
+		if target == "" {
+			target, _ = typed["href"].(string)
+		}
+
+		if target == "" {
+			return typed, nil, nil
+		}
+
+	default:
+		return nil, nil, derp.Internal(location, "Invalid page reference", reference)
+	}
+
+	if base != nil {
+		if resolved, err := base.Parse(target); err == nil {
+			target = resolved.String()
+		}
+	}
+
+	document := map[string]any{}
+
+	current := transaction.Clone().
+		Accept(ContentTypeActivityPub, ContentTypeJSONLD, ContentTypeJSON).
+		Result(&document)
+
+	// The collection URL includes its own query string, after the first request.
+	if base != nil {
+		current.url = target
+		current.query = url.Values{}
+	}
+
+	if err := current.Send(); err != nil {
+		return nil, nil, derp.Wrap(err, location, "Unable to retrieve document", target)
+	}
+
+	return document, current.finalURL(), nil
+}
+
+// collectionID identifies a document for loop detection, by its id or the URL it was retrieved from.
+func collectionID(document map[string]any, documentURL *url.URL) string {
+
+	if id, ok := document["id"].(string); ok && (id != "") {
+		return id
+	}
+
+	if documentURL != nil {
+		return documentURL.String()
+	}
+
+	return ""
+}
+
+// collectionList returns the items of a collection or collection page.
+func collectionList(document map[string]any) []any {
+
+	for _, name := range []string{"orderedItems", "items"} {
+
+		switch typed := document[name].(type) {
+
+		case []any:
+			return typed
+
+		case nil:
+			continue
+
+		default:
+			// A single item need not be wrapped in an array.
+			return []any{typed}
+		}
+	}
+
+	return nil
+}
+
+// decodeCollectionItem converts a raw item into the caller's type.
+func decodeCollectionItem[T any](item any) (T, error) {
+
+	var result T
+
+	// Fast path for the common case
+	if typed, ok := any(&result).(*map[string]any); ok {
+
+		switch value := item.(type) {
+		case map[string]any:
+			*typed = value
+			return result, nil
+		case string:
+			*typed = map[string]any{"id": value}
+			return result, nil
+		}
+	}
+
+	// Otherwise, round-trip the item through JSON.
+	encoded, err := json.Marshal(item)
+
+	if err != nil {
+		return result, err
+	}
+
+	if err := json.Unmarshal(encoded, &result); err == nil {
+		return result, nil
+	}
+
+	// Items that are only IRIs decode as objects with an id.
+	if iri, ok := item.(string); ok {
+
+		encoded, err = json.Marshal(map[string]string{"id": iri})
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	result = *new(T)
+	err = json.Unmarshal(encoded, &result)
+	return result, err
+}
@@ -0,0 +1,168 @@
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// collectionServer serves the JSON documents in the map, replacing "{host}"
+// with the server's URL.
+func collectionServer(documents map[string]string) *httptest.Server {
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		document, ok := documents[r.URL.RequestURI()]
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if !strings.Contains(r.Header.Get(Accept), ContentTypeActivityPub) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+
+		w.Header().Set(ContentType, ContentTypeActivityPub)
+		_, _ = w.Write([]byte(strings.ReplaceAll(document, "{host}", server.URL)))
+	}))
+
+	return server
+}
+
+// collectionIDs collects the ids of every item in a collection.
+func collectionIDs(t *testing.T, transaction *Transaction, config CollectionConfig) []string {
+
+	result := []string{}
+
+	for item, err := range CollectionItemsWith[map[string]any](transaction, config) {
+		require.NoError(t, err)
+		result = append(result, item["id"].(string))
+	}
+
+	return result
+}
+
+func TestCollectionItems_Pages(t *testing.T) {
+
+	ts := collectionServer(map[string]string{
+		"/outbox":        `{"id":"{host}/outbox","type":"OrderedCollection","totalItems":5,"first":"{host}/outbox?page=1"}`,
+		"/outbox?page=1": `{"id":"{host}/outbox?page=1","type":"OrderedCollectionPage","orderedItems":[{"id":"1"},{"id":"2"}],"next":"/outbox?page=2"}`,
+		"/outbox?page=2": `{"id":"{host}/outbox?page=2","type":"OrderedCollectionPage","orderedItems":[{"id":"3"},"https://example.com/4"],"next":{"type":"Link","href":"{host}/outbox?page=3"}}`,
+		"/outbox?page=3": `{"id":"{host}/outbox?page=3","type":"OrderedCollectionPage","orderedItems":{"id":"5"}}`,
+	})
+	defer ts.Close()
+
+	transaction := Get(ts.URL + "/outbox").AllowPrivateIPs(true)
+	require.Equal(t, []string{"1", "2", "3", "https://example.com/4", "5"}, collectionIDs(t, transaction, CollectionConfig{}))
+}
+
+func TestCollectionItems_Embedded(t *testing.T) {
+
+	ts := collectionServer(map[string]string{
+		"/followers":        `{"id":"{host}/followers","type":"Collection","first":{"type":"CollectionPage","items":["a","b"],"next":"{host}/followers?page=2"}}`,
+		"/followers?page=2": `{"id":"{host}/followers?page=2","type":"CollectionPage","items":["c"]}`,
+		"/liked":            `{"id":"{host}/liked","type":"Collection","items":["x","y"]}`,
+	})
+	defer ts.Close()
+
+	require.Equal(t, []string{"a", "b", "c"}, collectionIDs(t, Get(ts.URL+"/followers").AllowPrivateIPs(true), CollectionConfig{}))
+	require.Equal(t, []string{"x", "y"}, collectionIDs(t, Get(ts.URL+"/liked").AllowPrivateIPs(true), CollectionConfig{}))
+}
+
+func TestCollectionItems_Limits(t *testing.T) {
+
+	ts := collectionServer(map[string]string{
+		"/outbox":        `{"id":"{host}/outbox","type":"OrderedCollection","first":"{host}/outbox?page=1"}`,
+		"/outbox?page=1": `{"id":"{host}/outbox?page=1","orderedItems":["1","2"],"next":"{host}/outbox?page=2"}`,
+		"/outbox?page=2": `{"id":"{host}/outbox?page=2","orderedItems":["3","4"],"next":"{host}/outbox?page=3"}`,
+		"/outbox?page=3": `{"id":"{host}/outbox?page=3","orderedItems":["5","6"]}`,
+	})
+	defer ts.Close()
+
+	transaction := Get(ts.URL + "/outbox").AllowPrivateIPs(true)
+
+	require.Equal(t, []string{"1", "2", "3"}, collectionIDs(t, transaction, CollectionConfig{MaxItems: 3}))
+	require.Equal(t, []string{"1", "2", "3", "4"}, collectionIDs(t, transaction, CollectionConfig{MaxPages: 3}))
+	require.Len(t, collectionIDs(t, transaction, CollectionConfig{}), 6)
+}
+
+func TestCollectionItems_Loop(t *testing.T) {
+
+	ts := collectionServer(map[string]string{
+		"/outbox":        `{"id":"{host}/outbox","type":"OrderedCollection","first":"{host}/outbox?page=1"}`,
+		"/outbox?page=1": `{"id":"{host}/outbox?page=1","orderedItems":["1"],"next":"{host}/outbox?page=1"}`,
+	})
+	defer ts.Close()
+
+	results := []error{}
+
+	for _, err := range CollectionItems[map[string]any](Get(ts.URL + "/outbox").AllowPrivateIPs(true)) {
+		results = append(results, err)
+	}
+
+	require.Len(t, results, 2)
+	require.NoError(t, results[0])
+	require.Error(t, results[1])
+}
+
+func TestCollectionItems_Error(t *testing.T) {
+
+	ts := collectionServer(map[string]string{
+		"/outbox": `{"id":"{host}/outbox","type":"OrderedCollection","first":"{host}/missing"}`,
+	})
+	defer ts.Close()
+
+	count := 0
+
+	for _, err := range CollectionItems[map[string]any](Get(ts.URL + "/outbox").AllowPrivateIPs(true)) {
+		require.Error(t, err)
+		count++
+	}
+
+	require.Equal(t, 1, count)
+}
+
+func TestCollectionItems_Typed(t *testing.T) {
+
+	type activity struct {
+		ID     string          `json:"id"`
+		Type   string          `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+
+	ts := collectionServer(map[string]string{
+		"/outbox":    `{"id":"{host}/outbox","type":"OrderedCollection","orderedItems":[{"id":"1","type":"Create","object":{"type":"Note"}},"https://example.com/2"]}`,
+		"/following": `{"id":"{host}/following","type":"Collection","items":["https://example.com/alice","https://example.com/bob"]}`,
+	})
+	defer ts.Close()
+
+	activities := []activity{}
+
+	for item, err := range CollectionItems[activity](Get(ts.URL + "/outbox").AllowPrivateIPs(true)) {
+		require.NoError(t, err)
+		activities = append(activities, item)
+	}
+
+	require.Len(t, activities, 2)
+	require.Equal(t, "Create", activities[0].Type)
+	require.JSONEq(t, `{"type":"Note"}`, string(activities[0].Object))
+	require.Equal(t, "https://example.com/2", activities[1].ID)
+
+	// IRIs can also be collected as strings
+	iris := []string{}
+
+	for item, err := range CollectionItems[string](Get(ts.URL + "/following").AllowPrivateIPs(true)) {
+		require.NoError(t, err)
+		iris = append(iris, item)
+	}
+
+	require.Equal(t, []string{"https://example.com/alice", "https://example.com/bob"}, iris)
+}
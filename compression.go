@@ -0,0 +1,349 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/benpate/derp"
+	"github.com/klauspost/compress/zstd"
+)
+
+// EncodingGzip is the "gzip" content coding (RFC 1952)
+const EncodingGzip = "gzip"
+
+// EncodingDeflate is the "deflate" content coding (zlib, RFC 1950)
+const EncodingDeflate = "deflate"
+
+// EncodingBrotli is the "br" content coding (RFC 7932)
+const EncodingBrotli = "br"
+
+// EncodingZstd is the "zstd" content coding (RFC 8878)
+const EncodingZstd = "zstd"
+
+// defaultEncodings are the content codings accepted by default, most preferred first.
+var defaultEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+// defaultMaxCompressionRatio is the default limit on how many times larger a
+// response may become when it is decompressed. Text rarely compresses by
+// more than 20:1, while decompression bombs reach 1000:1 and beyond.
+const defaultMaxCompressionRatio = 100
+
+// compressionRatioAllowance is how much a response may expand before the
+// compression ratio is enforced, so that small, very repetitive documents
+// are not rejected.
+const compressionRatioAllowance = 1 << 20
+
+// zstdMaxWindow limits the memory that a zstd response can make the decoder
+// allocate, regardless of what the stream's header asks for.
+const zstdMaxWindow = 32 << 20
+
+// Compression configures how a transaction compresses its request and
+// decompresses its response. The zero value is the default: responses may
+// be compressed with any supported coding, and are decompressed before they
+// are read. MaxResponseSize limits the decompressed size, and MaxRatio rejects
+// responses that expand suspiciously far (decompression bombs).
+//
+// Setting an "Accept-Encoding" header replaces the negotiated codings, but
+// responses in a supported coding are still decompressed.
+type Compression struct {
+	Disabled        bool     // if TRUE, ask for an uncompressed response ("identity") and read the body exactly as it is sent
+	Encodings       []string // content codings to accept, most preferred first (default: zstd, br, gzip, deflate)
+	MaxRatio        int64    // largest allowed ratio of decompressed to compressed size (default: 100)
+	RequestEncoding string   // (if set) compress the request body with this coding, and send it as the Content-Encoding
+}
+
+// Compression sets how the request body is compressed, and how the response
+// is negotiated and decompressed.
+func (t *Transaction) Compression(compression Compression) *Transaction {
+	t.compression = compression
+	return t
+}
+
+// acceptEncoding returns the Accept-Encoding header for a request.
+func (compression Compression) acceptEncoding() string {
+
+	if compression.Disabled {
+		return "identity"
+	}
+
+	if len(compression.Encodings) == 0 {
+		return strings.Join(defaultEncodings, ", ")
+	}
+
+	return strings.Join(compression.Encodings, ", ")
+}
+
+// maxRatio returns the compression ratio limit, applying the default.
+func (compression Compression) maxRatio() int64 {
+
+	if compression.MaxRatio <= 0 {
+		return defaultMaxCompressionRatio
+	}
+
+	return compression.MaxRatio
+}
+
+// compressRequestBody compresses a request body with the configured coding.
+func (compression Compression) compressRequestBody(body []byte) ([]byte, error) {
+
+	const location = "remote.Compression.compressRequestBody"
+
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+
+	switch strings.ToLower(compression.RequestEncoding) {
+
+	case EncodingGzip:
+		writer = gzip.NewWriter(&buffer)
+
+	case EncodingDeflate:
+		writer = zlib.NewWriter(&buffer)
+
+	case EncodingBrotli:
+		writer = brotli.NewWriter(&buffer)
+
+	case EncodingZstd:
+		encoder, err := zstd.NewWriter(&buffer, zstd.WithEncoderConcurrency(1))
+
+		if err != nil {
+			return nil, derp.Wrap(err, location, "Unable to create zstd encoder")
+		}
+
+		writer = encoder
+
+	default:
+		return nil, derp.BadRequest(location, "Unsupported request encoding", compression.RequestEncoding)
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return nil, derp.Wrap(err, location, "Unable to compress request body", compression.RequestEncoding)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, derp.Wrap(err, location, "Unable to compress request body", compression.RequestEncoding)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// decompressResponse replaces the response body with a decompressing reader,
+// if the response uses supported content codings. The Content-Encoding and
+// Content-Length headers are removed, as the standard library does for the
+// gzip responses it decompresses itself. Decoding starts when the body is
+// first read, so errors are reported while reading the body.
+func (t *Transaction) decompressResponse() {
+
+	response := t.response
+
+	if t.compression.Disabled || (response.Body == nil) || (response.Body == http.NoBody) {
+		return
+	}
+
+	encodings := contentEncodings(response.Header)
+
+	if len(encodings) == 0 {
+		return
+	}
+
+	// Only decompress if every coding is supported
+	for _, encoding := range encodings {
+		if !slices.Contains(defaultEncodings, encoding) {
+			return
+		}
+	}
+
+	response.Body = newDecompressor(response.Body, encodings, t.compression.maxRatio())
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+}
+
+// contentEncodings returns the content codings applied to a response, in
+// the order they were applied, ignoring "identity".
+func contentEncodings(header http.Header) []string {
+
+	result := []string{}
+
+	for _, value := range header.Values("Content-Encoding") {
+		for encoding := range strings.SplitSeq(value, ",") {
+			if encoding = strings.ToLower(strings.TrimSpace(encoding)); (encoding != "") && (encoding != "identity") {
+
+				// "x-gzip" is an alias for "gzip" (RFC 9110, section 8.4.1.3)
+				if encoding == "x-gzip" {
+					encoding = EncodingGzip
+				}
+
+				result = append(result, encoding)
+			}
+		}
+	}
+
+	return result
+}
+
+/******************************************
+ * Decompressing Reader
+ ******************************************/
+
+// decompressor decodes a compressed response body, and fails if it expands
+// by more than maxRatio.
+type decompressor struct {
+	raw        io.ReadCloser // original (compressed) body
+	compressed *countingReader
+	encodings  []string
+	maxRatio   int64
+	reader     io.Reader   // decoded body, once decoding has started
+	closers    []io.Closer // decoders that must be closed to release their resources
+	total      int64       // number of decompressed bytes read
+	err        error       // sticky error
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (counter *countingReader) Read(p []byte) (int, error) {
+	n, err := counter.reader.Read(p)
+	counter.count += int64(n)
+	return n, err
+}
+
+func newDecompressor(raw io.ReadCloser, encodings []string, maxRatio int64) *decompressor {
+	return &decompressor{
+		raw:        raw,
+		compressed: &countingReader{reader: raw},
+		encodings:  encodings,
+		maxRatio:   maxRatio,
+	}
+}
+
+// Read implements the io.Reader interface.
+func (d *decompressor) Read(p []byte) (int, error) {
+
+	const location = "remote.decompressor.Read"
+
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	if d.reader == nil {
+		if d.err = d.start(); d.err != nil {
+			return 0, d.err
+		}
+	}
+
+	n, err := d.reader.Read(p)
+	d.total += int64(n)
+
+	if d.total > max(d.compressed.count*d.maxRatio, compressionRatioAllowance) {
+		d.err = derp.Internal(location, "Response body exceeds the maximum compression ratio", d.maxRatio)
+		return 0, d.err
+	}
+
+	if (err != nil) && (err != io.EOF) {
+		d.err = derp.Wrap(err, location, "Unable to decompress response body", d.encodings)
+		return n, d.err
+	}
+
+	return n, err
+}
+
+// start creates the decoders, undoing the codings in the reverse of the order they were applied.
+func (d *decompressor) start() error {
+
+	const location = "remote.decompressor.start"
+
+	var reader io.Reader = d.compressed
+
+	for _, encoding := range slices.Backward(d.encodings) {
+
+		switch encoding {
+
+		case EncodingGzip:
+			gzipReader, err := gzip.NewReader(reader)
+
+			if err == io.EOF {
+				d.reader = bytes.NewReader(nil) // empty body
+				return nil
+			}
+
+			if err != nil {
+				return derp.Wrap(err, location, "Invalid gzip response")
+			}
+
+			reader = gzipReader
+
+		case EncodingDeflate:
+			deflateReader, err := newDeflateReader(reader)
+
+			if err == io.EOF {
+				d.reader = bytes.NewReader(nil) // empty body
+				return nil
+			}
+
+			if err != nil {
+				return derp.Wrap(err, location, "Invalid deflate response")
+			}
+
+			reader = deflateReader
+
+		case EncodingBrotli:
+			reader = brotli.NewReader(reader)
+
+		case EncodingZstd:
+			decoder, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+
+			if err != nil {
+				return derp.Wrap(err, location, "Invalid zstd response")
+			}
+
+			d.closers = append(d.closers, decoder.IOReadCloser())
+			reader = decoder
+		}
+	}
+
+	d.reader = reader
+	return nil
+}
+
+// Close implements the io.Closer interface, releasing the decoders and closing the original body.
+func (d *decompressor) Close() error {
+
+	for _, closer := range d.closers {
+		_ = closer.Close()
+	}
+
+	d.closers = nil
+
+	return d.raw.Close()
+}
+
+// newDeflateReader reads a "deflate" body. The specification calls for zlib
+// data, but some servers send raw deflate data instead, so both are accepted.
+func newDeflateReader(reader io.Reader) (io.Reader, error) {
+
+	buffered := bufio.NewReader(reader)
+	header, err := buffered.Peek(2)
+
+	if (err == io.EOF) && (len(header) == 0) {
+		return nil, io.EOF
+	}
+
+	// A zlib header is a compression method of 8 (deflate), and a checksum
+	if (len(header) == 2) && (header[0]&0x0f == 8) && ((uint16(header[0])<<8|uint16(header[1]))%31 == 0) {
+		return zlib.NewReader(buffered)
+	}
+
+	return flate.NewReader(buffered), nil
+}
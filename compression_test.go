@@ -0,0 +1,311 @@
+package remote
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// compressForTest compresses data with a content coding ("deflate-raw" is raw deflate data).
+func compressForTest(t *testing.T, encoding string, data []byte) []byte {
+
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+
+	switch encoding {
+	case EncodingGzip:
+		writer = gzip.NewWriter(&buffer)
+	case EncodingDeflate:
+		writer = zlib.NewWriter(&buffer)
+	case "deflate-raw":
+		writer, _ = flate.NewWriter(&buffer, flate.DefaultCompression)
+	case EncodingBrotli:
+		writer = brotli.NewWriter(&buffer)
+	case EncodingZstd:
+		encoder, err := zstd.NewWriter(&buffer)
+		require.NoError(t, err)
+		writer = encoder
+	default:
+		t.Fatalf("unknown encoding %q", encoding)
+	}
+
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return buffer.Bytes()
+}
+
+// decompressForTest reverses compressForTest.
+func decompressForTest(t *testing.T, encoding string, data []byte) []byte {
+
+	var reader io.Reader
+	var err error
+
+	switch encoding {
+	case EncodingGzip:
+		reader, err = gzip.NewReader(bytes.NewReader(data))
+	case EncodingDeflate:
+		reader, err = zlib.NewReader(bytes.NewReader(data))
+	case EncodingBrotli:
+		reader = brotli.NewReader(bytes.NewReader(data))
+	case EncodingZstd:
+		reader, err = zstd.NewReader(bytes.NewReader(data))
+	}
+
+	require.NoError(t, err)
+
+	result, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return result
+}
+
+func TestCompression_Encodings(t *testing.T) {
+
+	document := []byte(`{"name":"` + strings.Repeat("compressible ", 100) + `"}`)
+
+	tests := []struct {
+		header string
+		body   []byte
+	}{
+		{"gzip", compressForTest(t, EncodingGzip, document)},
+		{"x-gzip", compressForTest(t, EncodingGzip, document)},
+		{"deflate", compressForTest(t, EncodingDeflate, document)},
+		{"deflate", compressForTest(t, "deflate-raw", document)},
+		{"br", compressForTest(t, EncodingBrotli, document)},
+		{"zstd", compressForTest(t, EncodingZstd, document)},
+		{"ZSTD", compressForTest(t, EncodingZstd, document)},
+		{"gzip, br", compressForTest(t, EncodingBrotli, compressForTest(t, EncodingGzip, document))},
+		{"identity", document},
+		{"", document},
+	}
+
+	for _, test := range tests {
+
+		option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "zstd, br, gzip, deflate", r.Header.Get("Accept-Encoding"))
+			w.Header().Set(ContentType, ContentTypeJSON)
+			w.Header().Set("Content-Encoding", test.header)
+			_, _ = w.Write(test.body)
+		})
+
+		result := map[string]string{}
+		transaction := Get("https://example.com/").With(option).Result(&result)
+
+		require.NoError(t, transaction.Send(), test.header)
+		require.Equal(t, strings.Repeat("compressible ", 100), result["name"], test.header)
+
+		if test.header != "identity" {
+			require.Empty(t, transaction.ResponseHeader().Get("Content-Encoding"), test.header)
+		}
+	}
+}
+
+func TestCompression_Negotiation(t *testing.T) {
+
+	acceptEncoding := ""
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(compressForTest(t, EncodingGzip, []byte("hello")))
+	})
+
+	// Custom encodings
+	body := ""
+	require.NoError(t, Get("https://example.com/").With(option).Compression(Compression{Encodings: []string{"gzip"}}).Result(&body).Send())
+	require.Equal(t, "gzip", acceptEncoding)
+	require.Equal(t, "hello", body)
+
+	// A header set by the caller is sent as-is, and the response is still decompressed
+	body = ""
+	require.NoError(t, Get("https://example.com/").Header("Accept-Encoding", "gzip;q=1.0").With(option).Result(&body).Send())
+	require.Equal(t, "gzip;q=1.0", acceptEncoding)
+	require.Equal(t, "hello", body)
+
+	// Disabled compression asks for identity, and leaves the body alone
+	raw := []byte{}
+	transaction := Get("https://example.com/").With(option).Compression(Compression{Disabled: true}).Result(&raw)
+	require.NoError(t, transaction.Send())
+	require.Equal(t, "identity", acceptEncoding)
+	require.Equal(t, compressForTest(t, EncodingGzip, []byte("hello")), raw)
+	require.Equal(t, "gzip", transaction.ResponseHeader().Get("Content-Encoding"))
+}
+
+func TestCompression_UnsupportedEncoding(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip, compress")
+		_, _ = w.Write([]byte("not decoded"))
+	})
+
+	// Responses in codings that cannot be decoded are returned as they are
+	body := ""
+	transaction := Get("https://example.com/").With(option).Result(&body)
+	require.NoError(t, transaction.Send())
+	require.Equal(t, "not decoded", body)
+	require.Equal(t, "gzip, compress", transaction.ResponseHeader().Get("Content-Encoding"))
+}
+
+func TestCompression_Invalid(t *testing.T) {
+
+	for _, encoding := range []string{"gzip", "deflate", "br", "zstd"} {
+
+		option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Encoding", encoding)
+			_, _ = w.Write([]byte("this is not compressed data, but it says it is"))
+		})
+
+		body := ""
+		require.Error(t, Get("https://example.com/").With(option).Result(&body).Send(), encoding)
+	}
+}
+
+func TestCompression_EmptyBody(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	body := ""
+	require.NoError(t, Get("https://example.com/").With(option).Result(&body).Send())
+	require.Equal(t, "", body)
+}
+
+func TestCompression_Bomb(t *testing.T) {
+
+	// 20MB of zeros compresses to about 20KB (1000:1)
+	bomb := compressForTest(t, EncodingGzip, make([]byte, 20<<20))
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(bomb)
+	})
+
+	// Rejected by the default ratio limit
+	body := []byte{}
+	err := Get("https://example.com/").With(option).Result(&body).Send()
+	require.Error(t, err)
+	require.Empty(t, body)
+
+	// Allowed with a higher ratio limit
+	require.NoError(t, Get("https://example.com/").With(option).Compression(Compression{MaxRatio: 10_000}).Result(&body).Send())
+	require.Len(t, body, 20<<20)
+
+	// MaxResponseSize limits the decompressed size
+	err = Get("https://example.com/").With(option).Compression(Compression{MaxRatio: 10_000}).MaxResponseSize(1 << 20).Result(&body).Send()
+	require.Error(t, err)
+}
+
+func TestCompression_SmallRepetitiveDocument(t *testing.T) {
+
+	// Very repetitive documents exceed the ratio, but are allowed while they are small
+	document := bytes.Repeat([]byte("a"), 512<<10)
+	compressed := compressForTest(t, EncodingZstd, document)
+	require.Greater(t, len(document)/len(compressed), defaultMaxCompressionRatio)
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "zstd")
+		_, _ = w.Write(compressed)
+	})
+
+	body := []byte{}
+	require.NoError(t, Get("https://example.com/").With(option).Result(&body).Send())
+	require.Equal(t, document, body)
+}
+
+func TestCompression_Request(t *testing.T) {
+
+	for _, encoding := range []string{EncodingGzip, EncodingDeflate, EncodingBrotli, EncodingZstd} {
+
+		option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+			require.Equal(t, encoding, r.Header.Get("Content-Encoding"))
+
+			compressed, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"hello":"world"}`, string(decompressForTest(t, encoding, compressed)))
+
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		transaction := Post("https://example.com/inbox").
+			JSON(map[string]string{"hello": "world"}).
+			Compression(Compression{RequestEncoding: strings.ToUpper(encoding)}).
+			With(option)
+
+		require.NoError(t, transaction.Send(), encoding)
+	}
+
+	// Unsupported encodings are an error
+	err := Post("https://example.com/inbox").Body("hello").Compression(Compression{RequestEncoding: "compress"}).Send()
+	require.Error(t, err)
+
+	// Empty bodies are not compressed
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("Content-Encoding"))
+	})
+
+	require.NoError(t, Post("https://example.com/inbox").Compression(Compression{RequestEncoding: "gzip"}).With(option).Send())
+}
+
+func TestCompression_Network(t *testing.T) {
+
+	document := strings.Repeat("Hello, World! ", 1000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// Prefer brotli, if the client accepts it
+		encoding := EncodingGzip
+
+		if strings.Contains(r.Header.Get("Accept-Encoding"), EncodingBrotli) {
+			encoding = EncodingBrotli
+		}
+
+		w.Header().Set(ContentType, ContentTypePlain)
+		w.Header().Set("Content-Encoding", encoding)
+		_, _ = w.Write(compressForTest(t, encoding, []byte(document)))
+	}))
+	defer server.Close()
+
+	body := ""
+	require.NoError(t, Get(server.URL).AllowPrivateIPs(true).Result(&body).Send())
+	require.Equal(t, document, body)
+
+	// The standard library does not decompress gzip itself, which would bypass the ratio limit
+	body = ""
+	require.NoError(t, Get(server.URL).AllowPrivateIPs(true).Compression(Compression{Encodings: []string{"gzip"}}).Result(&body).Send())
+	require.Equal(t, document, body)
+}
+
+func TestContentEncodings(t *testing.T) {
+
+	header := http.Header{}
+	require.Empty(t, contentEncodings(header))
+
+	header.Add("Content-Encoding", "identity")
+	header.Add("Content-Encoding", " GZIP , , br")
+	header.Add("Content-Encoding", "x-gzip")
+
+	require.Equal(t, []string{"gzip", "br", "gzip"}, contentEncodings(header))
+}
+
+func TestCompression_Clone(t *testing.T) {
+
+	original := Get("https://example.com/").Compression(Compression{Encodings: []string{"gzip", "br"}})
+	clone := original.Clone()
+
+	clone.compression.Encodings[0] = "zstd"
+	require.Equal(t, []string{"gzip", "br"}, original.compression.Encodings)
+}
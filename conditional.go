@@ -0,0 +1,76 @@
+package remote
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IfNoneMatch makes the request conditional on the resource NOT matching any
+// of the given ETags, usually the ETag of a cached copy. If it still matches,
+// the server returns 304 Not Modified: Send does not return an error, the
+// Result is left untouched, and NotModified returns TRUE. ETags are quoted if
+// they are not already, and "*" matches any version. Calling it with no ETags
+// removes the condition.
+func (t *Transaction) IfNoneMatch(etags ...string) *Transaction {
+	return t.conditionalHeader("If-None-Match", etags)
+}
+
+// IfMatch makes the request conditional on the resource matching one of the
+// given ETags, so that an update is only applied to the version that the
+// caller last saw. If it does not match, the server returns 412 Precondition
+// Failed, which Send reports as an error. ETags are quoted if they are not
+// already, and "*" matches any version. Calling it with no ETags removes the
+// condition.
+func (t *Transaction) IfMatch(etags ...string) *Transaction {
+	return t.conditionalHeader("If-Match", etags)
+}
+
+// IfModifiedSince makes the request conditional on the resource having changed
+// since the given time, usually the Last-Modified time of a cached copy. If it
+// has not, the server returns 304 Not Modified, which is handled as it is for
+// IfNoneMatch. A zero time removes the condition.
+func (t *Transaction) IfModifiedSince(value time.Time) *Transaction {
+
+	if value.IsZero() {
+		delete(t.header, "If-Modified-Since")
+		return t
+	}
+
+	return t.Header("If-Modified-Since", value.UTC().Format(http.TimeFormat))
+}
+
+// NotModified returns TRUE if the server responded 304 Not Modified to a
+// conditional request, meaning that the caller's cached copy is still current.
+func (t *Transaction) NotModified() bool {
+	return t.statusCode() == http.StatusNotModified
+}
+
+// conditionalHeader sets a header to a list of ETags, or removes it if the list is empty.
+func (t *Transaction) conditionalHeader(name string, etags []string) *Transaction {
+
+	values := make([]string, 0, len(etags))
+
+	for _, etag := range etags {
+		if etag = strings.TrimSpace(etag); etag != "" {
+			values = append(values, quoteETag(etag))
+		}
+	}
+
+	if len(values) == 0 {
+		delete(t.header, name)
+		return t
+	}
+
+	return t.Header(name, strings.Join(values, ", "))
+}
+
+// quoteETag returns an entity tag in its quoted form, such as "abc" or W/"abc".
+func quoteETag(etag string) string {
+
+	if (etag == "*") || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+
+	return `"` + etag + `"`
+}
@@ -0,0 +1,139 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var conditionalModified = time.Date(2026, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+// conditionalOption serves a JSON document with an ETag and Last-Modified
+// time, answering conditional requests as a real server would.
+func conditionalOption(etag string) Option {
+	return handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentType, ContentTypeJSON)
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", conditionalModified, strings.NewReader(`{"name":"new"}`))
+	})
+}
+
+func TestConditional_Headers(t *testing.T) {
+
+	transaction := Get("https://example.com/").
+		IfNoneMatch("abc", `"def"`, `W/"ghi"`).
+		IfMatch("*").
+		IfModifiedSince(time.Date(2026, time.January, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60)))
+
+	require.Equal(t, `"abc", "def", W/"ghi"`, transaction.header["If-None-Match"])
+	require.Equal(t, "*", transaction.header["If-Match"])
+	require.Equal(t, "Fri, 02 Jan 2026 08:04:05 GMT", transaction.header["If-Modified-Since"])
+
+	// Clones keep their conditions
+	clone := transaction.Clone()
+	require.Equal(t, `"abc", "def", W/"ghi"`, clone.header["If-None-Match"])
+
+	// Empty values remove the conditions
+	transaction.IfNoneMatch().IfMatch("", " ").IfModifiedSince(time.Time{})
+	require.NotContains(t, transaction.header, "If-None-Match")
+	require.NotContains(t, transaction.header, "If-Match")
+	require.NotContains(t, transaction.header, "If-Modified-Since")
+}
+
+func TestConditional_IfNoneMatch(t *testing.T) {
+
+	// A matching ETag returns 304, which is not an error, and leaves the result untouched
+	result := map[string]string{"name": "cached"}
+	transaction := Get("https://example.com/").With(conditionalOption(`"v1"`)).IfNoneMatch("v1").Result(&result)
+
+	require.NoError(t, transaction.Send())
+	require.True(t, transaction.NotModified())
+	require.Equal(t, http.StatusNotModified, transaction.ResponseStatusCode())
+	require.Equal(t, "cached", result["name"])
+
+	// A different ETag returns the new document
+	transaction = Get("https://example.com/").With(conditionalOption(`"v2"`)).IfNoneMatch("v1").Result(&result)
+
+	require.NoError(t, transaction.Send())
+	require.False(t, transaction.NotModified())
+	require.Equal(t, "new", result["name"])
+}
+
+func TestConditional_IfModifiedSince(t *testing.T) {
+
+	result := map[string]string{"name": "cached"}
+
+	// Unchanged since the given time
+	transaction := Get("https://example.com/").With(conditionalOption(`"v1"`)).IfModifiedSince(conditionalModified).Result(&result)
+	require.NoError(t, transaction.Send())
+	require.True(t, transaction.NotModified())
+	require.Equal(t, "cached", result["name"])
+
+	// Changed since the given time
+	transaction = Get("https://example.com/").With(conditionalOption(`"v1"`)).IfModifiedSince(conditionalModified.Add(-time.Hour)).Result(&result)
+	require.NoError(t, transaction.Send())
+	require.False(t, transaction.NotModified())
+	require.Equal(t, "new", result["name"])
+}
+
+func TestConditional_IfMatch(t *testing.T) {
+
+	// A matching ETag succeeds
+	transaction := Get("https://example.com/").With(conditionalOption(`"v1"`)).IfMatch("v1")
+	require.NoError(t, transaction.Send())
+	require.Equal(t, http.StatusOK, transaction.ResponseStatusCode())
+
+	// A different ETag fails the precondition, which is still an error
+	transaction = Get("https://example.com/").With(conditionalOption(`"v2"`)).IfMatch("v1")
+	require.Error(t, transaction.Send())
+	require.Equal(t, http.StatusPreconditionFailed, transaction.ResponseStatusCode())
+	require.False(t, transaction.NotModified())
+}
+
+func TestConditional_NotSent(t *testing.T) {
+	require.False(t, Get("https://example.com/").NotModified())
+}
+
+func TestConditional_Download(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	require.NoError(t, os.WriteFile(dst, []byte("cached"), 0o644))
+
+	ranges := []string{}
+
+	// The existing file is left as it is
+	transaction := Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).IfNoneMatch("v1")
+	require.NoError(t, transaction.Download(context.Background(), dst))
+	require.True(t, transaction.NotModified())
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, []byte("cached"), content)
+	require.NoFileExists(t, dst+downloadPartSuffix)
+
+	// ...and so is a parallel download, which stops at the probe
+	requests := []string{}
+	transaction = Get("https://example.com/export.bin").With(parallelOption(`"v1"`, &requests)).IfNoneMatch("v1").Downloads(DownloadPolicy{Parallel: 3})
+	require.NoError(t, transaction.Download(context.Background(), dst))
+	require.True(t, transaction.NotModified())
+	require.Len(t, requests, 1)
+
+	content, err = os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, []byte("cached"), content)
+
+	// A changed file is downloaded
+	transaction = Get("https://example.com/file.bin").With(downloadOption(`"v2"`, &ranges)).IfNoneMatch("v1")
+	require.NoError(t, transaction.Download(context.Background(), dst))
+	require.False(t, transaction.NotModified())
+
+	content, err = os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+}
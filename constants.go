@@ -45,6 +45,10 @@ const ContentTypeAtomXML = "application/atom+xml"
 // ContentTypeRSSXML is the standard MIME Type for a RSS feed
 const ContentTypeRSSXML = "application/rss+xml"
 
+// ContentTypeMultipartByteRanges is the standard MIME Type for a 206 Partial Content response with several byte ranges
+// https://www.rfc-editor.org/rfc/rfc9110#section-14.6
+const ContentTypeMultipartByteRanges = "multipart/byteranges"
+
 // contentTypeNonStandardXMLText is a non-standard MIME Type that might be used by other systems for XML content
 const contentTypeNonStandardXMLText = "text/xml"
 
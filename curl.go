@@ -0,0 +1,501 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+// curlRedacted replaces sensitive values in a redacted curl command.
+const curlRedacted = "[REDACTED]"
+
+// curlSensitiveHeaders are always redacted by CurlRedacted.
+var curlSensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Signature", "X-Api-Key"}
+
+// Curl renders the transaction as a curl command that sends exactly the same
+// request: method, URL (with query string), headers, and body. Every argument
+// is single-quoted, so the command is safe to paste into a POSIX shell. The
+// command includes credentials, so use CurlRedacted when sharing it. If the body
+// cannot be encoded, the command is rendered without it; Send reports the error.
+func (t *Transaction) Curl() string {
+	return t.curl(nil)
+}
+
+// CurlRedacted renders the transaction as a curl command, like Curl, but
+// replaces the values of sensitive headers (Authorization, Cookie, Signature,
+// etc.) with "[REDACTED]". Additional header or query parameter names to redact
+// can be passed as arguments.
+func (t *Transaction) CurlRedacted(names ...string) string {
+
+	redact := make([]string, 0, len(curlSensitiveHeaders)+len(names))
+
+	for _, name := range append(slices.Clone(curlSensitiveHeaders), names...) {
+		redact = append(redact, strings.ToLower(name))
+	}
+
+	return t.curl(redact)
+}
+
+// curl renders the command, redacting the (lowercase) names in redact.
+func (t *Transaction) curl(redact []string) string {
+
+	isRedacted := func(name string) bool {
+		return slices.Contains(redact, strings.ToLower(name))
+	}
+
+	arguments := []string{"curl"}
+
+	method := strings.ToUpper(t.method)
+
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	// HEAD must use -I, because "-X HEAD" makes curl wait for a body that never arrives
+	switch method {
+	case http.MethodGet:
+	case http.MethodHead:
+		arguments = append(arguments, "-I")
+	default:
+		arguments = append(arguments, "-X", method)
+	}
+
+	// Query string parameters
+	requestURL := t.url
+
+	if len(t.query) > 0 {
+
+		query := url.Values{}
+
+		for name, values := range t.query {
+			for _, value := range values {
+				if isRedacted(name) {
+					value = curlRedacted
+				}
+				query.Add(name, value)
+			}
+		}
+
+		requestURL += "?" + query.Encode()
+	}
+
+	arguments = append(arguments, shellQuote(requestURL))
+
+	// Headers, in a predictable order
+	names := make([]string, 0, len(t.header))
+
+	for name := range t.header {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+
+		value := t.header[name]
+
+		if isRedacted(name) {
+			value = curlRedacted
+		}
+
+		arguments = append(arguments, "-H", shellQuote(name+": "+value))
+	}
+
+	// GET and HEAD requests do not send a body. RequestBody returns a copy,
+	// and a streaming body is read only once, so the transaction is unchanged.
+	// A body that cannot be encoded is left out, rather than reported here.
+	if (method != http.MethodGet) && (method != http.MethodHead) {
+
+		if body, err := t.RequestBody(); (err == nil) && (len(body) > 0) {
+			arguments = append(arguments, "--data-raw", shellQuote(string(body)))
+		}
+	}
+
+	return strings.Join(arguments, " ")
+}
+
+// shellQuote wraps a value in single quotes for a POSIX shell. Single quotes
+// within the value are closed, escaped, and reopened.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// FromCurl builds a Transaction from a curl command, such as one copied from a
+// browser's developer tools or a bug report. It understands the options that
+// describe a request (-X, -H, -d and its variants, --json, -u, -A, -e, -b, -G,
+// -I, --url) and ignores options that only affect curl's own output and
+// connection handling (-s, -v, -L, -k, --compressed, etc.). Other options,
+// bundled short options (-sSL), and data read from files (-d @file), are
+// rejected with an error.
+func FromCurl(command string) (*Transaction, error) {
+
+	const location = "remote.FromCurl"
+
+	arguments, err := shellSplit(command)
+
+	if err != nil {
+		return nil, derp.Wrap(err, location, "Unable to parse curl command", command)
+	}
+
+	if (len(arguments) == 0) || (arguments[0] != "curl") {
+		return nil, derp.BadRequest(location, "Command must begin with 'curl'", command)
+	}
+
+	result := New()
+	method := ""
+	requestURL := ""
+	data := []string{}
+	useGet := false
+
+	for index := 1; index < len(arguments); index++ {
+
+		argument := arguments[index]
+
+		// Everything that is not an option is the URL
+		if !strings.HasPrefix(argument, "-") || (argument == "-") {
+			requestURL = argument
+			continue
+		}
+
+		name, value, hasValue, err := curlOption(argument)
+
+		if err != nil {
+			return nil, derp.Wrap(err, location, "Unable to parse curl option", argument)
+		}
+
+		if curlFlags[name] {
+			switch name {
+			case "-G", "--get":
+				useGet = true
+			case "-I", "--head":
+				method = http.MethodHead
+			}
+			continue
+		}
+
+		if !curlOptionsWithValues[name] {
+			return nil, derp.BadRequest(location, "Unsupported curl option", argument)
+		}
+
+		// Options with values read them from the next argument, unless attached
+		if !hasValue {
+			index++
+
+			if index >= len(arguments) {
+				return nil, derp.BadRequest(location, "Missing value for curl option", argument)
+			}
+
+			value = arguments[index]
+		}
+
+		switch name {
+
+		case "-X", "--request":
+			method = strings.ToUpper(value)
+
+		case "--url":
+			requestURL = value
+
+		case "-H", "--header":
+			headerName, headerValue, found := strings.Cut(value, ":")
+
+			if !found {
+				return nil, derp.BadRequest(location, "Invalid header", value)
+			}
+
+			result.Header(http.CanonicalHeaderKey(strings.TrimSpace(headerName)), strings.TrimSpace(headerValue))
+
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				return nil, derp.BadRequest(location, "Reading data from files is not supported", value)
+			}
+			data = append(data, value)
+
+		case "--data-raw":
+			data = append(data, value)
+
+		case "--data-urlencode":
+			encoded, err := curlURLEncode(value)
+
+			if err != nil {
+				return nil, derp.Wrap(err, location, "Invalid --data-urlencode value", value)
+			}
+
+			data = append(data, encoded)
+
+		case "--json":
+			if strings.HasPrefix(value, "@") {
+				return nil, derp.BadRequest(location, "Reading data from files is not supported", value)
+			}
+			data = append(data, value)
+			result.ContentType(ContentTypeJSON)
+			result.Accept(ContentTypeJSON)
+
+		case "-u", "--user":
+			result.Header("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+
+		case "-A", "--user-agent":
+			result.UserAgent(value)
+
+		case "-e", "--referer":
+			result.Header("Referer", value)
+
+		case "-b", "--cookie":
+			result.Header("Cookie", value)
+		}
+	}
+
+	if requestURL == "" {
+		return nil, derp.BadRequest(location, "Command does not include a URL", command)
+	}
+
+	// Split the query string from the URL, so that it can be modified with Query()
+	parsed, err := url.Parse(requestURL)
+
+	if err != nil {
+		return nil, derp.Wrap(err, location, "Invalid URL", requestURL)
+	}
+
+	for name, values := range parsed.Query() {
+		for _, value := range values {
+			result.Query(name, value)
+		}
+	}
+
+	parsed.RawQuery = ""
+	parsed.ForceQuery = false
+	result.URL(parsed.String())
+
+	// Data is sent in the query string (with -G) or as the request body.
+	if len(data) > 0 {
+
+		joined := strings.Join(data, "&")
+
+		if useGet {
+			values, err := url.ParseQuery(joined)
+
+			if err != nil {
+				return nil, derp.Wrap(err, location, "Invalid data for -G", joined)
+			}
+
+			for name, items := range values {
+				for _, item := range items {
+					result.Query(name, item)
+				}
+			}
+
+		} else {
+
+			if result.isContentTypeEmpty() {
+				result.ContentType(ContentTypeForm)
+			}
+
+			result.body = joined
+
+			if method == "" {
+				method = http.MethodPost
+			}
+		}
+	}
+
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	result.Method(method)
+	return result, nil
+}
+
+// curlFlags are curl options that take no value. Only -G and -I change the request.
+var curlFlags = map[string]bool{
+	"-G":           true,
+	"--get":        true,
+	"-I":           true,
+	"--head":       true,
+	"-s":           true,
+	"--silent":     true,
+	"-S":           true,
+	"--show-error": true,
+	"-v":           true,
+	"--verbose":    true,
+	"-i":           true,
+	"--include":    true,
+	"-L":           true,
+	"--location":   true,
+	"-k":           true,
+	"--insecure":   true,
+	"-f":           true,
+	"--fail":       true,
+	"--compressed": true,
+	"--http1.1":    true,
+	"--http2":      true,
+}
+
+// curlOptionsWithValues are the curl options that take a value.
+var curlOptionsWithValues = map[string]bool{
+	"-X":               true,
+	"--request":        true,
+	"-H":               true,
+	"--header":         true,
+	"-d":               true,
+	"--data":           true,
+	"--data-ascii":     true,
+	"--data-binary":    true,
+	"--data-raw":       true,
+	"--data-urlencode": true,
+	"--json":           true,
+	"-u":               true,
+	"--user":           true,
+	"-A":               true,
+	"--user-agent":     true,
+	"-e":               true,
+	"--referer":        true,
+	"-b":               true,
+	"--cookie":         true,
+	"--url":            true,
+}
+
+// curlOption splits an option from a value attached to it, as in "-XPOST" or
+// "--request=POST". Short flags bundled together (such as "-sSL" or "-kH")
+// are not supported, and return an error.
+func curlOption(argument string) (name string, value string, hasValue bool, err error) {
+
+	const location = "remote.curlOption"
+
+	if strings.HasPrefix(argument, "--") {
+		name, value, hasValue = strings.Cut(argument, "=")
+		return name, value, hasValue, nil
+	}
+
+	if len(argument) <= 2 {
+		return argument, "", false, nil
+	}
+
+	name = argument[:2]
+
+	// Only options that take a value can have one attached
+	if !curlOptionsWithValues[name] {
+		return "", "", false, derp.BadRequest(location, "Bundled curl options are not supported; write each option separately", argument)
+	}
+
+	return name, argument[2:], true, nil
+}
+
+// curlURLEncode encodes a --data-urlencode value ("content", "=content", or
+// "name=content") the way curl does.
+func curlURLEncode(value string) (string, error) {
+
+	const location = "remote.curlURLEncode"
+
+	name, content, found := strings.Cut(value, "=")
+
+	switch {
+
+	case !found:
+		if strings.Contains(value, "@") {
+			return "", derp.BadRequest(location, "Reading data from files is not supported", value)
+		}
+		return url.QueryEscape(value), nil
+
+	case name == "":
+		return url.QueryEscape(content), nil
+	}
+
+	return url.QueryEscape(name) + "=" + url.QueryEscape(content), nil
+}
+
+// shellSplit splits a command line into arguments, following POSIX shell
+// quoting rules: single quotes, double quotes (with backslash escapes), bare
+// backslash escapes, and backslash-newline line continuations.
+func shellSplit(command string) ([]string, error) {
+
+	const location = "remote.shellSplit"
+
+	result := []string{}
+	current := bytes.Buffer{}
+	inArgument := false
+
+	for index := 0; index < len(command); index++ {
+
+		character := command[index]
+
+		switch character {
+
+		case ' ', '\t', '\n', '\r':
+			if inArgument {
+				result = append(result, current.String())
+				current.Reset()
+				inArgument = false
+			}
+
+		case '\\':
+			if index+1 >= len(command) {
+				return nil, derp.BadRequest(location, "Command ends with a backslash")
+			}
+
+			index++
+
+			// Line continuation
+			if command[index] == '\n' {
+				continue
+			}
+
+			if (command[index] == '\r') && (index+1 < len(command)) && (command[index+1] == '\n') {
+				index++
+				continue
+			}
+
+			current.WriteByte(command[index])
+			inArgument = true
+
+		case '\'':
+			end := strings.IndexByte(command[index+1:], '\'')
+
+			if end < 0 {
+				return nil, derp.BadRequest(location, "Unterminated single quote")
+			}
+
+			current.WriteString(command[index+1 : index+1+end])
+			index += end + 1
+			inArgument = true
+
+		case '"':
+			index++
+
+			for ; (index < len(command)) && (command[index] != '"'); index++ {
+
+				// Within double quotes, backslash only escapes these characters
+				if (command[index] == '\\') && (index+1 < len(command)) && strings.IndexByte("\"\\$`\n", command[index+1]) >= 0 {
+					index++
+
+					if command[index] == '\n' {
+						continue
+					}
+				}
+
+				current.WriteByte(command[index])
+			}
+
+			if index >= len(command) {
+				return nil, derp.BadRequest(location, "Unterminated double quote")
+			}
+
+			inArgument = true
+
+		default:
+			current.WriteByte(character)
+			inArgument = true
+		}
+	}
+
+	if inArgument {
+		result = append(result, current.String())
+	}
+
+	return result, nil
+}
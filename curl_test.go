@@ -0,0 +1,191 @@
+package remote
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurl_Get(t *testing.T) {
+
+	transaction := Get("https://example.com/users").
+		Query("page", "2").
+		Header("Authorization", "Bearer secret").
+		Accept(ContentTypeJSON)
+
+	require.Equal(t,
+		`curl 'https://example.com/users?page=2' -H 'Accept: application/json' -H 'Authorization: Bearer secret'`,
+		transaction.Curl())
+}
+
+func TestCurl_PostJSON(t *testing.T) {
+
+	transaction := Post("https://example.com/inbox").
+		JSON(map[string]string{"name": "it's me"})
+
+	require.Equal(t,
+		`curl -X POST 'https://example.com/inbox' -H 'Content-Type: application/json' --data-raw '{"name":"it'\''s me"}'`,
+		transaction.Curl())
+}
+
+func TestCurl_Redacted(t *testing.T) {
+
+	transaction := Post("https://example.com/inbox").
+		Query("token", "abc").
+		Header("Authorization", "Bearer secret").
+		Header("Signature", "keyId=123").
+		Body("hello")
+
+	result := transaction.CurlRedacted("token")
+
+	require.NotContains(t, result, "secret")
+	require.NotContains(t, result, "keyId")
+	require.NotContains(t, result, "abc")
+	require.Contains(t, result, `'Authorization: [REDACTED]'`)
+	require.Contains(t, result, `token=%5BREDACTED%5D`)
+	require.Contains(t, result, `--data-raw 'hello'`)
+}
+
+func TestCurl_ReaderBody(t *testing.T) {
+
+	transaction := Post("https://example.com/upload").
+		ContentType(ContentTypePlain).
+		JSON(strings.NewReader("streamed"))
+
+	original := transaction.body
+
+	require.Contains(t, transaction.Curl(), `--data-raw 'streamed'`)
+	require.Contains(t, transaction.CurlRedacted(), `--data-raw 'streamed'`)
+
+	// The transaction is unchanged, and its body is still available to send afterward
+	require.Same(t, original, transaction.body)
+
+	body, err := transaction.RequestBody()
+	require.NoError(t, err)
+	require.Equal(t, "streamed", string(body))
+}
+
+func TestCurl_Head(t *testing.T) {
+
+	transaction := New().Method(http.MethodHead).URL("https://example.com/file.bin")
+	require.Equal(t, `curl -I 'https://example.com/file.bin'`, transaction.Curl())
+
+	parsed, err := FromCurl(transaction.Curl())
+	require.NoError(t, err)
+	require.Equal(t, http.MethodHead, parsed.method)
+}
+
+func TestCurl_InvalidBody(t *testing.T) {
+
+	// A body that cannot be encoded is left out of the command
+	transaction := Post("https://example.com/inbox").JSON(make(chan int))
+	require.Equal(t, `curl -X POST 'https://example.com/inbox' -H 'Content-Type: application/json'`, transaction.Curl())
+}
+
+func TestCurl_RoundTrip(t *testing.T) {
+
+	original := Put("https://example.com/items/1").
+		Query("draft", "true").
+		Header("X-Custom", `quotes ' and " and $HOME`).
+		JSON(map[string]any{"title": "Hello, World"})
+
+	parsed, err := FromCurl(original.Curl())
+	require.NoError(t, err)
+	require.Equal(t, original.MarshalMap(), parsed.MarshalMap())
+	require.Equal(t, original.Curl(), parsed.Curl())
+}
+
+func TestFromCurl_Browser(t *testing.T) {
+
+	// Similar to the output of "Copy as cURL" in a browser
+	command := `curl 'https://example.com/api/search?q=go' \
+  -H 'accept: application/json' \
+  -H 'user-agent: Mozilla/5.0' \
+  -b 'session=abc123' \
+  --compressed`
+
+	transaction, err := FromCurl(command)
+	require.NoError(t, err)
+	require.Equal(t, http.MethodGet, transaction.method)
+	require.Equal(t, "https://example.com/api/search", transaction.url)
+	require.Equal(t, "go", transaction.query.Get("q"))
+	require.Equal(t, "application/json", transaction.header["Accept"])
+	require.Equal(t, "Mozilla/5.0", transaction.header["User-Agent"])
+	require.Equal(t, "session=abc123", transaction.header["Cookie"])
+}
+
+func TestFromCurl_Data(t *testing.T) {
+
+	transaction, err := FromCurl(`curl https://example.com/login -d name=alice --data-urlencode "password=p&ss word"`)
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPost, transaction.method)
+	require.Equal(t, ContentTypeForm, transaction.header[ContentType])
+
+	body, err := transaction.RequestBody()
+	require.NoError(t, err)
+	require.Equal(t, "name=alice&password=p%26ss+word", string(body))
+}
+
+func TestFromCurl_Options(t *testing.T) {
+
+	{
+		transaction, err := FromCurl(`curl -XDELETE --url=https://example.com/items/1 -u alice:secret`)
+		require.NoError(t, err)
+		require.Equal(t, http.MethodDelete, transaction.method)
+		require.Equal(t, "https://example.com/items/1", transaction.url)
+		require.Equal(t, "Basic YWxpY2U6c2VjcmV0", transaction.header["Authorization"])
+	}
+
+	{
+		transaction, err := FromCurl(`curl -G https://example.com/search -d q=go -d page=2`)
+		require.NoError(t, err)
+		require.Equal(t, http.MethodGet, transaction.method)
+		require.Equal(t, "https://example.com/search?page=2&q=go", transaction.RequestURL())
+	}
+
+	{
+		transaction, err := FromCurl(`curl --json '{"a":1}' https://example.com/api`)
+		require.NoError(t, err)
+		require.Equal(t, http.MethodPost, transaction.method)
+		require.Equal(t, ContentTypeJSON, transaction.header[ContentType])
+		require.Equal(t, ContentTypeJSON, transaction.header[Accept])
+	}
+
+	{
+		transaction, err := FromCurl(`curl -I -s https://example.com`)
+		require.NoError(t, err)
+		require.Equal(t, http.MethodHead, transaction.method)
+	}
+}
+
+func TestFromCurl_Errors(t *testing.T) {
+
+	commands := []string{
+		``,
+		`wget https://example.com`,
+		`curl`,
+		`curl 'https://example.com`,
+		`curl "https://example.com`,
+		`curl https://example.com -H`,
+		`curl https://example.com -H 'no colon'`,
+		`curl https://example.com -d @/etc/passwd`,
+		`curl https://example.com -o output.html`,
+		`curl -kH 'Accept: text/html' https://example.com`,
+		`curl -sSL https://example.com`,
+	}
+
+	for _, command := range commands {
+		_, err := FromCurl(command)
+		require.Error(t, err, command)
+	}
+}
+
+func TestShellSplit(t *testing.T) {
+
+	result, err := shellSplit(`curl  'a b' "c \"d\" \$e" f\ g 'h'"i" \
+j`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"curl", "a b", `c "d" $e`, "f g", "hi", "j"}, result)
+}
@@ -0,0 +1,225 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/benpate/derp"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// discoverMaxResponseSize caps the size of the HTML pages read by DiscoverFeeds.
+const discoverMaxResponseSize = 5 << 20
+
+// FeedLink is a feed that a web page links to, found by DiscoverFeeds.
+type FeedLink struct {
+	URL   string // absolute URL of the feed
+	Type  string // media type, such as "application/rss+xml" (may be empty for rel="feed")
+	Title string // human-readable title, if the page provides one
+	Rel   string // link relation, such as "alternate" or "feed"
+}
+
+// DiscoverFeeds retrieves a web page, such as a blog's home page, and returns
+// the feeds it links to: RSS, Atom, JSON Feed, and ActivityPub alternates
+// declared with <link rel="alternate">, plus any <link rel="feed">. Relative
+// URLs are resolved against the page (honoring <base href>). If the URL is
+// already a feed, it is returned as the only candidate. Options are applied
+// to the request.
+func DiscoverFeeds(ctx context.Context, pageURL string, options ...Option) ([]FeedLink, error) {
+
+	const location = "remote.DiscoverFeeds"
+
+	var body []byte
+
+	transaction := Get(pageURL).
+		WithContext(ctx).
+		Accept(ContentTypeHTML, "application/xhtml+xml", ContentTypeRSSXML, ContentTypeAtomXML, ContentTypeJSONFeed).
+		MaxResponseSize(discoverMaxResponseSize).
+		With(options...).
+		Result(&body)
+
+	if err := transaction.Send(); err != nil {
+		return nil, derp.Wrap(err, location, "Unable to retrieve page", pageURL)
+	}
+
+	// The URL may be a feed itself
+	base := transaction.finalURL()
+	contentType := transaction.ResponseContentType()
+
+	if isFeedMediaType(contentType) {
+		return []FeedLink{{URL: base.String(), Type: mediaType(contentType), Rel: "self"}}, nil
+	}
+
+	// Titles may be in the page's own character encoding
+	if decoded, err := DecodeCharset(body, contentType); err == nil {
+		body = decoded
+	}
+
+	return ParseFeedLinks(base, bytes.NewReader(body)), nil
+}
+
+// ParseFeedLinks finds the feeds that an HTML document links to, for documents
+// that have already been retrieved. Relative URLs are resolved against base
+// (or the document's <base href>). Duplicate URLs are returned only once.
+func ParseFeedLinks(base *url.URL, document io.Reader) []FeedLink {
+
+	result := []FeedLink{}
+	tokenizer := html.NewTokenizer(document)
+
+	for {
+		switch tokenizer.Next() {
+
+		case html.ErrorToken:
+			return result
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+
+			token := tokenizer.Token()
+
+			switch token.DataAtom {
+
+			case atom.Base:
+				if resolved := resolveURL(base, htmlAttribute(token, "href")); resolved != nil {
+					base = resolved
+				}
+
+			case atom.Link:
+				link, ok := feedLink(token)
+
+				if !ok {
+					continue
+				}
+
+				resolved := resolveURL(base, link.URL)
+
+				if resolved == nil {
+					continue
+				}
+
+				link.URL = resolved.String()
+
+				if !slices.ContainsFunc(result, func(existing FeedLink) bool { return existing.URL == link.URL }) {
+					result = append(result, link)
+				}
+			}
+		}
+	}
+}
+
+// feedLink returns the FeedLink described by a <link> tag, if it is one.
+func feedLink(token html.Token) (FeedLink, bool) {
+
+	href := strings.TrimSpace(htmlAttribute(token, "href"))
+
+	if href == "" {
+		return FeedLink{}, false
+	}
+
+	rels := strings.Fields(strings.ToLower(htmlAttribute(token, "rel")))
+	linkType := strings.TrimSpace(htmlAttribute(token, "type"))
+
+	link := FeedLink{
+		URL:   href,
+		Type:  mediaType(linkType),
+		Title: strings.TrimSpace(htmlAttribute(token, "title")),
+	}
+
+	switch {
+
+	case slices.Contains(rels, "feed"):
+		link.Rel = "feed"
+		return link, (linkType == "") || isFeedMediaType(linkType)
+
+	case slices.Contains(rels, "alternate"):
+		link.Rel = "alternate"
+		return link, isFeedMediaType(linkType)
+	}
+
+	return FeedLink{}, false
+}
+
+// isFeedMediaType reports whether a Content-Type identifies a feed, or an
+// ActivityPub document (which serves as a feed for fediverse accounts).
+// Plain JSON is not included, because many sites link to APIs that way.
+func isFeedMediaType(value string) bool {
+
+	parsed, params, err := mime.ParseMediaType(value)
+
+	if err != nil {
+		return false
+	}
+
+	switch parsed {
+
+	case ContentTypeRSSXML,
+		ContentTypeAtomXML,
+		ContentTypeJSONFeed,
+		ContentTypeActivityPub,
+		"application/rss", // seen in the wild
+		"application/x-rss+xml":
+		return true
+
+	case ContentTypeJSONLD:
+		return strings.Contains(params["profile"], "activitystreams")
+	}
+
+	return false
+}
+
+// mediaType returns the media type of a Content-Type, keeping only the
+// ActivityStreams profile parameter (which distinguishes ActivityPub JSON-LD).
+func mediaType(value string) string {
+
+	parsed, params, err := mime.ParseMediaType(value)
+
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+
+	if profile := params["profile"]; profile != "" {
+		return mime.FormatMediaType(parsed, map[string]string{"profile": profile})
+	}
+
+	return parsed
+}
+
+// htmlAttribute returns the value of an attribute of an HTML tag.
+func htmlAttribute(token html.Token, name string) string {
+
+	for _, attribute := range token.Attr {
+		if attribute.Namespace == "" && strings.EqualFold(attribute.Key, name) {
+			return attribute.Val
+		}
+	}
+
+	return ""
+}
+
+// resolveURL resolves a reference against a base URL, which may be nil. It
+// returns nil if the reference is empty or invalid.
+func resolveURL(base *url.URL, reference string) *url.URL {
+
+	reference = strings.TrimSpace(reference)
+
+	if reference == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(reference)
+
+	if err != nil {
+		return nil
+	}
+
+	if base == nil {
+		return parsed
+	}
+
+	return base.ResolveReference(parsed)
+}
@@ -0,0 +1,103 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFeedLinks(t *testing.T) {
+
+	document := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Example Blog</title>
+	<link rel="stylesheet" href="/style.css">
+	<link rel="alternate" type="application/rss+xml" title="Posts (RSS)" href="/feed.xml">
+	<link rel="alternate" type="application/atom+xml" title="Posts (Atom)" href="atom.xml">
+	<LINK REL="Alternate" TYPE="application/feed+json; charset=utf-8" TITLE=" JSON Feed " HREF="https://example.com/feed.json">
+	<link rel="alternate" type="application/json" href="/wp-json/wp/v2/pages/2">
+	<link rel="alternate" type="text/html" hreflang="fr" href="/fr/">
+	<link rel="alternate" type="application/activity+json" href="https://social.example.com/users/alice">
+	<link rel="alternate" type='application/ld+json; profile="https://www.w3.org/ns/activitystreams"' href="/actor">
+	<link rel="alternate" type="application/ld+json" href="/schema.jsonld">
+	<link rel="feed" href="/updates">
+	<link rel="alternate" type="application/rss+xml" title="Duplicate" href="/feed.xml">
+	<link rel="alternate" type="application/rss+xml">
+</head>
+<body><link rel="feed alternate" type="application/rss+xml" href="/comments.xml" title="Comments"></body>
+</html>`
+
+	base, err := url.Parse("https://example.com/blog/index.html")
+	require.NoError(t, err)
+
+	links := ParseFeedLinks(base, strings.NewReader(document))
+
+	require.Equal(t, []FeedLink{
+		{URL: "https://example.com/feed.xml", Type: ContentTypeRSSXML, Title: "Posts (RSS)", Rel: "alternate"},
+		{URL: "https://example.com/blog/atom.xml", Type: ContentTypeAtomXML, Title: "Posts (Atom)", Rel: "alternate"},
+		{URL: "https://example.com/feed.json", Type: ContentTypeJSONFeed, Title: "JSON Feed", Rel: "alternate"},
+		{URL: "https://social.example.com/users/alice", Type: ContentTypeActivityPub, Rel: "alternate"},
+		{URL: "https://example.com/actor", Type: `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`, Rel: "alternate"},
+		{URL: "https://example.com/updates", Rel: "feed"},
+		{URL: "https://example.com/comments.xml", Type: ContentTypeRSSXML, Title: "Comments", Rel: "feed"},
+	}, links)
+}
+
+func TestParseFeedLinks_Base(t *testing.T) {
+
+	document := `<html><head><base href="https://cdn.example.com/site/"><link rel="alternate" type="application/atom+xml" href="feed.atom"></head></html>`
+
+	base, err := url.Parse("https://example.com/")
+	require.NoError(t, err)
+
+	links := ParseFeedLinks(base, strings.NewReader(document))
+	require.Len(t, links, 1)
+	require.Equal(t, "https://cdn.example.com/site/feed.atom", links[0].URL)
+}
+
+func TestParseFeedLinks_Empty(t *testing.T) {
+	require.Empty(t, ParseFeedLinks(nil, strings.NewReader(``)))
+	require.Empty(t, ParseFeedLinks(nil, strings.NewReader(`<p>No feeds here</p>`)))
+}
+
+func TestDiscoverFeeds(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+		switch r.URL.Path {
+
+		case "/":
+			http.Redirect(w, r, "/home/", http.StatusFound)
+
+		case "/home/":
+			w.Header().Set(ContentType, "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" title="RSS" href="rss.xml"></head></html>`))
+
+		case "/feed.atom":
+			w.Header().Set(ContentType, ContentTypeAtomXML+"; charset=utf-8")
+			_, _ = w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// A page that links to a feed
+	links, err := DiscoverFeeds(context.Background(), "https://example.com/home/", option)
+	require.NoError(t, err)
+	require.Equal(t, []FeedLink{{URL: "https://example.com/home/rss.xml", Type: ContentTypeRSSXML, Title: "RSS", Rel: "alternate"}}, links)
+
+	// A URL that is already a feed
+	links, err = DiscoverFeeds(context.Background(), "https://example.com/feed.atom", option)
+	require.NoError(t, err)
+	require.Equal(t, []FeedLink{{URL: "https://example.com/feed.atom", Type: ContentTypeAtomXML, Rel: "self"}}, links)
+
+	// A page that does not exist
+	_, err = DiscoverFeeds(context.Background(), "https://example.com/missing", option)
+	require.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package remote
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/benpate/derp"
+)
+
+// minDownloadChunkSize is the smallest range that a parallel download requests,
+// so that small files are not split into many tiny requests.
+const minDownloadChunkSize = 1 << 20
+
+// parallel downloads the file in several concurrent range requests. It first
+// probes the server with a HEAD request, and returns FALSE (without an error)
+// if the server does not accept byte ranges, or does not report the file's
+// size and a strong ETag, so that the file is downloaded in a single request
+// instead.
+func (d *downloader) parallel(ctx context.Context) (bool, error) {
+
+	const location = "remote.downloader.parallel"
+
+	t := d.transaction
+
+	// Probe the server for the file's size, and whether it accepts range requests
+	probe := t.Clone().Method(http.MethodHead).Result(nil).Error(nil).OnDownloadProgress(nil)
+
+	if err := probe.Send(); err != nil {
+		return false, nil
+	}
+
+	// The caller's copy is still current, so there is nothing to download
+	if probe.NotModified() {
+		t.request = probe.request
+		t.response = probe.response
+		return true, nil
+	}
+
+	total := probe.response.ContentLength
+	etag := probe.ResponseHeader().Get("ETag")
+
+	if !strings.Contains(strings.ToLower(probe.ResponseHeader().Get("Accept-Ranges")), "bytes") {
+		return false, nil
+	}
+
+	if (etag == "") || strings.HasPrefix(etag, "W/") {
+		return false, nil
+	}
+
+	chunks := downloadChunks(total, d.policy.Parallel)
+
+	if len(chunks) < 2 {
+		return false, nil
+	}
+
+	// The probe stands in for the response, since the file arrives in several
+	t.request = probe.request
+	t.response = probe.response
+
+	maxSize := t.maxResponseSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxResponseSize
+	}
+
+	if total > maxSize {
+		return true, derp.Internal(location, "Response body exceeds maximum size", maxSize)
+	}
+
+	// Parallel downloads are not resumed, because the partial file has gaps
+	d.discard()
+
+	file, err := os.OpenFile(d.path+downloadPartSuffix, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+
+	if err != nil {
+		return true, derp.Wrap(err, location, "Unable to create partial file", d.path)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if err := file.Truncate(total); err != nil {
+		d.discard()
+		return true, derp.Wrap(err, location, "Unable to create partial file", d.path, total)
+	}
+
+	// Report progress across every chunk, to both the download policy and the transaction
+	var mutex sync.Mutex
+	written := int64(0)
+	reporters := []*progressReporter{}
+
+	for _, callback := range []ProgressFunc{d.policy.Progress, t.downloadProgress} {
+		if reporter := t.newProgressReporter(callback); reporter != nil {
+			reporters = append(reporters, reporter)
+		}
+	}
+
+	progress := progressCounter(func(count int64) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		written += count
+
+		for _, reporter := range reporters {
+			reporter.report(written, total, written == total)
+		}
+	})
+
+	progress(0)
+
+	// Download every chunk, and stop them all at the first error
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+
+	for _, chunk := range chunks {
+		wg.Go(func() {
+			if err := d.downloadChunk(ctx, file, chunk, etag, progress); err != nil {
+				cancel(err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		d.discard()
+		return true, derp.Wrap(err, location, "Unable to download file in parallel", d.path)
+	}
+
+	// The checksum is calculated once the chunks are in place
+	if d.checksum == nil {
+		return true, d.finish(file, nil)
+	}
+
+	hash := d.checksum.newHash()
+
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, total)); err != nil {
+		d.discard()
+		return true, derp.Wrap(err, location, "Unable to read partial file", d.path)
+	}
+
+	return true, d.finish(file, hash)
+}
+
+// downloadChunk requests one range of the file, and writes it into place. The
+// If-Range header makes the server send the whole file instead of the range
+// if the file has changed, which fails the download.
+func (d *downloader) downloadChunk(ctx context.Context, file *os.File, chunk [2]int64, etag string, progress progressCounter) error {
+
+	const location = "remote.downloader.downloadChunk"
+
+	transaction := d.transaction.Clone().WithContext(ctx).Result(nil).Error(nil).OnDownloadProgress(nil)
+	transaction.header["Range"] = "bytes=" + strconv.FormatInt(chunk[0], 10) + "-" + strconv.FormatInt(chunk[1], 10)
+	transaction.header["If-Range"] = etag
+
+	transaction.streamBody = func(response *http.Response) error {
+
+		if response.StatusCode != http.StatusPartialContent {
+
+			if (response.StatusCode >= 200) && (response.StatusCode <= 299) {
+				return derp.Internal(location, "File changed during download", etag)
+			}
+
+			return derp.NewHTTPError(transaction.request, response)
+		}
+
+		// The server may send the range in several parts, in any order
+		received := [][2]int64{}
+
+		for byteRange, err := range ByteRanges(response) {
+
+			if err != nil {
+				return derp.Wrap(err, location, "Unable to read range")
+			}
+
+			if (byteRange.Start < chunk[0]) || (byteRange.End > chunk[1]) {
+				return derp.Internal(location, "Server returned a range that was not requested", byteRange.Start, byteRange.End, chunk)
+			}
+
+			writer := io.MultiWriter(io.NewOffsetWriter(file, byteRange.Start), progress)
+			written, err := io.Copy(writer, byteRange.Body)
+
+			if err != nil {
+				return derp.Wrap(err, location, "Unable to download range", byteRange.Start, byteRange.End)
+			}
+
+			if written < byteRange.Length() {
+				return derp.Internal(location, "Range is incomplete", byteRange.Start, byteRange.End, written)
+			}
+
+			received = append(received, [2]int64{byteRange.Start, byteRange.End})
+		}
+
+		if !rangesCover(received, chunk) {
+			return derp.Internal(location, "Server did not return the whole range", chunk)
+		}
+
+		return nil
+	}
+
+	return transaction.Send()
+}
+
+// downloadChunks splits a file into at most n ranges of (nearly) equal size,
+// each at least minDownloadChunkSize bytes long. Ranges are inclusive.
+func downloadChunks(size int64, n int) [][2]int64 {
+
+	count := min(int64(n), size/minDownloadChunkSize)
+
+	if count < 1 {
+		return nil
+	}
+
+	chunkSize := (size + count - 1) / count
+	result := make([][2]int64, 0, count)
+
+	for start := int64(0); start < size; start += chunkSize {
+		result = append(result, [2]int64{start, min(start+chunkSize, size) - 1})
+	}
+
+	return result
+}
+
+// rangesCover returns TRUE if the (inclusive) ranges contain every byte in target.
+func rangesCover(ranges [][2]int64, target [2]int64) bool {
+
+	slices.SortFunc(ranges, func(a, b [2]int64) int {
+		return cmp.Compare(a[0], b[0])
+	})
+
+	next := target[0]
+
+	for _, byteRange := range ranges {
+
+		if byteRange[0] > next {
+			return false
+		}
+
+		next = max(next, byteRange[1]+1)
+	}
+
+	return next > target[1]
+}
+
+// progressCounter passes the number of bytes written through it to a function.
+type progressCounter func(count int64)
+
+func (counter progressCounter) Write(p []byte) (int, error) {
+	counter(int64(len(p)))
+	return len(p), nil
+}
@@ -0,0 +1,271 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// parallelContent is a file large enough to be split into several ranges.
+var parallelContent = func() []byte {
+	result := make([]byte, 3*minDownloadChunkSize+12345)
+	for index := range result {
+		result[index] = byte(index % 251)
+	}
+	return result
+}()
+
+// parallelOption serves parallelContent, supporting HEAD, Range and If-Range
+// requests. Each request's method and Range header is appended to requests.
+func parallelOption(etag string, requests *[]string) Option {
+
+	var mutex sync.Mutex
+
+	return handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		*requests = append(*requests, r.Method+" "+r.Header.Get("Range"))
+		mutex.Unlock()
+
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(parallelContent))
+	})
+}
+
+func TestDownload_Parallel(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "export.bin")
+	requests := []string{}
+	digest := sha256.Sum256(parallelContent)
+
+	var mutex sync.Mutex
+	progress := int64(0)
+	calls := 0
+
+	transaction := Get("https://example.com/export.bin").
+		With(parallelOption(`"v1"`, &requests)).
+		ProgressInterval(time.Nanosecond).
+		Downloads(DownloadPolicy{
+			Parallel: 3,
+			Checksum: hex.EncodeToString(digest[:]),
+			Progress: func(written int64, total int64) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				require.GreaterOrEqual(t, written, progress)
+				require.Equal(t, int64(len(parallelContent)), total)
+				progress = written
+				calls++
+			},
+		})
+
+	require.NoError(t, transaction.Download(context.Background(), dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, parallelContent, content)
+	require.Equal(t, int64(len(parallelContent)), progress)
+	require.Greater(t, calls, 3)
+
+	// One probe, then three ranges
+	require.Equal(t, "HEAD ", requests[0])
+	require.ElementsMatch(t, []string{"GET bytes=0-1052690", "GET bytes=1052691-2105381", "GET bytes=2105382-3158072"}, requests[1:])
+	require.Equal(t, http.MethodHead, transaction.Request().Method)
+
+	require.NoFileExists(t, dst+downloadPartSuffix)
+	require.NoFileExists(t, dst+downloadETagSuffix)
+}
+
+func TestDownload_ParallelCoalesced(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "export.bin")
+
+	// The server answers every range with a multipart body, in two parts that
+	// overlap and arrive out of order.
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Accept-Ranges", "bytes")
+
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(parallelContent)))
+			return
+		}
+
+		var start, end int64
+		_, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		require.NoError(t, err)
+
+		middle := (start + end) / 2
+		parts := [][2]int64{{middle - 100, end}, {start, middle}}
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+
+		for _, part := range parts {
+			partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+				"Content-Type":  {"application/octet-stream"},
+				"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", part[0], part[1], len(parallelContent))},
+			})
+			require.NoError(t, err)
+			_, _ = partWriter.Write(parallelContent[part[0] : part[1]+1])
+		}
+
+		require.NoError(t, writer.Close())
+
+		w.Header().Set(ContentType, ContentTypeMultipartByteRanges+"; boundary="+writer.Boundary())
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(body.Bytes())
+	})
+
+	require.NoError(t, Get("https://example.com/export.bin").With(option).Downloads(DownloadPolicy{Parallel: 2}).Download(context.Background(), dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, parallelContent, content)
+}
+
+func TestDownload_ParallelFallback(t *testing.T) {
+
+	tests := map[string]func(http.ResponseWriter, *http.Request){
+
+		"no ranges": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write(parallelContent)
+		},
+
+		"weak etag": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `W/"v1"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(parallelContent))
+		},
+
+		"no HEAD": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(parallelContent))
+		},
+	}
+
+	for name, handler := range tests {
+
+		dst := filepath.Join(t.TempDir(), "export.bin")
+		gets := 0
+
+		option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				gets++
+				require.Empty(t, r.Header.Get("Range"), name)
+			}
+			handler(w, r)
+		})
+
+		require.NoError(t, Get("https://example.com/export.bin").With(option).Downloads(DownloadPolicy{Parallel: 4}).Download(context.Background(), dst), name)
+		require.Equal(t, 1, gets, name)
+
+		content, err := os.ReadFile(dst)
+		require.NoError(t, err, name)
+		require.Equal(t, parallelContent, content, name)
+	}
+}
+
+func TestDownload_ParallelSmallFile(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	// Files smaller than two chunks are downloaded in a single request
+	require.NoError(t, Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).Downloads(DownloadPolicy{Parallel: 8}).Download(context.Background(), dst))
+	require.Equal(t, []string{"", ""}, ranges)
+}
+
+func TestDownload_ParallelFileChanged(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "export.bin")
+	var requests atomic.Int32
+
+	// The file changes after the probe, so If-Range no longer matches
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"v2"`
+
+		if requests.Add(1) == 1 {
+			etag = `"v1"`
+		}
+
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(parallelContent))
+	})
+
+	err := Get("https://example.com/export.bin").With(option).Downloads(DownloadPolicy{Parallel: 2}).Download(context.Background(), dst)
+	require.Error(t, err)
+	require.NoFileExists(t, dst)
+	require.NoFileExists(t, dst+downloadPartSuffix)
+}
+
+func TestDownload_ParallelLimits(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "export.bin")
+	requests := []string{}
+
+	// MaxResponseSize is checked before any range is requested
+	err := Get("https://example.com/export.bin").With(parallelOption(`"v1"`, &requests)).MaxResponseSize(1<<20).Downloads(DownloadPolicy{Parallel: 2}).Download(context.Background(), dst)
+	require.Error(t, err)
+	require.Equal(t, []string{"HEAD "}, requests)
+
+	// Checksums are verified once every range is in place
+	requests = []string{}
+	err = Get("https://example.com/export.bin").With(parallelOption(`"v1"`, &requests)).Downloads(DownloadPolicy{Parallel: 2, Checksum: hex.EncodeToString(make([]byte, 32))}).Download(context.Background(), dst)
+	require.Error(t, err)
+	require.Len(t, requests, 3)
+	require.NoFileExists(t, dst)
+	require.NoFileExists(t, dst+downloadPartSuffix)
+}
+
+func TestDownload_ParallelNetwork(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "export.bin", time.Time{}, bytes.NewReader(parallelContent))
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "export.bin")
+	require.NoError(t, Get(server.URL).AllowPrivateIPs(true).Downloads(DownloadPolicy{Parallel: 3}).Download(context.Background(), dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, parallelContent, content)
+}
+
+func TestDownloadChunks(t *testing.T) {
+
+	require.Nil(t, downloadChunks(1000, 4))
+	require.Equal(t, [][2]int64{{0, minDownloadChunkSize*2 - 1}}, downloadChunks(minDownloadChunkSize*2, 1))
+	require.Equal(t, [][2]int64{{0, minDownloadChunkSize - 1}, {minDownloadChunkSize, minDownloadChunkSize*2 - 1}}, downloadChunks(minDownloadChunkSize*2, 4))
+	require.Equal(t, [][2]int64{{0, 1398101}, {1398102, 2796203}, {2796204, 4194303}}, downloadChunks(4*minDownloadChunkSize, 3))
+}
+
+func TestRangesCover(t *testing.T) {
+
+	require.True(t, rangesCover([][2]int64{{0, 99}}, [2]int64{0, 99}))
+	require.True(t, rangesCover([][2]int64{{50, 99}, {0, 60}}, [2]int64{0, 99}))
+	require.True(t, rangesCover([][2]int64{{10, 19}, {0, 9}, {20, 29}}, [2]int64{0, 29}))
+	require.False(t, rangesCover([][2]int64{{0, 49}, {51, 99}}, [2]int64{0, 99}))
+	require.False(t, rangesCover([][2]int64{{0, 98}}, [2]int64{0, 99}))
+	require.False(t, rangesCover(nil, [2]int64{0, 99}))
+}
@@ -0,0 +1,466 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+// downloadPartSuffix names the file that holds a download while it is in progress.
+const downloadPartSuffix = ".part"
+
+// downloadETagSuffix names the file that holds the ETag of a partial download,
+// which is sent in the If-Range header when the download is resumed.
+const downloadETagSuffix = ".part.etag"
+
+// DownloadPolicy configures how Download saves a response to a file.
+type DownloadPolicy struct {
+	Checksum      string       // (if set) expected checksum of the complete file: a hex-encoded sha256 digest, or a Subresource Integrity string such as "sha256-<base64>"
+	DisableResume bool         // if TRUE, discard any partial download and start over
+	Progress      ProgressFunc // (if set) called as the file is written (at most once per ProgressInterval), with the bytes written so far and the total size (-1 if unknown)
+	Parallel      int          // (if set) split large files into this many concurrent range requests, if the server supports them
+}
+
+// Downloads sets how Download verifies the file it saves, whether it resumes
+// partial downloads, and how it reports progress.
+func (t *Transaction) Downloads(policy DownloadPolicy) *Transaction {
+	t.downloadPolicy = policy
+	return t
+}
+
+// Download sends the transaction and streams the response body into the file
+// at dst, without holding it in memory. The body is written to "dst.part" and
+// renamed to dst once it is complete (and matches the expected checksum, if
+// one is set), so dst never contains a partial file.
+//
+// If an earlier download was interrupted, Download resumes it with a Range
+// request. The If-Range header carries the ETag of the partial file, so the
+// server sends the whole file again if it has changed since. With the Parallel
+// policy, a HEAD request checks that the server accepts ranges, and large files
+// are requested in several ranges at once and written into place as they
+// arrive. Parallel downloads are not resumed; if one fails, it starts over.
+// Responses are requested without compression, because byte ranges and
+// checksums refer to the file as it is stored.
+//
+// With IfNoneMatch or IfModifiedSince, a 304 Not Modified response leaves dst
+// as it is, and NotModified returns TRUE.
+//
+// Download is bounded by ctx and Timeouts, rather than the one-minute default
+// that applies to Send, so that large files have time to arrive; set
+// Timeouts.BodyIdle to abort a transfer that stalls. MaxResponseSize limits
+// the size of the complete file. Concurrent downloads must use different
+// destinations.
+func (t *Transaction) Download(ctx context.Context, dst string) error {
+
+	const location = "remote.Transaction.Download"
+
+	// Validate the checksum before contacting the server
+	expected, err := parseChecksum(t.downloadPolicy.Checksum)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Invalid checksum", t.downloadPolicy.Checksum)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Restore the settings that Download changes when it is done
+	originalCtx, originalCompression, originalHeader := t.ctx, t.compression, maps.Clone(t.header)
+
+	defer func() {
+		t.ctx = originalCtx
+		t.compression = originalCompression
+		t.header = originalHeader
+		t.streamBody = nil
+	}()
+
+	t.ctx = ctx
+	t.compression.Disabled = true
+
+	download := &downloader{
+		transaction: t,
+		path:        dst,
+		policy:      t.downloadPolicy,
+		checksum:    expected,
+	}
+
+	if t.downloadPolicy.DisableResume {
+		download.discard()
+	}
+
+	// Split large files into concurrent range requests, unless a partial download can be resumed
+	if (t.downloadPolicy.Parallel > 1) && (t.method == http.MethodGet) {
+		if offset, etag := download.partial(); (offset == 0) || (etag == "") {
+			if isParallel, err := download.parallel(ctx); isParallel {
+
+				if err != nil {
+					return derp.Wrap(err, location, "Unable to download file", dst)
+				}
+
+				return nil
+			}
+		}
+	}
+
+	t.streamBody = download.write
+
+	err = download.send()
+
+	// If the partial file cannot be resumed, then start over
+	if download.restart {
+		download.discard()
+		err = download.send()
+	}
+
+	if err != nil {
+		return derp.Wrap(err, location, "Unable to download file", dst)
+	}
+
+	return nil
+}
+
+/******************************************
+ * Downloader
+ ******************************************/
+
+// downloader writes the response body of a single Download to disk.
+type downloader struct {
+	transaction *Transaction
+	path        string
+	policy      DownloadPolicy
+	checksum    *checksum
+	offset      int64 // number of bytes already downloaded when the request was sent
+	restart     bool  // TRUE if the partial file could not be resumed
+}
+
+// send requests the file, resuming a partial download if one exists.
+func (d *downloader) send() error {
+
+	t := d.transaction
+
+	delete(t.header, "Range")
+	delete(t.header, "If-Range")
+
+	d.offset = 0
+	d.restart = false
+
+	// Only resume if the partial file can be matched to the server's file
+	if t.method == http.MethodGet {
+		if offset, etag := d.partial(); (offset > 0) && (etag != "") {
+			d.offset = offset
+			t.header["Range"] = "bytes=" + strconv.FormatInt(offset, 10) + "-"
+			t.header["If-Range"] = etag
+		}
+	}
+
+	return t.Send()
+}
+
+// partial returns the size and ETag of a previous, incomplete download.
+func (d *downloader) partial() (int64, string) {
+
+	info, err := os.Stat(d.path + downloadPartSuffix)
+
+	if err != nil {
+		return 0, ""
+	}
+
+	etag, err := os.ReadFile(d.path + downloadETagSuffix)
+
+	if err != nil {
+		return 0, ""
+	}
+
+	return info.Size(), strings.TrimSpace(string(etag))
+}
+
+// discard removes any partial download.
+func (d *downloader) discard() {
+	_ = os.Remove(d.path + downloadPartSuffix)
+	_ = os.Remove(d.path + downloadETagSuffix)
+}
+
+// write saves the response body to the partial file, and moves it into place
+// once it is complete. It is the transaction's streamBody function.
+func (d *downloader) write(response *http.Response) error {
+
+	const location = "remote.downloader.write"
+
+	t := d.transaction
+
+	maxSize := t.maxResponseSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxResponseSize
+	}
+
+	offset := int64(0)
+	total := response.ContentLength
+
+	switch statusCode := response.StatusCode; {
+
+	// The server is sending the rest of the partial file
+	case (statusCode == http.StatusPartialContent) && (d.offset > 0):
+
+		start, _, length, isValid := parseContentRange(response.Header.Get("Content-Range"))
+
+		if !isValid || (start != d.offset) {
+			d.restart = true
+			return derp.Internal(location, "Server returned an unexpected range", response.Header.Get("Content-Range"), d.offset)
+		}
+
+		offset, total = start, length
+
+	// The server is sending the whole file
+	case (statusCode >= 200) && (statusCode <= 299) && (statusCode != http.StatusPartialContent):
+
+	// The partial file is no longer valid
+	case (statusCode == http.StatusRequestedRangeNotSatisfiable) && (d.offset > 0):
+		d.restart = true
+		return derp.NewHTTPError(t.request, response)
+
+	// A range that was not requested
+	case statusCode == http.StatusPartialContent:
+		return derp.Internal(location, "Server returned a range that was not requested", response.Header.Get("Content-Range"))
+
+	// Otherwise, this is an error response
+	default:
+		body, err := t.ResponseBody()
+
+		if err != nil {
+			return derp.Wrap(err, location, "Reading response body")
+		}
+
+		return t.processResponse(body)
+	}
+
+	if total > maxSize {
+		d.discard()
+		return derp.Internal(location, "Response body exceeds maximum size", maxSize)
+	}
+
+	// Remember the ETag, so that an interrupted download can be resumed
+	if etag := response.Header.Get("ETag"); (etag != "") && !strings.HasPrefix(etag, "W/") {
+		if err := os.WriteFile(d.path+downloadETagSuffix, []byte(etag), 0o644); err != nil {
+			return derp.Wrap(err, location, "Unable to save ETag", d.path)
+		}
+	} else {
+		_ = os.Remove(d.path + downloadETagSuffix)
+	}
+
+	file, hash, err := d.open(offset)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Unable to open partial file", d.path)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// Write the body to the file, the checksum, and the progress callback
+	writers := []io.Writer{file}
+
+	if hash != nil {
+		writers = append(writers, hash)
+	}
+
+	reporter := t.newProgressReporter(d.policy.Progress)
+
+	if reporter != nil {
+		progress := offset
+		reporter.report(offset, total, false)
+		writers = append(writers, progressCounter(func(count int64) {
+			progress += count
+			reporter.report(progress, total, progress == total)
+		}))
+	}
+
+	limit := maxSize - offset
+	written, err := io.Copy(io.MultiWriter(writers...), io.LimitReader(response.Body, limit+1))
+
+	if err != nil {
+		return derp.Wrap(err, location, "Unable to download response body", offset+written)
+	}
+
+	if written > limit {
+		d.discard()
+		return derp.Internal(location, "Response body exceeds maximum size", maxSize)
+	}
+
+	// Once the file is complete, its size is known
+	if reporter != nil {
+		reporter.report(offset+written, offset+written, true)
+	}
+
+	return d.finish(file, hash)
+}
+
+// finish saves the partial file, verifies its checksum, and moves it into place.
+func (d *downloader) finish(file *os.File, hash hash.Hash) error {
+
+	const location = "remote.downloader.finish"
+
+	if err := file.Sync(); err != nil {
+		return derp.Wrap(err, location, "Unable to save partial file", d.path)
+	}
+
+	if err := file.Close(); err != nil {
+		return derp.Wrap(err, location, "Unable to save partial file", d.path)
+	}
+
+	// Verify the complete file before moving it into place
+	if (hash != nil) && !d.checksum.matches(hash.Sum(nil)) {
+		d.discard()
+		return derp.Internal(location, "Downloaded file does not match checksum", d.policy.Checksum)
+	}
+
+	if err := os.Rename(d.path+downloadPartSuffix, d.path); err != nil {
+		return derp.Wrap(err, location, "Unable to move file into place", d.path)
+	}
+
+	_ = os.Remove(d.path + downloadETagSuffix)
+	return nil
+}
+
+// open opens the partial file, keeping the first offset bytes, and returns a
+// hash (if a checksum is expected) that has already read them.
+func (d *downloader) open(offset int64) (*os.File, hash.Hash, error) {
+
+	file, err := os.OpenFile(d.path+downloadPartSuffix, os.O_RDWR|os.O_CREATE, 0o644)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+
+	var hash hash.Hash
+
+	if d.checksum != nil {
+		hash = d.checksum.newHash()
+
+		if _, err := io.Copy(hash, io.NewSectionReader(file, 0, offset)); err != nil {
+			_ = file.Close()
+			return nil, nil, err
+		}
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+
+	return file, hash, nil
+}
+
+/******************************************
+ * Checksums
+ ******************************************/
+
+// checksum is the expected digest of a downloaded file.
+type checksum struct {
+	newHash func() hash.Hash
+	digests [][]byte // any of these digests is a match
+}
+
+// checksumAlgorithms are the Subresource Integrity hash algorithms, weakest first.
+var checksumAlgorithms = []struct {
+	name    string
+	newHash func() hash.Hash
+}{
+	{"sha256", sha256.New},
+	{"sha384", sha512.New384},
+	{"sha512", sha512.New},
+}
+
+// parseChecksum parses an expected checksum, which is either a hex-encoded
+// sha256 digest or a Subresource Integrity string ("sha256-<base64>"). SRI
+// strings may list several digests, and only the strongest algorithm is used.
+// It returns nil if no checksum is expected.
+func parseChecksum(value string) (*checksum, error) {
+
+	const location = "remote.parseChecksum"
+
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return nil, nil
+	}
+
+	// Hex-encoded sha256 digest
+	if digest, err := hex.DecodeString(value); (err == nil) && (len(digest) == sha256.Size) {
+		return &checksum{newHash: sha256.New, digests: [][]byte{digest}}, nil
+	}
+
+	// Subresource Integrity (https://www.w3.org/TR/SRI/)
+	strongest := -1
+	result := &checksum{}
+
+	for _, field := range strings.Fields(value) {
+
+		name, encoded, found := strings.Cut(field, "-")
+
+		if !found {
+			continue
+		}
+
+		encoded, _, _ = strings.Cut(encoded, "?") // Remove options, which are reserved
+
+		for index, algorithm := range checksumAlgorithms {
+
+			if name != algorithm.name {
+				continue
+			}
+
+			digest, err := base64.StdEncoding.DecodeString(encoded)
+
+			if (err != nil) || (len(digest) != algorithm.newHash().Size()) {
+				return nil, derp.BadRequest(location, "Invalid digest", field)
+			}
+
+			if index > strongest {
+				strongest = index
+				result.newHash = algorithm.newHash
+				result.digests = nil
+			}
+
+			if index == strongest {
+				result.digests = append(result.digests, digest)
+			}
+		}
+	}
+
+	if strongest < 0 {
+		return nil, derp.BadRequest(location, "Checksum must be a sha256 digest or a Subresource Integrity string", value)
+	}
+
+	return result, nil
+}
+
+// matches returns TRUE if the digest matches one of the expected digests.
+func (checksum *checksum) matches(digest []byte) bool {
+
+	for _, expected := range checksum.digests {
+		if bytes.Equal(expected, digest) {
+			return true
+		}
+	}
+
+	return false
+}
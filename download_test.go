@@ -0,0 +1,364 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// downloadContent is the file served by downloadOption.
+var downloadContent = bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+// downloadOption serves downloadContent with an ETag, supporting Range and If-Range
+// requests. Each request's Range header is appended to ranges.
+func downloadOption(etag string, ranges *[]string) Option {
+	return handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(downloadContent))
+	})
+}
+
+func TestDownload(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	digest := sha256.Sum256(downloadContent)
+	transaction := Get("https://example.com/file.bin").
+		With(downloadOption(`"v1"`, &ranges)).
+		Downloads(DownloadPolicy{Checksum: hex.EncodeToString(digest[:])})
+
+	require.NoError(t, transaction.Download(context.Background(), dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+	require.Equal(t, []string{""}, ranges)
+	require.Equal(t, "identity", transaction.Request().Header.Get("Accept-Encoding"))
+
+	// Temporary files are removed
+	require.NoFileExists(t, dst+downloadPartSuffix)
+	require.NoFileExists(t, dst+downloadETagSuffix)
+
+	// The transaction's settings are restored
+	require.False(t, transaction.compression.Disabled)
+	require.Nil(t, transaction.streamBody)
+}
+
+func TestDownload_Resume(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	// An earlier download was interrupted halfway through
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, downloadContent[:1000], 0o644))
+	require.NoError(t, os.WriteFile(dst+downloadETagSuffix, []byte(`"v1"`), 0o644))
+
+	digest := sha256.Sum256(downloadContent)
+	progress := [][2]int64{}
+
+	transaction := Get("https://example.com/file.bin").
+		With(downloadOption(`"v1"`, &ranges)).
+		Downloads(DownloadPolicy{
+			Checksum: "sha256-" + base64.StdEncoding.EncodeToString(digest[:]),
+			Progress: func(written int64, total int64) {
+				progress = append(progress, [2]int64{written, total})
+			},
+		})
+
+	require.NoError(t, transaction.Download(context.Background(), dst))
+	require.Equal(t, []string{"bytes=1000-"}, ranges)
+	require.Equal(t, `"v1"`, transaction.Request().Header.Get("If-Range"))
+	require.Equal(t, http.StatusPartialContent, transaction.ResponseStatusCode())
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+
+	// Progress starts from the partial file, and ends with the whole file
+	total := int64(len(downloadContent))
+	require.Equal(t, [2]int64{1000, total}, progress[0])
+	require.Equal(t, [2]int64{total, total}, progress[len(progress)-1])
+
+	// Range headers are not left on the transaction
+	require.NotContains(t, transaction.header, "Range")
+	require.NotContains(t, transaction.header, "If-Range")
+}
+
+func TestDownload_ResumeChangedFile(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	// The partial file belongs to an older version, so the whole file is sent again
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, []byte("old version of the file"), 0o644))
+	require.NoError(t, os.WriteFile(dst+downloadETagSuffix, []byte(`"v1"`), 0o644))
+
+	require.NoError(t, Get("https://example.com/file.bin").With(downloadOption(`"v2"`, &ranges)).Download(context.Background(), dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+	require.Equal(t, []string{"bytes=23-"}, ranges)
+}
+
+func TestDownload_ResumeWithoutETag(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	// Without an ETag, the partial file cannot be matched, so it is replaced
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, []byte("unknown"), 0o644))
+
+	require.NoError(t, Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).Download(context.Background(), dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+	require.Equal(t, []string{""}, ranges)
+}
+
+func TestDownload_DisableResume(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, downloadContent[:1000], 0o644))
+	require.NoError(t, os.WriteFile(dst+downloadETagSuffix, []byte(`"v1"`), 0o644))
+
+	transaction := Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).Downloads(DownloadPolicy{DisableResume: true})
+	require.NoError(t, transaction.Download(context.Background(), dst))
+	require.Equal(t, []string{""}, ranges)
+}
+
+func TestDownload_RangeNotSatisfiable(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	// The partial file is longer than the server's file, so the download starts over
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, append(bytes.Clone(downloadContent), "extra"...), 0o644))
+	require.NoError(t, os.WriteFile(dst+downloadETagSuffix, []byte(`"v1"`), 0o644))
+
+	require.NoError(t, Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).Download(context.Background(), dst))
+	require.Equal(t, []string{"bytes=65541-", ""}, ranges)
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+}
+
+func TestDownload_UnexpectedRange(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	requests := 0
+
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, downloadContent[:1000], 0o644))
+	require.NoError(t, os.WriteFile(dst+downloadETagSuffix, []byte(`"v1"`), 0o644))
+
+	// The server returns a different range than the one requested
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", "bytes 0-99/65536")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(downloadContent[:100])
+			return
+		}
+
+		_, _ = w.Write(downloadContent)
+	})
+
+	require.NoError(t, Get("https://example.com/file.bin").With(option).Download(context.Background(), dst))
+	require.Equal(t, 2, requests)
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+}
+
+func TestDownload_Interrupted(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+
+	// The connection fails halfway through the body
+	option := Option{
+		ModifyRequest: func(_ *Transaction, request *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        http.Header{"Etag": []string{`"v1"`}},
+				Body:          io.NopCloser(io.MultiReader(bytes.NewReader(downloadContent[:2048]), failingReader{})),
+				ContentLength: int64(len(downloadContent)),
+				Request:       request,
+			}
+		},
+	}
+
+	require.Error(t, Get("https://example.com/file.bin").With(option).Download(context.Background(), dst))
+	require.NoFileExists(t, dst)
+
+	// The partial file is kept, ready to resume
+	partial, err := os.ReadFile(dst + downloadPartSuffix)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent[:2048], partial)
+
+	etag, err := os.ReadFile(dst + downloadETagSuffix)
+	require.NoError(t, err)
+	require.Equal(t, `"v1"`, string(etag))
+
+	// ...and resuming completes the file
+	ranges := []string{}
+	require.NoError(t, Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).Download(context.Background(), dst))
+	require.Equal(t, []string{"bytes=2048-"}, ranges)
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+}
+
+// failingReader always returns an error.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestDownload_ChecksumMismatch(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	digest := sha256.Sum256([]byte("something else"))
+	transaction := Get("https://example.com/file.bin").
+		With(downloadOption(`"v1"`, &ranges)).
+		Downloads(DownloadPolicy{Checksum: hex.EncodeToString(digest[:])})
+
+	require.Error(t, transaction.Download(context.Background(), dst))
+	require.NoFileExists(t, dst)
+	require.NoFileExists(t, dst+downloadPartSuffix)
+	require.NoFileExists(t, dst+downloadETagSuffix)
+
+	// Invalid checksums are rejected before the request is sent
+	require.Error(t, Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).Downloads(DownloadPolicy{Checksum: "md5-abc"}).Download(context.Background(), dst))
+	require.Len(t, ranges, 1)
+}
+
+func TestDownload_ErrorResponse(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(ContentType, ContentTypeJSON)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"missing"}`))
+	})
+
+	failure := map[string]string{}
+	require.Error(t, Get("https://example.com/file.bin").With(option).Error(&failure).Download(context.Background(), dst))
+	require.Equal(t, "missing", failure["error"])
+	require.NoFileExists(t, dst)
+	require.NoFileExists(t, dst+downloadPartSuffix)
+}
+
+func TestDownload_MaxResponseSize(t *testing.T) {
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ranges := []string{}
+
+	// Rejected by Content-Length
+	require.Error(t, Get("https://example.com/file.bin").With(downloadOption(`"v1"`, &ranges)).MaxResponseSize(1000).Download(context.Background(), dst))
+	require.NoFileExists(t, dst)
+
+	// Rejected while reading a body of unknown length
+	option := Option{
+		ModifyRequest: func(_ *Transaction, request *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Body:          io.NopCloser(bytes.NewReader(downloadContent)),
+				ContentLength: -1,
+				Request:       request,
+			}
+		},
+	}
+
+	require.Error(t, Get("https://example.com/file.bin").With(option).MaxResponseSize(1000).Download(context.Background(), dst))
+	require.NoFileExists(t, dst)
+	require.NoFileExists(t, dst+downloadPartSuffix)
+}
+
+func TestDownload_Network(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(downloadContent))
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, downloadContent[:5000], 0o644))
+	require.NoError(t, os.WriteFile(dst+downloadETagSuffix, []byte(`"v1"`), 0o644))
+
+	transaction := Get(server.URL).AllowPrivateIPs(true)
+	require.NoError(t, transaction.Download(context.Background(), dst))
+	require.Equal(t, http.StatusPartialContent, transaction.ResponseStatusCode())
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, downloadContent, content)
+
+	// The private-IP guard still applies
+	require.Error(t, Get(server.URL).Download(context.Background(), filepath.Join(t.TempDir(), "blocked.bin")))
+}
+
+func TestParseChecksum(t *testing.T) {
+
+	sha256Digest := sha256.Sum256([]byte("hello"))
+	sha512Digest := sha512.Sum512([]byte("hello"))
+
+	sha256SRI := "sha256-" + base64.StdEncoding.EncodeToString(sha256Digest[:])
+	sha512SRI := "sha512-" + base64.StdEncoding.EncodeToString(sha512Digest[:])
+
+	// No checksum
+	result, err := parseChecksum("  ")
+	require.NoError(t, err)
+	require.Nil(t, result)
+
+	// Hex sha256
+	result, err = parseChecksum(strings.ToUpper(hex.EncodeToString(sha256Digest[:])))
+	require.NoError(t, err)
+	require.True(t, result.matches(sha256Digest[:]))
+
+	// SRI, with options
+	result, err = parseChecksum(sha256SRI + "?ct=application/octet-stream")
+	require.NoError(t, err)
+	require.True(t, result.matches(sha256Digest[:]))
+
+	// The strongest algorithm wins, and unknown algorithms are ignored
+	result, err = parseChecksum("md5-XUFAKrxLKna5cZ2REBfFkg== " + sha256SRI + " " + sha512SRI)
+	require.NoError(t, err)
+	require.True(t, result.matches(sha512Digest[:]))
+	require.False(t, result.matches(sha256Digest[:]))
+
+	// Invalid checksums
+	for _, value := range []string{"abc", "md5-XUFAKrxLKna5cZ2REBfFkg==", "sha256-tooShort", "sha256-!!!"} {
+		_, err := parseChecksum(value)
+		require.Error(t, err, value)
+	}
+}
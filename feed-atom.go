@@ -0,0 +1,382 @@
+package remote
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+)
+
+// xmlNamespace is the namespace of the reserved "xml" prefix, used by xml:base and xml:lang.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// AtomFeed is an Atom 1.0 document (RFC 4287), including Media RSS
+// thumbnails. Elements are matched by namespace, whatever prefix the feed
+// uses, and feeds that omit the Atom namespace are also accepted.
+type AtomFeed struct {
+	Base         string // xml:base
+	Lang         string // xml:lang
+	ID           string
+	Title        AtomText
+	Subtitle     AtomText
+	Updated      string
+	Links        []AtomLink
+	Authors      []AtomPerson
+	Contributors []AtomPerson
+	Categories   []AtomCategory
+	Generator    string
+	Icon         string
+	Logo         string
+	Rights       AtomText
+	Entries      []AtomEntry
+}
+
+// AtomEntry is an <entry> element of an Atom feed.
+type AtomEntry struct {
+	Base            string // xml:base
+	ID              string
+	Title           AtomText
+	Summary         AtomText
+	Content         AtomText
+	Links           []AtomLink
+	Authors         []AtomPerson
+	Contributors    []AtomPerson
+	Categories      []AtomCategory
+	Published       string
+	Updated         string
+	Rights          AtomText
+	MediaContents   []FeedMedia
+	MediaThumbnails []FeedMedia
+	MediaGroup      FeedMediaGroup
+}
+
+// AtomLink is an Atom <link> element, which RSS feeds also use as <atom:link>.
+type AtomLink struct {
+	Href     string `xml:"href,attr"`
+	Rel      string `xml:"rel,attr"`
+	Type     string `xml:"type,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+	Title    string `xml:"title,attr"`
+	Length   string `xml:"length,attr"`
+}
+
+// AtomPerson is an Atom <author> or <contributor> element.
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+	URI   string `xml:"uri"`
+}
+
+// AtomCategory is an Atom <category> element.
+type AtomCategory struct {
+	Term   string `xml:"term,attr"`
+	Scheme string `xml:"scheme,attr"`
+	Label  string `xml:"label,attr"`
+}
+
+// AtomText is an Atom text construct, such as <title> or <content>. Type is
+// "text", "html", or "xhtml". Src is set for content that is stored elsewhere.
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Src      string `xml:"src,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+// Value returns the text, or (for "xhtml" text) the XHTML markup.
+func (text AtomText) Value() string {
+
+	if strings.EqualFold(text.Type, "xhtml") {
+		return strings.TrimSpace(text.InnerXML)
+	}
+
+	return strings.TrimSpace(text.Text)
+}
+
+// IsHTML reports whether the text is HTML or XHTML markup.
+func (text AtomText) IsHTML() bool {
+
+	switch strings.ToLower(text.Type) {
+	case "html", "xhtml", "text/html", "application/xhtml+xml":
+		return true
+	}
+
+	return false
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface, matching elements
+// by namespace so that (for example) <media:title> is not read as <title>.
+func (feed *AtomFeed) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+
+	if start.Name.Local != "feed" {
+		return xml.UnmarshalError("expected element <feed> but have <" + start.Name.Local + ">")
+	}
+
+	feed.Base = xmlAttribute(start, xmlNamespace, "base")
+	feed.Lang = xmlAttribute(start, xmlNamespace, "lang")
+
+	return decodeFeedElements(decoder, "atom", func(name string, _ xml.StartElement) any {
+
+		switch name {
+
+		case "id":
+			return &feed.ID
+		case "title":
+			return &feed.Title
+		case "subtitle", "tagline":
+			return &feed.Subtitle
+		case "updated", "modified":
+			return &feed.Updated
+		case "generator":
+			return &feed.Generator
+		case "icon":
+			return &feed.Icon
+		case "logo":
+			return &feed.Logo
+		case "rights", "copyright":
+			return &feed.Rights
+
+		case "link":
+			feed.Links = append(feed.Links, AtomLink{})
+			return &feed.Links[len(feed.Links)-1]
+
+		case "author":
+			feed.Authors = append(feed.Authors, AtomPerson{})
+			return &feed.Authors[len(feed.Authors)-1]
+
+		case "contributor":
+			feed.Contributors = append(feed.Contributors, AtomPerson{})
+			return &feed.Contributors[len(feed.Contributors)-1]
+
+		case "category":
+			feed.Categories = append(feed.Categories, AtomCategory{})
+			return &feed.Categories[len(feed.Categories)-1]
+
+		case "entry":
+			feed.Entries = append(feed.Entries, AtomEntry{})
+			return &feed.Entries[len(feed.Entries)-1]
+		}
+
+		return nil
+	})
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface, matching elements
+// by namespace so that (for example) <media:content> is not read as <content>.
+func (entry *AtomEntry) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+
+	entry.Base = xmlAttribute(start, xmlNamespace, "base")
+
+	return decodeFeedElements(decoder, "atom", func(name string, _ xml.StartElement) any {
+
+		switch name {
+
+		case "id":
+			return &entry.ID
+		case "title":
+			return &entry.Title
+		case "summary":
+			return &entry.Summary
+		case "content":
+			return &entry.Content
+		case "published", "issued":
+			return &entry.Published
+		case "updated", "modified":
+			return &entry.Updated
+		case "rights":
+			return &entry.Rights
+		case "media:group":
+			return &entry.MediaGroup
+
+		case "link":
+			entry.Links = append(entry.Links, AtomLink{})
+			return &entry.Links[len(entry.Links)-1]
+
+		case "author":
+			entry.Authors = append(entry.Authors, AtomPerson{})
+			return &entry.Authors[len(entry.Authors)-1]
+
+		case "contributor":
+			entry.Contributors = append(entry.Contributors, AtomPerson{})
+			return &entry.Contributors[len(entry.Contributors)-1]
+
+		case "category":
+			entry.Categories = append(entry.Categories, AtomCategory{})
+			return &entry.Categories[len(entry.Categories)-1]
+
+		case "media:content":
+			entry.MediaContents = append(entry.MediaContents, FeedMedia{})
+			return &entry.MediaContents[len(entry.MediaContents)-1]
+
+		case "media:thumbnail":
+			entry.MediaThumbnails = append(entry.MediaThumbnails, FeedMedia{})
+			return &entry.MediaThumbnails[len(entry.MediaThumbnails)-1]
+		}
+
+		return nil
+	})
+}
+
+// Feed returns the normalized version of the feed, with relative URLs
+// resolved against xml:base and base (which may be nil).
+func (feed AtomFeed) Feed(base *url.URL) Feed {
+
+	base = feedBase(base, feed.Base)
+
+	result := Feed{
+		Format:      FeedFormatAtom,
+		Title:       feed.Title.Value(),
+		Description: feed.Subtitle.Value(),
+		HomePageURL: feedURL(base, atomAlternate(feed.Links)),
+		FeedURL:     feedURL(base, atomLinkHref(feed.Links, "self")),
+		NextURL:     feedURL(base, atomLinkHref(feed.Links, "next")),
+		ImageURL:    feedURL(base, feed.Logo),
+		IconURL:     feedURL(base, feed.Icon),
+		Language:    strings.TrimSpace(feed.Lang),
+		Authors:     atomAuthors(base, feed.Authors),
+		Items:       make([]Item, 0, len(feed.Entries)),
+	}
+
+	result.Updated, _ = ParseFeedDate(feed.Updated)
+
+	for _, entry := range feed.Entries {
+
+		item := entry.Item(base)
+
+		// Entries inherit the feed's authors (RFC 4287, section 4.2.1)
+		if len(item.Authors) == 0 {
+			item.Authors = append(item.Authors, result.Authors...)
+		}
+
+		result.Items = append(result.Items, item)
+	}
+
+	return result
+}
+
+// Item returns the normalized version of the entry, with relative URLs
+// resolved against xml:base and base (which may be nil).
+func (entry AtomEntry) Item(base *url.URL) Item {
+
+	base = feedBase(base, entry.Base)
+
+	result := Item{
+		ID:          strings.TrimSpace(entry.ID),
+		URL:         feedURL(base, atomAlternate(entry.Links)),
+		ExternalURL: feedURL(base, atomLinkHref(entry.Links, "related")),
+		Title:       entry.Title.Value(),
+		Summary:     entry.Summary.Value(),
+		Authors:     atomAuthors(base, entry.Authors),
+		Tags:        []string{},
+		Enclosures:  []Enclosure{},
+	}
+
+	if result.ID == "" {
+		result.ID = result.URL
+	}
+
+	if entry.Content.Src == "" {
+		if entry.Content.IsHTML() {
+			result.ContentHTML = entry.Content.Value()
+		} else {
+			result.ContentText = entry.Content.Value()
+		}
+	}
+
+	for _, category := range entry.Categories {
+		result.Tags = append(result.Tags, firstNonEmpty(category.Term, category.Label))
+	}
+
+	result.Tags = feedTags(result.Tags...)
+	result.Published, _ = ParseFeedDate(entry.Published)
+	result.Updated, _ = ParseFeedDate(entry.Updated)
+
+	if result.Published.IsZero() {
+		result.Published = result.Updated
+	}
+
+	// Attachments
+	for _, link := range entry.Links {
+		if atomLinkRel(link) == "enclosure" {
+			if enclosureURL := feedURL(base, link.Href); enclosureURL != "" {
+				result.Enclosures = append(result.Enclosures, Enclosure{
+					URL:    enclosureURL,
+					Type:   strings.TrimSpace(link.Type),
+					Length: parseFeedLength(link.Length),
+					Title:  strings.TrimSpace(link.Title),
+				})
+			}
+		}
+	}
+
+	media := append(append([]FeedMedia{}, entry.MediaContents...), entry.MediaGroup.Contents...)
+	thumbnails := append(append([]FeedMedia{}, entry.MediaThumbnails...), entry.MediaGroup.Thumbnails...)
+
+	for _, content := range media {
+		if !strings.EqualFold(content.Medium, "image") && !strings.HasPrefix(content.Type, "image/") {
+			result.Enclosures = appendMediaEnclosure(base, result.Enclosures, content)
+		}
+	}
+
+	// Image
+	result.ImageURL = feedURL(base, firstNonEmpty(
+		feedMediaImage(thumbnails),
+		feedMediaImage(media),
+		feedEnclosureImage(result.Enclosures),
+	))
+
+	return result
+}
+
+// atomLinkRel returns the relation type of an Atom link, which is "alternate" by default.
+func atomLinkRel(link AtomLink) string {
+
+	if rel := strings.TrimSpace(link.Rel); rel != "" {
+		return rel
+	}
+
+	return "alternate"
+}
+
+// atomAlternate returns the href of the alternate link, preferring HTML.
+func atomAlternate(links []AtomLink) string {
+
+	result := ""
+
+	for _, link := range links {
+
+		if atomLinkRel(link) != "alternate" {
+			continue
+		}
+
+		if mediaType(link.Type) == ContentTypeHTML {
+			return link.Href
+		}
+
+		if result == "" {
+			result = link.Href
+		}
+	}
+
+	return result
+}
+
+// atomAuthors converts Atom people into a list of Authors.
+func atomAuthors(base *url.URL, people []AtomPerson) []Author {
+
+	result := make([]Author, 0, len(people))
+
+	for _, person := range people {
+
+		author := Author{
+			Name:  strings.TrimSpace(person.Name),
+			Email: strings.TrimSpace(person.Email),
+			URL:   feedURL(base, person.URI),
+		}
+
+		if author != (Author{}) {
+			result = append(result, author)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,112 @@
+package remote
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/" xml:base="https://blog.example.com/" xml:lang="en">
+	<id>urn:uuid:60a76c80-d399-11d9-b91C-0003939e0af6</id>
+	<title type="text">Example Blog</title>
+	<subtitle type="html">A blog about &lt;em&gt;examples&lt;/em&gt;</subtitle>
+	<updated>2025-06-10T04:00:00Z</updated>
+	<link rel="self" href="/atom.xml"/>
+	<link rel="alternate" type="application/json" href="/feed.json"/>
+	<link rel="alternate" type="text/html" href="/"/>
+	<author><name>Alice</name><email>alice@example.com</email><uri>/about</uri></author>
+	<icon>/favicon.ico</icon>
+	<logo>/logo.png</logo>
+	<entry>
+		<id>tag:blog.example.com,2025:1</id>
+		<title>First Post</title>
+		<link href="posts/1"/>
+		<link rel="related" href="https://news.example.org/story"/>
+		<link rel="enclosure" type="audio/mpeg" length="1024" title="Audio version" href="audio/1.mp3"/>
+		<published>2025-06-09T08:30:00-04:00</published>
+		<updated>2025-06-09T09:00:00.5-04:00</updated>
+		<summary>Summary of the first post</summary>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Hello, <b>world</b></p></div></content>
+		<category term="examples" label="Examples"/>
+		<category label="Label Only"/>
+		<media:title>Not the entry title</media:title>
+		<media:content url="images/1.jpg" medium="image"/>
+	</entry>
+	<entry xml:base="/archive/">
+		<id>tag:blog.example.com,2025:2</id>
+		<title type="html">Second &amp;amp; Last</title>
+		<link href="2"/>
+		<updated>2025-06-01T00:00:00Z</updated>
+		<author><name>Bob</name></author>
+		<content type="text">Plain text content</content>
+		<media:group>
+			<media:thumbnail url="thumb.jpg"/>
+			<media:content url="video.mp4" type="video/mp4"/>
+		</media:group>
+	</entry>
+</feed>`
+
+func TestAtomFeed(t *testing.T) {
+
+	base, err := url.Parse("https://example.com/feeds/atom.xml")
+	require.NoError(t, err)
+
+	feed, err := ParseFeed([]byte(testAtomFeed), ContentTypeAtomXML, base)
+	require.NoError(t, err)
+
+	require.Equal(t, FeedFormatAtom, feed.Format)
+	require.Equal(t, "Example Blog", feed.Title)
+	require.Equal(t, "A blog about <em>examples</em>", feed.Description)
+	require.Equal(t, "https://blog.example.com/", feed.HomePageURL)
+	require.Equal(t, "https://blog.example.com/atom.xml", feed.FeedURL)
+	require.Equal(t, "https://blog.example.com/logo.png", feed.ImageURL)
+	require.Equal(t, "https://blog.example.com/favicon.ico", feed.IconURL)
+	require.Equal(t, "en", feed.Language)
+	require.Equal(t, []Author{{Name: "Alice", Email: "alice@example.com", URL: "https://blog.example.com/about"}}, feed.Authors)
+	require.True(t, feed.Updated.Equal(time.Date(2025, 6, 10, 4, 0, 0, 0, time.UTC)))
+	require.Len(t, feed.Items, 2)
+
+	// First entry inherits the feed's author
+	item := feed.Items[0]
+	require.Equal(t, "tag:blog.example.com,2025:1", item.ID)
+	require.Equal(t, "https://blog.example.com/posts/1", item.URL)
+	require.Equal(t, "https://news.example.org/story", item.ExternalURL)
+	require.Equal(t, "First Post", item.Title)
+	require.Equal(t, "Summary of the first post", item.Summary)
+	require.Contains(t, item.ContentHTML, "<p>Hello, <b>world</b></p>")
+	require.Equal(t, "", item.ContentText)
+	require.Equal(t, feed.Authors, item.Authors)
+	require.Equal(t, []string{"examples", "Label Only"}, item.Tags)
+	require.True(t, item.Published.Equal(time.Date(2025, 6, 9, 12, 30, 0, 0, time.UTC)))
+	require.True(t, item.Updated.Equal(time.Date(2025, 6, 9, 13, 0, 0, 500_000_000, time.UTC)))
+	require.Equal(t, "https://blog.example.com/images/1.jpg", item.ImageURL)
+	require.Equal(t, []Enclosure{{URL: "https://blog.example.com/audio/1.mp3", Type: "audio/mpeg", Length: 1024, Title: "Audio version"}}, item.Enclosures)
+
+	// Second entry has its own xml:base and author
+	item = feed.Items[1]
+	require.Equal(t, "https://blog.example.com/archive/2", item.URL)
+	require.Equal(t, "Second &amp; Last", item.Title)
+	require.Equal(t, "Plain text content", item.ContentText)
+	require.Equal(t, []Author{{Name: "Bob"}}, item.Authors)
+	require.Equal(t, item.Updated, item.Published)
+	require.Equal(t, "https://blog.example.com/archive/thumb.jpg", item.ImageURL)
+	require.Equal(t, []Enclosure{{URL: "https://blog.example.com/archive/video.mp4", Type: "video/mp4"}}, item.Enclosures)
+}
+
+func TestAtomFeed_NoNamespace(t *testing.T) {
+
+	feed, err := ParseFeed([]byte(`<feed><title>Plain</title><entry><title>Entry</title><link href="https://example.com/1"/></entry></feed>`), ContentTypeXML, nil)
+	require.NoError(t, err)
+	require.Equal(t, FeedFormatAtom, feed.Format)
+	require.Equal(t, "Plain", feed.Title)
+	require.Len(t, feed.Items, 1)
+	require.Equal(t, "https://example.com/1", feed.Items[0].ID)
+}
+
+func TestAtomFeed_WrongRoot(t *testing.T) {
+	document := AtomFeed{}
+	require.Error(t, decodeFeedXML([]byte(`<rss><channel/></rss>`), &document))
+}
@@ -0,0 +1,148 @@
+package remote
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// feedDateLayouts are the date formats that ParseFeedDate accepts, after the
+// weekday has been removed and named time zones have been replaced by offsets.
+var feedDateLayouts = []string{
+
+	// RFC 3339 and ISO 8601 (Atom, JSON Feed, Dublin Core)
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+
+	// RFC 822 and RFC 1123 (RSS), with the many variations found in the wild
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05 -0700",
+	"2 Jan 2006",
+	"2 January 2006",
+
+	// US style and C library formats
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006 3:04 PM",
+	"January 2, 2006 15:04:05 -0700",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"Mon Jan 2 15:04:05 2006",
+	"Mon Jan 2 15:04:05 -0700 2006",
+	"Mon Jan 2 15:04:05 MST 2006",
+}
+
+// feedTimeZones are the named time zones that RFC 822 allows, plus a few
+// others that are common in feeds. Go cannot know the offset of a named zone
+// that is not local, so they are replaced with numeric offsets before parsing.
+var feedTimeZones = map[string]string{
+	"Z":    "+0000",
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"JST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+}
+
+// ParseFeedDate parses a date from a feed, and reports whether it was
+// successful. It accepts RFC 3339 and ISO 8601 dates (used by Atom and JSON
+// Feed), RFC 822 and RFC 1123 dates (used by RSS), and the many variations
+// of them that real feeds publish: missing or incorrect weekdays, two-digit
+// years, single-digit days, full month names, missing seconds or time zones,
+// and named time zones such as "EST". Dates without a time zone are UTC.
+func ParseFeedDate(value string) (time.Time, bool) {
+
+	value = normalizeFeedDate(value)
+
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range feedDateLayouts {
+		if result, err := time.Parse(layout, value); err == nil {
+			return result, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// normalizeFeedDate removes the parts of a date that vary without adding
+// information: extra whitespace, a leading weekday, and named time zones.
+func normalizeFeedDate(value string) string {
+
+	fields := strings.Fields(value)
+
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Remove a leading weekday ("Mon,", "Tues,", "Monday,"), which is often wrong anyway
+	if weekday, found := strings.CutSuffix(fields[0], ","); found && isLetters(weekday) {
+		fields = fields[1:]
+	} else if (len(fields) > 1) && isLetters(fields[0]) && strings.HasPrefix(fields[1], ",") {
+		fields = fields[1:]
+
+		if fields[0] = strings.TrimPrefix(fields[0], ","); fields[0] == "" {
+			fields = fields[1:]
+		}
+	}
+
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Replace named time zones, such as "GMT" or "(EST)", wherever they appear.
+	// RFC 822 dates end with the zone, but C library dates put it before the year.
+	for index := 1; index < len(fields); index++ {
+		if offset, ok := feedTimeZones[strings.ToUpper(strings.Trim(fields[index], "()"))]; ok {
+			fields[index] = offset
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// isLetters reports whether a string is non-empty and made up only of letters.
+func isLetters(value string) bool {
+
+	if value == "" {
+		return false
+	}
+
+	for _, r := range value {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+
+	return true
+}
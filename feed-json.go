@@ -0,0 +1,209 @@
+package remote
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// JSONFeed is a JSON Feed 1.1 document (https://www.jsonfeed.org/version/1.1/).
+// The singular "author" from version 1.0 is also accepted.
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url,omitempty"`
+	FeedURL     string           `json:"feed_url,omitempty"`
+	Description string           `json:"description,omitempty"`
+	UserComment string           `json:"user_comment,omitempty"`
+	NextURL     string           `json:"next_url,omitempty"`
+	Icon        string           `json:"icon,omitempty"`
+	Favicon     string           `json:"favicon,omitempty"`
+	Authors     []JSONFeedAuthor `json:"authors,omitempty"`
+	Author      *JSONFeedAuthor  `json:"author,omitempty"` // JSON Feed 1.0
+	Language    string           `json:"language,omitempty"`
+	Expired     bool             `json:"expired,omitempty"`
+	Hubs        []JSONFeedHub    `json:"hubs,omitempty"`
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+// JSONFeedItem is a single item in a JSON Feed.
+type JSONFeedItem struct {
+	ID            string               `json:"id"` // numeric IDs are also accepted
+	URL           string               `json:"url,omitempty"`
+	ExternalURL   string               `json:"external_url,omitempty"`
+	Title         string               `json:"title,omitempty"`
+	ContentHTML   string               `json:"content_html,omitempty"`
+	ContentText   string               `json:"content_text,omitempty"`
+	Summary       string               `json:"summary,omitempty"`
+	Image         string               `json:"image,omitempty"`
+	BannerImage   string               `json:"banner_image,omitempty"`
+	DatePublished string               `json:"date_published,omitempty"`
+	DateModified  string               `json:"date_modified,omitempty"`
+	Authors       []JSONFeedAuthor     `json:"authors,omitempty"`
+	Author        *JSONFeedAuthor      `json:"author,omitempty"` // JSON Feed 1.0
+	Tags          []string             `json:"tags,omitempty"`
+	Language      string               `json:"language,omitempty"`
+	Attachments   []JSONFeedAttachment `json:"attachments,omitempty"`
+}
+
+// JSONFeedAuthor is the author of a JSON Feed or item.
+type JSONFeedAuthor struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// JSONFeedAttachment is a file attached to a JSON Feed item.
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	Title             string  `json:"title,omitempty"`
+	SizeInBytes       int64   `json:"size_in_bytes,omitempty"`
+	DurationInSeconds float64 `json:"duration_in_seconds,omitempty"`
+}
+
+// JSONFeedHub is a real-time notification endpoint for a JSON Feed, such as WebSub.
+type JSONFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It accepts
+// numeric IDs, which the specification forbids but many feeds publish.
+func (item *JSONFeedItem) UnmarshalJSON(data []byte) error {
+
+	type jsonFeedItem JSONFeedItem
+
+	value := struct {
+		*jsonFeedItem
+		ID json.RawMessage `json:"id"`
+	}{
+		jsonFeedItem: (*jsonFeedItem)(item),
+	}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	item.ID = lenientString(value.ID)
+	return nil
+}
+
+// Feed returns the normalized version of the feed, with relative URLs
+// resolved against base (which may be nil).
+func (feed JSONFeed) Feed(base *url.URL) Feed {
+
+	result := Feed{
+		Format:      FeedFormatJSON,
+		Title:       strings.TrimSpace(feed.Title),
+		Description: strings.TrimSpace(feed.Description),
+		HomePageURL: feedURL(base, feed.HomePageURL),
+		FeedURL:     feedURL(base, feed.FeedURL),
+		NextURL:     feedURL(base, feed.NextURL),
+		ImageURL:    feedURL(base, feed.Icon),
+		IconURL:     feedURL(base, feed.Favicon),
+		Language:    strings.TrimSpace(feed.Language),
+		Authors:     jsonFeedAuthors(base, feed.Authors, feed.Author),
+		Items:       make([]Item, 0, len(feed.Items)),
+	}
+
+	for _, feedItem := range feed.Items {
+
+		item := feedItem.Item(base)
+
+		// Items inherit the feed's authors
+		if len(item.Authors) == 0 {
+			item.Authors = append(item.Authors, result.Authors...)
+		}
+
+		// JSON Feed has no feed-level date, so use the most recent item
+		if item.Updated.After(result.Updated) {
+			result.Updated = item.Updated
+		} else if item.Published.After(result.Updated) {
+			result.Updated = item.Published
+		}
+
+		result.Items = append(result.Items, item)
+	}
+
+	return result
+}
+
+// Item returns the normalized version of the item, with relative URLs
+// resolved against base (which may be nil).
+func (item JSONFeedItem) Item(base *url.URL) Item {
+
+	result := Item{
+		ID:          strings.TrimSpace(item.ID),
+		URL:         feedURL(base, item.URL),
+		ExternalURL: feedURL(base, item.ExternalURL),
+		Title:       strings.TrimSpace(item.Title),
+		Summary:     strings.TrimSpace(item.Summary),
+		ContentHTML: strings.TrimSpace(item.ContentHTML),
+		ContentText: strings.TrimSpace(item.ContentText),
+		ImageURL:    feedURL(base, firstNonEmpty(item.Image, item.BannerImage)),
+		Authors:     jsonFeedAuthors(base, item.Authors, item.Author),
+		Tags:        feedTags(item.Tags...),
+		Enclosures:  make([]Enclosure, 0, len(item.Attachments)),
+	}
+
+	if result.ID == "" {
+		result.ID = result.URL
+	}
+
+	result.Published, _ = ParseFeedDate(item.DatePublished)
+	result.Updated, _ = ParseFeedDate(item.DateModified)
+
+	for _, attachment := range item.Attachments {
+
+		attachmentURL := feedURL(base, attachment.URL)
+
+		if attachmentURL == "" {
+			continue
+		}
+
+		enclosure := Enclosure{
+			URL:   attachmentURL,
+			Type:  strings.TrimSpace(attachment.MimeType),
+			Title: strings.TrimSpace(attachment.Title),
+		}
+
+		if attachment.SizeInBytes > 0 {
+			enclosure.Length = attachment.SizeInBytes
+		}
+
+		if attachment.DurationInSeconds > 0 {
+			enclosure.Duration = time.Duration(attachment.DurationInSeconds * float64(time.Second))
+		}
+
+		result.Enclosures = append(result.Enclosures, enclosure)
+	}
+
+	return result
+}
+
+// jsonFeedAuthors converts JSON Feed authors (and the 1.0 author, if there
+// are no others) into a list of Authors.
+func jsonFeedAuthors(base *url.URL, authors []JSONFeedAuthor, author *JSONFeedAuthor) []Author {
+
+	if (len(authors) == 0) && (author != nil) {
+		authors = []JSONFeedAuthor{*author}
+	}
+
+	result := make([]Author, 0, len(authors))
+
+	for _, author := range authors {
+
+		value := Author{
+			Name: strings.TrimSpace(author.Name),
+			URL:  feedURL(base, author.URL),
+		}
+
+		if value != (Author{}) {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,95 @@
+package remote
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testJSONFeed = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Example Microblog",
+	"home_page_url": "https://example.com/",
+	"feed_url": "/feed.json",
+	"next_url": "/feed.json?before=2",
+	"icon": "/icon.png",
+	"favicon": "/favicon.ico",
+	"language": "en",
+	"authors": [{"name": "Alice", "url": "/alice", "avatar": "/alice.png"}],
+	"items": [
+		{
+			"id": 2,
+			"url": "/2",
+			"external_url": "https://example.org/article",
+			"title": "With attachments",
+			"content_html": "<p>Hello</p>",
+			"summary": "Hello",
+			"image": "/2.jpg",
+			"date_published": "2025-06-09T08:30:00-04:00",
+			"date_modified": "2025-06-10T00:00:00Z",
+			"tags": ["one", "two", "one"],
+			"attachments": [
+				{"url": "/2.mp3", "mime_type": "audio/mpeg", "title": "Audio", "size_in_bytes": 2048, "duration_in_seconds": 90.5},
+				{"url": "", "mime_type": "audio/mpeg"}
+			]
+		},
+		{
+			"id": "1",
+			"content_text": "Just text",
+			"date_published": "2025-06-01T00:00:00Z",
+			"author": {"name": "Bob"}
+		}
+	]
+}`
+
+func TestJSONFeed(t *testing.T) {
+
+	base, err := url.Parse("https://example.com/feed.json")
+	require.NoError(t, err)
+
+	feed, err := ParseFeed([]byte(testJSONFeed), ContentTypeJSONFeed, base)
+	require.NoError(t, err)
+
+	require.Equal(t, FeedFormatJSON, feed.Format)
+	require.Equal(t, "Example Microblog", feed.Title)
+	require.Equal(t, "https://example.com/", feed.HomePageURL)
+	require.Equal(t, "https://example.com/feed.json", feed.FeedURL)
+	require.Equal(t, "https://example.com/feed.json?before=2", feed.NextURL)
+	require.Equal(t, "https://example.com/icon.png", feed.ImageURL)
+	require.Equal(t, "https://example.com/favicon.ico", feed.IconURL)
+	require.Equal(t, []Author{{Name: "Alice", URL: "https://example.com/alice"}}, feed.Authors)
+	require.True(t, feed.Updated.Equal(time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)))
+	require.Len(t, feed.Items, 2)
+
+	// Numeric ID, attachments, and inherited authors
+	item := feed.Items[0]
+	require.Equal(t, "2", item.ID)
+	require.Equal(t, "https://example.com/2", item.URL)
+	require.Equal(t, "https://example.org/article", item.ExternalURL)
+	require.Equal(t, "<p>Hello</p>", item.ContentHTML)
+	require.Equal(t, "Hello", item.Summary)
+	require.Equal(t, "https://example.com/2.jpg", item.ImageURL)
+	require.Equal(t, feed.Authors, item.Authors)
+	require.Equal(t, []string{"one", "two"}, item.Tags)
+	require.True(t, item.Published.Equal(time.Date(2025, 6, 9, 12, 30, 0, 0, time.UTC)))
+	require.Equal(t, []Enclosure{{URL: "https://example.com/2.mp3", Type: "audio/mpeg", Title: "Audio", Length: 2048, Duration: 90500 * time.Millisecond}}, item.Enclosures)
+
+	// JSON Feed 1.0 author
+	item = feed.Items[1]
+	require.Equal(t, "1", item.ID)
+	require.Equal(t, "Just text", item.ContentText)
+	require.Equal(t, []Author{{Name: "Bob"}}, item.Authors)
+}
+
+func TestJSONFeedItem_UnmarshalJSON(t *testing.T) {
+
+	item := JSONFeedItem{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 12345678901, "title": "Numeric"}`), &item))
+	require.Equal(t, "12345678901", item.ID)
+	require.Equal(t, "Numeric", item.Title)
+
+	require.Error(t, json.Unmarshal([]byte(`{"id": "1", "tags": "not a list"}`), &item))
+}
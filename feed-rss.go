@@ -0,0 +1,454 @@
+package remote
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RSSFeed is an RSS 2.0 document (https://www.rssboard.org/rss-specification),
+// including the common content, Dublin Core, iTunes, Media RSS, and Atom
+// extensions. Elements are matched by namespace, whatever prefix the feed uses.
+type RSSFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+// RSSChannel is the <channel> element of an RSS feed.
+type RSSChannel struct {
+	Title          string
+	Link           string
+	Description    string
+	Language       string
+	Copyright      string
+	ManagingEditor string
+	WebMaster      string
+	PubDate        string
+	LastBuildDate  string
+	Generator      string
+	TTL            string
+	Categories     []RSSCategory
+	Image          RSSImage
+	ITunesImage    string     // href of <itunes:image>
+	ITunesAuthor   string     // <itunes:author>
+	AtomLinks      []AtomLink // <atom:link>, such as rel="self" and rel="next"
+	Items          []RSSItem
+}
+
+// RSSItem is an <item> element of an RSS feed.
+type RSSItem struct {
+	Title           string
+	Link            string
+	Description     string
+	ContentEncoded  string // <content:encoded>
+	Author          string
+	DCCreator       string // <dc:creator>
+	ITunesAuthor    string // <itunes:author>
+	Categories      []RSSCategory
+	Comments        string
+	Enclosures      []RSSEnclosure
+	GUID            RSSGUID
+	PubDate         string
+	DCDate          string // <dc:date>
+	ITunesImage     string // href of <itunes:image>
+	ITunesDuration  string // <itunes:duration>
+	MediaContents   []FeedMedia
+	MediaThumbnails []FeedMedia
+	MediaGroup      FeedMediaGroup
+	AtomLinks       []AtomLink
+}
+
+// RSSCategory is a <category> element of an RSS feed.
+type RSSCategory struct {
+	Domain string `xml:"domain,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// RSSImage is the <image> element of an RSS channel.
+type RSSImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+// RSSEnclosure is an <enclosure> element of an RSS item.
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"` // kept as text, because many feeds publish invalid lengths
+}
+
+// RSSGUID is the <guid> element of an RSS item.
+type RSSGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// FeedMedia is a <media:content> or <media:thumbnail> element (Media RSS),
+// which is used by both RSS and Atom feeds.
+type FeedMedia struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+	Width    string `xml:"width,attr"`
+	Height   string `xml:"height,attr"`
+}
+
+// FeedMediaGroup is a <media:group> element (Media RSS).
+type FeedMediaGroup struct {
+	Title       string      `xml:"http://search.yahoo.com/mrss/ title"`
+	Description string      `xml:"http://search.yahoo.com/mrss/ description"`
+	Contents    []FeedMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails  []FeedMedia `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface, matching elements
+// by namespace so that (for example) <itunes:image> is not read as <image>.
+func (channel *RSSChannel) UnmarshalXML(decoder *xml.Decoder, _ xml.StartElement) error {
+
+	return decodeFeedElements(decoder, "", func(name string, start xml.StartElement) any {
+
+		switch name {
+
+		case "title":
+			return &channel.Title
+		case "link":
+			return &channel.Link
+		case "description":
+			return &channel.Description
+		case "language":
+			return &channel.Language
+		case "copyright":
+			return &channel.Copyright
+		case "managingEditor":
+			return &channel.ManagingEditor
+		case "webMaster":
+			return &channel.WebMaster
+		case "pubDate":
+			return &channel.PubDate
+		case "lastBuildDate":
+			return &channel.LastBuildDate
+		case "generator":
+			return &channel.Generator
+		case "ttl":
+			return &channel.TTL
+		case "image":
+			return &channel.Image
+		case "itunes:author":
+			return &channel.ITunesAuthor
+
+		case "itunes:image":
+			channel.ITunesImage = xmlAttribute(start, "", "href")
+
+		case "category":
+			channel.Categories = append(channel.Categories, RSSCategory{})
+			return &channel.Categories[len(channel.Categories)-1]
+
+		case "atom:link":
+			channel.AtomLinks = append(channel.AtomLinks, AtomLink{})
+			return &channel.AtomLinks[len(channel.AtomLinks)-1]
+
+		case "item":
+			channel.Items = append(channel.Items, RSSItem{})
+			return &channel.Items[len(channel.Items)-1]
+		}
+
+		return nil
+	})
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface, matching elements
+// by namespace so that (for example) <media:content> is not read as <content>.
+func (item *RSSItem) UnmarshalXML(decoder *xml.Decoder, _ xml.StartElement) error {
+
+	return decodeFeedElements(decoder, "", func(name string, start xml.StartElement) any {
+
+		switch name {
+
+		case "title":
+			return &item.Title
+		case "link":
+			return &item.Link
+		case "description":
+			return &item.Description
+		case "content:encoded":
+			return &item.ContentEncoded
+		case "author":
+			return &item.Author
+		case "dc:creator":
+			return &item.DCCreator
+		case "itunes:author":
+			return &item.ITunesAuthor
+		case "comments":
+			return &item.Comments
+		case "guid":
+			return &item.GUID
+		case "pubDate":
+			return &item.PubDate
+		case "dc:date":
+			return &item.DCDate
+		case "itunes:duration":
+			return &item.ITunesDuration
+		case "media:group":
+			return &item.MediaGroup
+
+		case "itunes:image":
+			item.ITunesImage = xmlAttribute(start, "", "href")
+
+		case "category":
+			item.Categories = append(item.Categories, RSSCategory{})
+			return &item.Categories[len(item.Categories)-1]
+
+		case "enclosure":
+			item.Enclosures = append(item.Enclosures, RSSEnclosure{})
+			return &item.Enclosures[len(item.Enclosures)-1]
+
+		case "media:content":
+			item.MediaContents = append(item.MediaContents, FeedMedia{})
+			return &item.MediaContents[len(item.MediaContents)-1]
+
+		case "media:thumbnail":
+			item.MediaThumbnails = append(item.MediaThumbnails, FeedMedia{})
+			return &item.MediaThumbnails[len(item.MediaThumbnails)-1]
+
+		case "atom:link":
+			item.AtomLinks = append(item.AtomLinks, AtomLink{})
+			return &item.AtomLinks[len(item.AtomLinks)-1]
+		}
+
+		return nil
+	})
+}
+
+// Feed returns the normalized version of the feed, with relative URLs
+// resolved against base (which may be nil).
+func (document RSSFeed) Feed(base *url.URL) Feed {
+
+	channel := document.Channel
+
+	result := Feed{
+		Format:      FeedFormatRSS,
+		Title:       strings.TrimSpace(channel.Title),
+		Description: strings.TrimSpace(channel.Description),
+		HomePageURL: feedURL(base, channel.Link),
+		FeedURL:     feedURL(base, atomLinkHref(channel.AtomLinks, "self")),
+		NextURL:     feedURL(base, atomLinkHref(channel.AtomLinks, "next")),
+		ImageURL:    feedURL(base, firstNonEmpty(channel.Image.URL, channel.ITunesImage)),
+		Language:    strings.TrimSpace(channel.Language),
+		Authors:     rssAuthors(channel.ManagingEditor, channel.ITunesAuthor),
+		Items:       make([]Item, 0, len(channel.Items)),
+	}
+
+	result.Updated, _ = ParseFeedDate(firstNonEmpty(channel.LastBuildDate, channel.PubDate))
+
+	for _, item := range channel.Items {
+		result.Items = append(result.Items, item.Item(base))
+	}
+
+	return result
+}
+
+// Item returns the normalized version of the item, with relative URLs
+// resolved against base (which may be nil).
+func (item RSSItem) Item(base *url.URL) Item {
+
+	guid := strings.TrimSpace(item.GUID.Value)
+	link := item.Link
+
+	// A GUID is a permalink unless it says otherwise
+	if (link == "") && !strings.EqualFold(item.GUID.IsPermaLink, "false") && strings.Contains(guid, "://") {
+		link = guid
+	}
+
+	result := Item{
+		ID:         firstNonEmpty(guid, feedURL(base, link)),
+		URL:        feedURL(base, link),
+		Title:      strings.TrimSpace(item.Title),
+		Authors:    rssAuthors(firstNonEmpty(item.Author, item.DCCreator, item.ITunesAuthor)),
+		Tags:       []string{},
+		Enclosures: []Enclosure{},
+	}
+
+	// <description> is the full content unless <content:encoded> is also present
+	if content := strings.TrimSpace(item.ContentEncoded); content != "" {
+		result.ContentHTML = content
+		result.Summary = strings.TrimSpace(item.Description)
+	} else {
+		result.ContentHTML = strings.TrimSpace(item.Description)
+	}
+
+	for _, category := range item.Categories {
+		result.Tags = append(result.Tags, category.Value)
+	}
+
+	result.Tags = feedTags(result.Tags...)
+	result.Published, _ = ParseFeedDate(firstNonEmpty(item.PubDate, item.DCDate))
+
+	// Attachments
+	duration := parseFeedDuration(item.ITunesDuration)
+
+	for _, enclosure := range item.Enclosures {
+		if enclosureURL := feedURL(base, enclosure.URL); enclosureURL != "" {
+			result.Enclosures = append(result.Enclosures, Enclosure{
+				URL:      enclosureURL,
+				Type:     strings.TrimSpace(enclosure.Type),
+				Length:   parseFeedLength(enclosure.Length),
+				Duration: duration,
+			})
+		}
+	}
+
+	media := append(append([]FeedMedia{}, item.MediaContents...), item.MediaGroup.Contents...)
+	thumbnails := append(append([]FeedMedia{}, item.MediaThumbnails...), item.MediaGroup.Thumbnails...)
+
+	for _, content := range media {
+		if !strings.EqualFold(content.Medium, "image") && !strings.HasPrefix(content.Type, "image/") {
+			result.Enclosures = appendMediaEnclosure(base, result.Enclosures, content)
+		}
+	}
+
+	// Image
+	result.ImageURL = feedURL(base, firstNonEmpty(
+		feedMediaImage(thumbnails),
+		feedMediaImage(media),
+		item.ITunesImage,
+		feedEnclosureImage(result.Enclosures),
+	))
+
+	return result
+}
+
+// atomLinkHref returns the href of the first Atom link with the relation type.
+func atomLinkHref(links []AtomLink, rel string) string {
+
+	for _, link := range links {
+		if atomLinkRel(link) == rel {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
+// rssAuthors converts the first non-empty RSS author into a list, which
+// may be empty. RSS authors are email addresses, usually followed by a
+// name in parentheses, but many feeds publish only a name.
+func rssAuthors(values ...string) []Author {
+
+	value := firstNonEmpty(values...)
+
+	if value == "" {
+		return []Author{}
+	}
+
+	// "alice@example.com (Alice Smith)"
+	if email, name, found := strings.Cut(value, "("); found && strings.Contains(email, "@") {
+		return []Author{{
+			Name:  strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(name), ")")),
+			Email: strings.TrimSpace(email),
+		}}
+	}
+
+	// "alice@example.com"
+	if strings.Contains(value, "@") && !strings.ContainsAny(value, " \t") {
+		return []Author{{Email: value}}
+	}
+
+	return []Author{{Name: value}}
+}
+
+// appendMediaEnclosure adds a Media RSS element to a list of enclosures,
+// unless an enclosure with the same URL is already present.
+func appendMediaEnclosure(base *url.URL, enclosures []Enclosure, media FeedMedia) []Enclosure {
+
+	mediaURL := feedURL(base, media.URL)
+
+	if mediaURL == "" {
+		return enclosures
+	}
+
+	for _, enclosure := range enclosures {
+		if enclosure.URL == mediaURL {
+			return enclosures
+		}
+	}
+
+	return append(enclosures, Enclosure{
+		URL:      mediaURL,
+		Type:     strings.TrimSpace(media.Type),
+		Length:   parseFeedLength(media.FileSize),
+		Duration: parseFeedDuration(media.Duration),
+	})
+}
+
+// feedMediaImage returns the URL of the first image in a list of Media RSS elements.
+func feedMediaImage(media []FeedMedia) string {
+
+	for _, content := range media {
+
+		if content.URL == "" {
+			continue
+		}
+
+		if ((content.Medium == "") && (content.Type == "")) || strings.EqualFold(content.Medium, "image") || strings.HasPrefix(content.Type, "image/") {
+			return content.URL
+		}
+	}
+
+	return ""
+}
+
+// feedEnclosureImage returns the URL of the first image in a list of enclosures.
+func feedEnclosureImage(enclosures []Enclosure) string {
+
+	for _, enclosure := range enclosures {
+		if strings.HasPrefix(enclosure.Type, "image/") {
+			return enclosure.URL
+		}
+	}
+
+	return ""
+}
+
+// parseFeedLength parses a size in bytes, returning zero if it is missing or invalid.
+func parseFeedLength(value string) int64 {
+
+	result, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+
+	if (err != nil) || (result < 0) {
+		return 0
+	}
+
+	return result
+}
+
+// parseFeedDuration parses a playing time, in seconds ("3600", "3600.5") or
+// clock form ("1:00:00" or "60:00"), returning zero if it is missing or invalid.
+func parseFeedDuration(value string) time.Duration {
+
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return 0
+	}
+
+	var seconds float64
+
+	for part := range strings.SplitSeq(value, ":") {
+
+		number, err := strconv.ParseFloat(part, 64)
+
+		if (err != nil) || (number < 0) {
+			return 0
+		}
+
+		seconds = (seconds * 60) + number
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,147 @@
+package remote
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"
+	xmlns:m="http://search.yahoo.com/mrss/">
+<channel>
+	<title> Example Podcast </title>
+	<link>/</link>
+	<description>Weekly episodes&nbsp;about examples</description>
+	<language>en-us</language>
+	<managingEditor>editor@example.com (Eddie Tor)</managingEditor>
+	<lastBuildDate>Tue, 10 Jun 2025 04:00:00 GMT</lastBuildDate>
+	<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+	<atom:link href="/feed.xml?page=2" rel="next"/>
+	<itunes:image href="/artwork.jpg"/>
+	<image><url>/logo.png</url><title>Example</title><link>/</link></image>
+	<category>Technology</category>
+	<item>
+		<title>Episode 1</title>
+		<link>/episodes/1</link>
+		<description>A short summary</description>
+		<content:encoded><![CDATA[<p>The <b>full</b> show notes</p>]]></content:encoded>
+		<dc:creator>Alice</dc:creator>
+		<itunes:author>Someone Else</itunes:author>
+		<category>Examples</category>
+		<category> Examples </category>
+		<category>Testing</category>
+		<guid isPermaLink="false">episode-1</guid>
+		<pubDate>Mon, 9 Jun 2025 08:30:00 EDT</pubDate>
+		<itunes:duration>1:02:03</itunes:duration>
+		<enclosure url="/audio/1.mp3" type="audio/mpeg" length="12345"/>
+		<m:thumbnail url="/images/1.jpg"/>
+		<m:content url="/video/1.mp4" type="video/mp4" fileSize="999"/>
+	</item>
+	<item>
+		<title>Episode 2</title>
+		<description>Only a description</description>
+		<author>bob@example.com</author>
+		<guid>https://example.com/episodes/2</guid>
+		<pubDate>not a date</pubDate>
+		<enclosure url="/audio/2.mp3" type="audio/mpeg" length="unknown"/>
+		<enclosure url="/images/2.png" type="image/png"/>
+	</item>
+</channel>
+</rss>`
+
+func TestRSSFeed(t *testing.T) {
+
+	base, err := url.Parse("https://example.com/feed.xml")
+	require.NoError(t, err)
+
+	feed, err := ParseFeed([]byte(testRSSFeed), ContentTypeRSSXML, base)
+	require.NoError(t, err)
+
+	require.Equal(t, FeedFormatRSS, feed.Format)
+	require.Equal(t, "Example Podcast", feed.Title)
+	require.Equal(t, "Weekly episodes about examples", feed.Description)
+	require.Equal(t, "https://example.com/", feed.HomePageURL)
+	require.Equal(t, "https://example.com/feed.xml", feed.FeedURL)
+	require.Equal(t, "https://example.com/feed.xml?page=2", feed.NextURL)
+	require.Equal(t, "https://example.com/logo.png", feed.ImageURL)
+	require.Equal(t, "en-us", feed.Language)
+	require.Equal(t, []Author{{Name: "Eddie Tor", Email: "editor@example.com"}}, feed.Authors)
+	require.True(t, feed.Updated.Equal(time.Date(2025, 6, 10, 4, 0, 0, 0, time.UTC)))
+	require.Len(t, feed.Items, 2)
+
+	// Full item
+	item := feed.Items[0]
+	require.Equal(t, "episode-1", item.ID)
+	require.Equal(t, "https://example.com/episodes/1", item.URL)
+	require.Equal(t, "Episode 1", item.Title)
+	require.Equal(t, "A short summary", item.Summary)
+	require.Equal(t, "<p>The <b>full</b> show notes</p>", item.ContentHTML)
+	require.Equal(t, []Author{{Name: "Alice"}}, item.Authors)
+	require.Equal(t, []string{"Examples", "Testing"}, item.Tags)
+	require.True(t, item.Published.Equal(time.Date(2025, 6, 9, 12, 30, 0, 0, time.UTC)))
+	require.Equal(t, "https://example.com/images/1.jpg", item.ImageURL)
+	require.Equal(t, []Enclosure{
+		{URL: "https://example.com/audio/1.mp3", Type: "audio/mpeg", Length: 12345, Duration: time.Hour + 2*time.Minute + 3*time.Second},
+		{URL: "https://example.com/video/1.mp4", Type: "video/mp4", Length: 999},
+	}, item.Enclosures)
+
+	// Minimal item
+	item = feed.Items[1]
+	require.Equal(t, "https://example.com/episodes/2", item.ID)
+	require.Equal(t, "https://example.com/episodes/2", item.URL)
+	require.Equal(t, "", item.Summary)
+	require.Equal(t, "Only a description", item.ContentHTML)
+	require.Equal(t, []Author{{Email: "bob@example.com"}}, item.Authors)
+	require.Empty(t, item.Tags)
+	require.True(t, item.Published.IsZero())
+	require.Equal(t, "https://example.com/images/2.png", item.ImageURL)
+	require.Equal(t, int64(0), item.Enclosures[0].Length)
+}
+
+func TestRSSFeed_Typed(t *testing.T) {
+
+	// The typed document keeps the original values, and extension
+	// elements do not overwrite the standard ones
+	document := RSSFeed{}
+	require.NoError(t, decodeFeedXML([]byte(testRSSFeed), &document))
+
+	require.Equal(t, "2.0", document.Version)
+	require.Equal(t, "/logo.png", document.Channel.Image.URL)
+	require.Equal(t, "/artwork.jpg", document.Channel.ITunesImage)
+	require.Len(t, document.Channel.AtomLinks, 2)
+	require.Equal(t, "/", document.Channel.Link)
+
+	item := document.Channel.Items[0]
+	require.Equal(t, "A short summary", item.Description)
+	require.Equal(t, "Alice", item.DCCreator)
+	require.Equal(t, "Someone Else", item.ITunesAuthor)
+	require.Equal(t, "", item.Author)
+	require.Equal(t, RSSGUID{IsPermaLink: "false", Value: "episode-1"}, item.GUID)
+	require.Equal(t, "1:02:03", item.ITunesDuration)
+	require.Len(t, item.MediaThumbnails, 1)
+	require.Len(t, item.MediaContents, 1)
+}
+
+func TestRSSAuthors(t *testing.T) {
+	require.Equal(t, []Author{}, rssAuthors("", " "))
+	require.Equal(t, []Author{{Name: "Alice", Email: "alice@example.com"}}, rssAuthors("alice@example.com (Alice)"))
+	require.Equal(t, []Author{{Email: "alice@example.com"}}, rssAuthors("alice@example.com"))
+	require.Equal(t, []Author{{Name: "Alice Smith"}}, rssAuthors("", "Alice Smith"))
+}
+
+func TestParseFeedDuration(t *testing.T) {
+	require.Equal(t, time.Duration(0), parseFeedDuration(""))
+	require.Equal(t, time.Duration(0), parseFeedDuration("abc"))
+	require.Equal(t, time.Duration(0), parseFeedDuration("-5"))
+	require.Equal(t, 90*time.Second, parseFeedDuration("90"))
+	require.Equal(t, 1500*time.Millisecond, parseFeedDuration("1.5"))
+	require.Equal(t, 61*time.Second, parseFeedDuration("1:01"))
+	require.Equal(t, time.Hour+time.Second, parseFeedDuration("01:00:01"))
+}
@@ -0,0 +1,316 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/benpate/derp"
+)
+
+// FeedFormatRSS identifies a feed that was published as RSS 2.0
+const FeedFormatRSS = "rss"
+
+// FeedFormatAtom identifies a feed that was published as Atom 1.0
+const FeedFormatAtom = "atom"
+
+// FeedFormatJSON identifies a feed that was published as JSON Feed 1.1
+const FeedFormatJSON = "json"
+
+// Feed is a normalized view of an RSS, Atom, or JSON Feed document. URLs are
+// absolute, dates are parsed, and missing values are empty. Use the format's
+// own type (RSSFeed, AtomFeed, or JSONFeed) to read anything else.
+//
+// Reading a response into a *Feed with Transaction.Result() chooses the
+// format automatically.
+type Feed struct {
+	Format      string    // FeedFormatRSS, FeedFormatAtom, or FeedFormatJSON
+	Title       string    // title of the feed
+	Description string    // description or subtitle of the feed
+	HomePageURL string    // web page that the feed describes
+	FeedURL     string    // canonical URL of the feed itself, if it declares one
+	NextURL     string    // next page of a paginated feed, if any
+	ImageURL    string    // logo or artwork for the feed
+	IconURL     string    // small icon for the feed
+	Language    string    // language of the feed, such as "en-us"
+	Authors     []Author  // authors of the feed
+	Updated     time.Time // when the feed last changed
+	Items       []Item    // items in the feed, in document order
+}
+
+// Item is a single entry in a Feed.
+type Item struct {
+	ID          string      // unique identifier of the item (falls back to its URL)
+	URL         string      // web page for the item
+	ExternalURL string      // page that the item is about, such as a linked article
+	Title       string      // title of the item
+	Summary     string      // short summary of the item
+	ContentHTML string      // full content, as HTML
+	ContentText string      // full content, as plain text
+	ImageURL    string      // main image or thumbnail for the item
+	Authors     []Author    // authors of the item
+	Tags        []string    // categories or tags
+	Published   time.Time   // when the item was first published
+	Updated     time.Time   // when the item last changed
+	Enclosures  []Enclosure // attached files, such as podcast audio
+}
+
+// Author is a person or organization that wrote a feed or item.
+type Author struct {
+	Name  string
+	Email string
+	URL   string
+}
+
+// Enclosure is a file attached to a feed item, such as a podcast episode.
+type Enclosure struct {
+	URL      string
+	Type     string        // media type, such as "audio/mpeg"
+	Length   int64         // size in bytes, if known
+	Title    string        // title of the file, if any
+	Duration time.Duration // playing time, if known
+}
+
+// ParseFeed decodes an RSS 2.0, Atom 1.0, or JSON Feed 1.1 document into a
+// normalized Feed. The format is chosen from the Content-Type (JSON Feed or
+// XML) and the document's root element (RSS or Atom), so feeds that are
+// served with a generic or incorrect Content-Type are still read. Documents
+// are converted to UTF-8 first (see DecodeCharset). Relative URLs are
+// resolved against base, which may be nil.
+func ParseFeed(body []byte, contentType string, base *url.URL) (Feed, error) {
+
+	const location = "remote.ParseFeed"
+
+	body, err := DecodeCharset(body, contentType)
+
+	if err != nil {
+		return Feed{}, derp.Wrap(err, location, "Unable to decode feed")
+	}
+
+	switch feedFormat(body, contentType) {
+
+	case FeedFormatJSON:
+		document := JSONFeed{}
+
+		if err := json.Unmarshal(body, &document); err != nil {
+			return Feed{}, derp.Wrap(err, location, "Unable to decode JSON Feed")
+		}
+
+		return document.Feed(base), nil
+
+	case FeedFormatAtom:
+		document := AtomFeed{}
+
+		if err := decodeFeedXML(body, &document); err != nil {
+			return Feed{}, derp.Wrap(err, location, "Unable to decode Atom feed")
+		}
+
+		return document.Feed(base), nil
+
+	case FeedFormatRSS:
+		document := RSSFeed{}
+
+		if err := decodeFeedXML(body, &document); err != nil {
+			return Feed{}, derp.Wrap(err, location, "Unable to decode RSS feed")
+		}
+
+		return document.Feed(base), nil
+	}
+
+	return Feed{}, derp.BadRequest(location, "Document is not an RSS, Atom, or JSON feed", contentType)
+}
+
+// feedFormat identifies the format of a feed document, or returns an empty
+// string if it is not a supported feed.
+func feedFormat(body []byte, contentType string) string {
+
+	contentType, _, _ = mime.ParseMediaType(contentType)
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+
+	switch contentType {
+
+	case ContentTypeJSONFeed, ContentTypeJSON, contentTypeNonStandardJSONText:
+		return FeedFormatJSON
+	}
+
+	// Otherwise, sniff JSON and XML documents
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return FeedFormatJSON
+	}
+
+	decoder := newFeedDecoder(trimmed)
+
+	for {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return ""
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			switch strings.ToLower(start.Name.Local) {
+			case "rss":
+				return FeedFormatRSS
+			case "feed":
+				return FeedFormatAtom
+			}
+			return ""
+		}
+	}
+}
+
+// decodeFeedXML decodes an XML feed that has already been converted to UTF-8.
+// Decoding is not strict, because many feeds use HTML entities (such as
+// &nbsp;) or undeclared namespace prefixes.
+func decodeFeedXML(body []byte, result any) error {
+	return newFeedDecoder(body).Decode(result)
+}
+
+// newFeedDecoder returns a lenient XML decoder for feed documents.
+func newFeedDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = utf8CharsetReader
+	return decoder
+}
+
+/******************************************
+ * XML Helpers
+ ******************************************/
+
+// feedNamespaces maps the XML namespaces used by feeds to their usual prefixes.
+var feedNamespaces = map[string]string{
+	"http://www.w3.org/2005/Atom":                "atom",
+	"http://purl.org/rss/1.0/modules/content/":   "content",
+	"http://purl.org/dc/elements/1.1/":           "dc",
+	"http://www.itunes.com/dtds/podcast-1.0.dtd": "itunes",
+	"http://search.yahoo.com/mrss/":              "media",
+	"http://search.yahoo.com/mrss":               "media",
+}
+
+// feedElementName returns the name of an element as "prefix:local", using
+// the usual prefix for its namespace (or just "local" for elements in the
+// default namespace). This matches elements no matter which prefix a feed
+// declares, and also when a lenient decoder leaves a prefix undeclared.
+func feedElementName(name xml.Name, defaultPrefix string) string {
+
+	prefix, ok := feedNamespaces[name.Space]
+
+	if !ok {
+		prefix = strings.ToLower(name.Space)
+	}
+
+	if (prefix == "") || (prefix == defaultPrefix) {
+		return name.Local
+	}
+
+	return prefix + ":" + name.Local
+}
+
+// decodeFeedElements decodes the children of an XML element. The field
+// function returns a pointer to decode each child into (by its
+// feedElementName), or nil to skip it.
+func decodeFeedElements(decoder *xml.Decoder, defaultPrefix string, field func(name string, start xml.StartElement) any) error {
+
+	for {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+
+		case xml.StartElement:
+			if target := field(feedElementName(token.Name, defaultPrefix), token); target != nil {
+				if err := decoder.DecodeElement(target, &token); err != nil {
+					return err
+				}
+			} else if err := decoder.Skip(); err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// xmlAttribute returns the value of an attribute of an XML element.
+func xmlAttribute(start xml.StartElement, space string, name string) string {
+
+	for _, attribute := range start.Attr {
+		if (attribute.Name.Local == name) && ((attribute.Name.Space == space) || (feedXMLNamespace(attribute.Name.Space) == space)) {
+			return attribute.Value
+		}
+	}
+
+	return ""
+}
+
+// feedXMLNamespace maps the reserved "xml" prefix to its namespace.
+func feedXMLNamespace(space string) string {
+
+	if space == "xml" {
+		return "http://www.w3.org/XML/1998/namespace"
+	}
+
+	return space
+}
+
+/******************************************
+ * Normalization Helpers
+ ******************************************/
+
+// feedURL resolves a URL from a feed, returning an empty string if it is empty or invalid.
+func feedURL(base *url.URL, reference string) string {
+
+	if resolved := resolveURL(base, reference); resolved != nil {
+		return resolved.String()
+	}
+
+	return ""
+}
+
+// feedBase resolves an xml:base attribute, returning the original base if there is none.
+func feedBase(base *url.URL, reference string) *url.URL {
+
+	if resolved := resolveURL(base, reference); resolved != nil {
+		return resolved
+	}
+
+	return base
+}
+
+// feedTags returns the non-empty, distinct tags from a list.
+func feedTags(values ...string) []string {
+
+	result := []string{}
+	seen := map[string]bool{}
+
+	for _, value := range values {
+		if value = strings.TrimSpace(value); (value != "") && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+// firstNonEmpty returns the first value that is not empty (or only whitespace).
+func firstNonEmpty(values ...string) string {
+
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedFormat(t *testing.T) {
+
+	// The Content-Type chooses JSON Feed, and the root element chooses RSS or Atom
+	require.Equal(t, FeedFormatJSON, feedFormat([]byte(`{}`), ContentTypeJSONFeed+"; charset=utf-8"))
+	require.Equal(t, FeedFormatJSON, feedFormat([]byte(`{}`), ContentTypeJSON))
+	require.Equal(t, FeedFormatJSON, feedFormat([]byte("\xef\xbb\xbf  {}"), "application/octet-stream"))
+	require.Equal(t, FeedFormatRSS, feedFormat([]byte(testRSSFeed), ContentTypeRSSXML))
+	require.Equal(t, FeedFormatRSS, feedFormat([]byte(testRSSFeed), contentTypeNonStandardXMLText))
+	require.Equal(t, FeedFormatAtom, feedFormat([]byte(testAtomFeed), ContentTypeAtomXML))
+	require.Equal(t, FeedFormatAtom, feedFormat([]byte(testAtomFeed), ContentTypeRSSXML)) // mislabeled
+	require.Equal(t, FeedFormatAtom, feedFormat([]byte(`<!-- comment --><feed/>`), ""))
+
+	require.Equal(t, "", feedFormat([]byte(`<html><body>Not a feed</body></html>`), ContentTypeHTML))
+	require.Equal(t, "", feedFormat([]byte(``), ContentTypeXML))
+}
+
+func TestParseFeed_Invalid(t *testing.T) {
+
+	_, err := ParseFeed([]byte(`<html><body>Not a feed</body></html>`), ContentTypeHTML, nil)
+	require.Error(t, err)
+
+	_, err = ParseFeed([]byte(`{"items": "wrong"}`), ContentTypeJSONFeed, nil)
+	require.Error(t, err)
+
+	_, err = ParseFeed([]byte(`<rss><channel><title>Unclosed`), ContentTypeRSSXML, nil)
+	require.Error(t, err)
+}
+
+func TestFeed_Result(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+		switch r.URL.Path {
+
+		case "/rss":
+			w.Header().Set(ContentType, contentTypeNonStandardXMLText+"; charset=utf-8")
+			_, _ = w.Write([]byte(testRSSFeed))
+
+		case "/json":
+			w.Header().Set(ContentType, ContentTypeJSONFeed)
+			_, _ = w.Write([]byte(testJSONFeed))
+
+		default:
+			w.Header().Set(ContentType, ContentTypeHTML)
+			_, _ = w.Write([]byte(`<html></html>`))
+		}
+	})
+
+	// RSS, with URLs resolved against the feed's location
+	feed := Feed{}
+	require.NoError(t, Get("https://podcast.example.com/rss").With(option).Result(&feed).Send())
+	require.Equal(t, FeedFormatRSS, feed.Format)
+	require.Equal(t, "https://podcast.example.com/episodes/1", feed.Items[0].URL)
+
+	// JSON Feed
+	feed = Feed{}
+	require.NoError(t, Get("https://example.com/json").With(option).Result(&feed).Send())
+	require.Equal(t, FeedFormatJSON, feed.Format)
+
+	// Not a feed
+	require.Error(t, Get("https://example.com/html").With(option).Result(&feed).Send())
+}
+
+func TestParseFeedDate(t *testing.T) {
+
+	expected := time.Date(2025, 6, 9, 14, 30, 0, 0, time.UTC)
+
+	tests := []string{
+		"2025-06-09T14:30:00Z",
+		"2025-06-09T14:30:00.000Z",
+		"2025-06-09T10:30:00-04:00",
+		"2025-06-09T10:30:00-0400",
+		"2025-06-09T10:30-04:00",
+		"2025-06-09T14:30:00",
+		"2025-06-09 14:30:00",
+		"2025-06-09 10:30:00 -0400",
+		"Mon, 09 Jun 2025 14:30:00 GMT",
+		"Mon, 09 Jun 2025 14:30:00 +0000",
+		"Mon, 9 Jun 2025 10:30:00 EDT",
+		"Mon, 9 Jun 2025 07:30:00 PDT",
+		"Monday, 09 Jun 2025 14:30:00 UT",
+		"Fri, 09 Jun 2025 14:30:00 GMT", // wrong weekday
+		"Mon , 09 Jun 2025 14:30:00 GMT",
+		"09 Jun 2025 14:30:00 Z",
+		"9 Jun 2025 14:30 +0000",
+		"9 Jun 25 14:30:00 +0000",
+		"9 June 2025 14:30:00 +0000",
+		"Mon, 09 Jun 2025 10:30:00 -04:00",
+		"Mon, 09 Jun 2025 14:30:00",
+		"  Mon,  09   Jun 2025 14:30:00 GMT  ",
+		"Monday, 09-Jun-25 14:30:00 UTC",
+		"Jun 9, 2025 14:30:00 +0000",
+		"Jun 9, 2025 2:30 PM",
+		"Mon Jun  9 14:30:00 2025",
+		"Mon Jun 9 14:30:00 +0000 2025",
+		"Mon Jun  9 14:30:00 UTC 2025",
+		"Mon Jun 9 07:30:00 PDT 2025",
+		"Mon Jun 9 10:30:00 (EDT) 2025",
+	}
+
+	for _, test := range tests {
+		result, ok := ParseFeedDate(test)
+		require.True(t, ok, test)
+		require.True(t, expected.Equal(result), test+" parsed as "+result.String())
+	}
+
+	// Dates without times
+	for _, test := range []string{"2025-06-09", "9 Jun 2025", "June 9, 2025", "Jun 9, 2025"} {
+		result, ok := ParseFeedDate(test)
+		require.True(t, ok, test)
+		require.True(t, time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC).Equal(result), test)
+	}
+
+	// Invalid dates
+	for _, test := range []string{"", "   ", "yesterday", "Mon,", "2025-13-45", "GMT"} {
+		_, ok := ParseFeedDate(test)
+		require.False(t, ok, test)
+	}
+}
@@ -3,18 +3,20 @@ module github.com/benpate/remote
 go 1.25.0
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/benpate/derp v0.37.0
 	github.com/benpate/rosetta v0.33.0
 	github.com/benpate/uri v0.4.0
+	github.com/klauspost/compress v1.18.0
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/net v0.57.0
+	golang.org/x/text v0.40.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/net v0.57.0 // indirect
-	golang.org/x/text v0.40.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,213 @@
+package remote
+
+import (
+	"slices"
+	"strings"
+)
+
+// Link is a single web link from an HTTP Link header (RFC 8288), such as
+// `<https://example.com/page/2>; rel="next"`.
+type Link struct {
+	URL    string            // target of the link
+	Rel    string            // relation type(s), such as "next" (several are separated by spaces)
+	Params map[string]string // every other parameter, such as "type" or "title", keyed in lowercase
+}
+
+// Links is a list of web links, in the order they appeared.
+type Links []Link
+
+// HasRel reports whether the link includes the relation type (case-insensitive).
+func (link Link) HasRel(rel string) bool {
+	return slices.ContainsFunc(strings.Fields(link.Rel), func(value string) bool {
+		return strings.EqualFold(value, rel)
+	})
+}
+
+// Rel returns the first link with the relation type, and whether one was found.
+func (links Links) Rel(rel string) (Link, bool) {
+
+	for _, link := range links {
+		if link.HasRel(rel) {
+			return link, true
+		}
+	}
+
+	return Link{}, false
+}
+
+// Href returns the URL of the first link with the relation type, or an empty
+// string if there is none.
+func (links Links) Href(rel string) string {
+	link, _ := links.Rel(rel)
+	return link.URL
+}
+
+// ResponseLinks parses the Link headers of the response. Relative URLs are
+// resolved against the URL of the final response (after any redirects).
+func (t *Transaction) ResponseLinks() Links {
+
+	if t.response == nil {
+		return Links{}
+	}
+
+	base := t.finalURL()
+
+	result := ParseLinks(t.response.Header.Values("Link")...)
+
+	if base == nil {
+		return result
+	}
+
+	for index, link := range result {
+		if target, err := base.Parse(link.URL); err == nil {
+			result[index].URL = target.String()
+		}
+	}
+
+	return result
+}
+
+// ParseLinks parses the values of one or more HTTP Link headers (RFC 8288).
+// Malformed links are skipped. URLs are returned exactly as written, so
+// relative URLs are not resolved.
+func ParseLinks(values ...string) Links {
+
+	result := Links{}
+
+	for _, value := range values {
+		result = append(result, parseLinkHeader(value)...)
+	}
+
+	return result
+}
+
+// parseLinkHeader parses a single Link header, which may hold several
+// comma-separated links.
+func parseLinkHeader(value string) Links {
+
+	result := Links{}
+
+	for {
+		value = strings.TrimLeft(value, " \t,")
+
+		if value == "" {
+			return result
+		}
+
+		// Each link begins with a URL in angle brackets.
+		end := strings.IndexByte(value, '>')
+
+		if end < 0 {
+			return result
+		}
+
+		if value[0] != '<' {
+			value = skipLink(value)
+			continue
+		}
+
+		link := Link{
+			URL:    strings.TrimSpace(value[1:end]),
+			Params: map[string]string{},
+		}
+
+		value = value[end+1:]
+
+		// ...followed by parameters, each introduced by a semicolon.
+		for {
+			value = strings.TrimLeft(value, " \t")
+
+			if !strings.HasPrefix(value, ";") {
+				break
+			}
+
+			var name, param string
+			name, param, value = parseLinkParam(value[1:])
+
+			if name == "" {
+				continue
+			}
+
+			// Only the first occurrence of each parameter counts.
+			if name == "rel" {
+				if link.Rel == "" {
+					link.Rel = strings.Join(strings.Fields(param), " ")
+				}
+				continue
+			}
+
+			if _, exists := link.Params[name]; !exists {
+				link.Params[name] = param
+			}
+		}
+
+		result = append(result, link)
+
+		value = skipLink(value)
+	}
+}
+
+// skipLink discards anything unexpected up to the start of the next link.
+func skipLink(value string) string {
+
+	if index := strings.IndexByte(value, ','); index >= 0 {
+		return value[index+1:]
+	}
+
+	return ""
+}
+
+// parseLinkParam parses a single `name=value` or `name="quoted value"`
+// parameter, and returns its lowercase name, its value, and the rest of the input.
+func parseLinkParam(value string) (string, string, string) {
+
+	value = strings.TrimLeft(value, " \t")
+
+	// Parameter name
+	end := strings.IndexAny(value, "=;, \t")
+
+	if end < 0 {
+		end = len(value)
+	}
+
+	name := strings.ToLower(value[:end])
+	value = strings.TrimLeft(value[end:], " \t")
+
+	// Parameters may have no value at all
+	if !strings.HasPrefix(value, "=") {
+		return name, "", value
+	}
+
+	value = strings.TrimLeft(value[1:], " \t")
+
+	// Quoted value, with backslash escapes
+	if strings.HasPrefix(value, `"`) {
+
+		builder := strings.Builder{}
+
+		for index := 1; index < len(value); index++ {
+			switch value[index] {
+			case '\\':
+				if index+1 < len(value) {
+					index++
+					builder.WriteByte(value[index])
+				}
+			case '"':
+				return name, builder.String(), value[index+1:]
+			default:
+				builder.WriteByte(value[index])
+			}
+		}
+
+		return name, builder.String(), ""
+	}
+
+	// Token value
+	end = strings.IndexAny(value, ";, \t")
+
+	if end < 0 {
+		end = len(value)
+	}
+
+	return name, value[:end], value[end:]
+}
@@ -0,0 +1,72 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLinks(t *testing.T) {
+
+	links := ParseLinks(
+		`<https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=5>; rel="last"`,
+		`<https://example.com/a,b>; rel="alternate nofollow"; type="text/html"; title="A \"quoted\" title"; hreflang=en`,
+	)
+
+	require.Len(t, links, 3)
+
+	require.Equal(t, "https://api.example.com/items?page=2", links[0].URL)
+	require.Equal(t, "next", links[0].Rel)
+	require.Empty(t, links[0].Params)
+
+	require.Equal(t, "https://api.example.com/items?page=5", links.Href("last"))
+
+	require.Equal(t, "https://example.com/a,b", links[2].URL)
+	require.True(t, links[2].HasRel("alternate"))
+	require.True(t, links[2].HasRel("NoFollow"))
+	require.Equal(t, "text/html", links[2].Params["type"])
+	require.Equal(t, `A "quoted" title`, links[2].Params["title"])
+	require.Equal(t, "en", links[2].Params["hreflang"])
+
+	_, found := links.Rel("prev")
+	require.False(t, found)
+	require.Empty(t, links.Href("prev"))
+}
+
+func TestParseLinks_Malformed(t *testing.T) {
+
+	links := ParseLinks(
+		``,
+		`garbage`,
+		`garbage>, <https://example.com/ok>;REL=Next;rel=ignored`,
+		`<https://example.com/unterminated`,
+		`<https://example.com/bare>; crossorigin; rel=prev`,
+	)
+
+	require.Len(t, links, 2)
+	require.Equal(t, "https://example.com/ok", links.Href("next"))
+	require.Equal(t, "Next", links[0].Rel)
+	require.Equal(t, "https://example.com/bare", links.Href("prev"))
+	require.Contains(t, links[1].Params, "crossorigin")
+}
+
+func TestResponseLinks(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Add("Link", `</items?page=2>; rel="next"`)
+		w.Header().Add("Link", `<https://other.example.com/>; rel="related"`)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	transaction := Get(ts.URL + "/items").AllowPrivateIPs(true)
+	require.Empty(t, transaction.ResponseLinks())
+	require.NoError(t, transaction.Send())
+
+	links := transaction.ResponseLinks()
+	require.Len(t, links, 2)
+	require.Equal(t, ts.URL+"/items?page=2", links.Href("next"))
+	require.Equal(t, "https://other.example.com/", links.Href("related"))
+}
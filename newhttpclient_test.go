@@ -59,12 +59,29 @@ func TestNewHTTPClient_AllowsPublicAddress(t *testing.T) {
 	require.NotZero(t, response.StatusCode)
 }
 
-func TestGuardedRedirect_CapsChain(t *testing.T) {
-	// The standalone redirect policy rejects a chain that reaches maxRedirects.
-	via := make([]*http.Request, maxRedirects)
-	err := guardedRedirect(&http.Request{}, via)
+func TestNewHTTPClient_CapsRedirectChain(t *testing.T) {
+	// The standalone redirect policy rejects a redirect past maxRedirects.
+	client := NewHTTPClient(false)
+	via := make([]*http.Request, maxRedirects+1)
+	err := client.CheckRedirect(&http.Request{}, via)
 	require.Error(t, err)
 
-	// A shorter chain is allowed.
-	require.NoError(t, guardedRedirect(&http.Request{}, make([]*http.Request, maxRedirects-1)))
+	// The last redirect within the cap is allowed.
+	require.NoError(t, client.CheckRedirect(&http.Request{}, make([]*http.Request, maxRedirects)))
+}
+
+func TestNewHTTPClientWithPolicy(t *testing.T) {
+
+	client := NewHTTPClientWithPolicy(false, RedirectPolicy{MaxHops: 2})
+	require.True(t, client.Transport == safeTransport)
+	require.Equal(t, time.Minute, client.Timeout)
+
+	// The policy's own cap applies, instead of the default
+	require.Error(t, client.CheckRedirect(&http.Request{}, make([]*http.Request, 3)))
+	require.NoError(t, client.CheckRedirect(&http.Request{}, make([]*http.Request, 2)))
+
+	// NoFollow returns the redirect response as-is
+	client = NewHTTPClientWithPolicy(true, RedirectPolicy{NoFollow: true})
+	require.True(t, client.Transport == http.DefaultTransport)
+	require.ErrorIs(t, client.CheckRedirect(&http.Request{}, nil), http.ErrUseLastResponse)
 }
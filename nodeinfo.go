@@ -0,0 +1,361 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+// nodeInfoMaxResponseSize caps the size of NodeInfo documents. Some servers
+// include a lot of metadata, but nothing close to this.
+const nodeInfoMaxResponseSize = 1 << 20
+
+// nodeInfoSchemaPrefix begins the link relation for each NodeInfo schema version.
+const nodeInfoSchemaPrefix = "nodeinfo.diaspora.software/ns/schema/"
+
+// nodeInfoVersions are the supported NodeInfo schema versions, most preferred first.
+var nodeInfoVersions = []string{"2.1", "2.0"}
+
+// NodeInfoDocument describes a fediverse server: the software it runs, the
+// protocols it speaks, and how many people use it (https://nodeinfo.diaspora.software).
+type NodeInfoDocument struct {
+	Version           string           `json:"version"`
+	Software          NodeInfoSoftware `json:"software"`
+	Protocols         []string         `json:"protocols"`
+	Services          NodeInfoServices `json:"services"`
+	OpenRegistrations bool             `json:"openRegistrations"`
+	Usage             NodeInfoUsage    `json:"usage"`
+	Metadata          map[string]any   `json:"metadata"`
+}
+
+// NodeInfoSoftware identifies the software that a server runs.
+type NodeInfoSoftware struct {
+	Name       string `json:"name"` // always lowercase, such as "mastodon"
+	Version    string `json:"version"`
+	Repository string `json:"repository,omitempty"`
+	Homepage   string `json:"homepage,omitempty"`
+}
+
+// NodeInfoServices lists the third-party services that a server can
+// receive messages from (Inbound) or publish messages to (Outbound).
+type NodeInfoServices struct {
+	Inbound  []string `json:"inbound"`
+	Outbound []string `json:"outbound"`
+}
+
+// NodeInfoUsage holds a server's usage statistics.
+type NodeInfoUsage struct {
+	Users         NodeInfoUsers `json:"users"`
+	LocalPosts    int64         `json:"localPosts"`
+	LocalComments int64         `json:"localComments"`
+}
+
+// NodeInfoUsers counts a server's users.
+type NodeInfoUsers struct {
+	Total          int64 `json:"total"`
+	ActiveHalfyear int64 `json:"activeHalfyear"`
+	ActiveMonth    int64 `json:"activeMonth"`
+}
+
+// NodeInfo retrieves the NodeInfo document for a server, given its hostname
+// (such as "example.com") or any URL on it. It reads /.well-known/nodeinfo,
+// chooses the highest supported schema version (2.1 or 2.0), and follows that
+// link, which must stay on the same host. Documents are decoded leniently, to
+// accept the many servers that do not quite follow the schema. Options are
+// applied to every request.
+func NodeInfo(ctx context.Context, host string, options ...Option) (NodeInfoDocument, error) {
+
+	const location = "remote.NodeInfo"
+
+	host, err := nodeInfoHost(host)
+
+	if err != nil {
+		return NodeInfoDocument{}, derp.Wrap(err, location, "Invalid host")
+	}
+
+	hostname := strings.ToLower((&url.URL{Host: host}).Hostname())
+
+	// Find the link to the NodeInfo document
+	wellKnown := "https://" + host + "/.well-known/nodeinfo"
+	body, err := nodeInfoFetch(ctx, wellKnown, hostname, options)
+
+	if err != nil {
+		return NodeInfoDocument{}, derp.Wrap(err, location, "Unable to retrieve NodeInfo links", host)
+	}
+
+	links, err := parseDescriptor(body)
+
+	if err != nil {
+		return NodeInfoDocument{}, derp.Wrap(err, location, "Unable to parse NodeInfo links", host)
+	}
+
+	href, version := nodeInfoLink(links)
+
+	if href == "" {
+		return NodeInfoDocument{}, derp.NotFound(location, "No supported NodeInfo schema", host)
+	}
+
+	// Resolve relative links against the well-known URL
+	if base, err := url.Parse(wellKnown); err == nil {
+		if resolved, err := base.Parse(href); err == nil {
+			href = resolved.String()
+		}
+	}
+
+	// Retrieve the document itself
+	body, err = nodeInfoFetch(ctx, href, hostname, options)
+
+	if err != nil {
+		return NodeInfoDocument{}, derp.Wrap(err, location, "Unable to retrieve NodeInfo document", href)
+	}
+
+	result := NodeInfoDocument{}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return NodeInfoDocument{}, derp.Wrap(err, location, "Unable to decode NodeInfo document", href)
+	}
+
+	// Trust the version of the link we followed over a missing or mangled one
+	if (result.Version == "") || !strings.Contains(result.Version, ".") {
+		result.Version = version
+	}
+
+	return result, nil
+}
+
+// nodeInfoHost returns the host (and port, if any) for a hostname or URL.
+func nodeInfoHost(value string) (string, error) {
+
+	const location = "remote.nodeInfoHost"
+
+	value = strings.TrimSpace(value)
+
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+
+	parsed, err := url.Parse(value)
+
+	if err != nil {
+		return "", derp.Wrap(err, location, "Unable to parse host", value)
+	}
+
+	if parsed.Host == "" {
+		return "", derp.BadRequest(location, "Host is required", value)
+	}
+
+	return parsed.Host, nil
+}
+
+// nodeInfoFetch retrieves a NodeInfo document, which must be on the named host.
+func nodeInfoFetch(ctx context.Context, endpoint string, hostname string, options []Option) ([]byte, error) {
+
+	var body []byte
+
+	err := Get(endpoint).
+		WithContext(ctx).
+		Accept(ContentTypeJSON).
+		AllowHosts(hostname).
+		MaxResponseSize(nodeInfoMaxResponseSize).
+		With(options...).
+		Result(&body).
+		Send()
+
+	return body, err
+}
+
+// nodeInfoLink chooses the link to the highest supported schema version, and
+// returns its URL and version.
+func nodeInfoLink(links JRD) (string, string) {
+
+	for _, version := range nodeInfoVersions {
+		for _, link := range links.Links {
+
+			// Tolerate http/https and trailing slashes in the relation type
+			rel := strings.TrimSuffix(link.Rel, "/")
+
+			if strings.HasSuffix(rel, nodeInfoSchemaPrefix+version) && (link.Href != "") {
+				return link.Href, version
+			}
+		}
+	}
+
+	return "", ""
+}
+
+/******************************************
+ * Lenient Decoding
+ ******************************************/
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It accepts common
+// deviations from the schema, such as numbers sent as strings, versions sent
+// as numbers, missing or null sections, and the NodeInfo 1.x form of protocols.
+func (document *NodeInfoDocument) UnmarshalJSON(data []byte) error {
+
+	var raw struct {
+		Version           json.RawMessage `json:"version"`
+		Software          json.RawMessage `json:"software"`
+		Protocols         json.RawMessage `json:"protocols"`
+		Services          json.RawMessage `json:"services"`
+		OpenRegistrations json.RawMessage `json:"openRegistrations"`
+		Usage             json.RawMessage `json:"usage"`
+		Metadata          json.RawMessage `json:"metadata"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var software, services, usage, users map[string]json.RawMessage
+	_ = json.Unmarshal(raw.Software, &software)
+	_ = json.Unmarshal(raw.Services, &services)
+	_ = json.Unmarshal(raw.Usage, &usage)
+	_ = json.Unmarshal(usage["users"], &users)
+
+	*document = NodeInfoDocument{
+		Version: lenientString(raw.Version),
+		Software: NodeInfoSoftware{
+			Name:       strings.ToLower(lenientString(software["name"])),
+			Version:    lenientString(software["version"]),
+			Repository: lenientString(software["repository"]),
+			Homepage:   lenientString(software["homepage"]),
+		},
+		Protocols: lenientStrings(raw.Protocols),
+		Services: NodeInfoServices{
+			Inbound:  lenientStrings(services["inbound"]),
+			Outbound: lenientStrings(services["outbound"]),
+		},
+		OpenRegistrations: lenientBool(raw.OpenRegistrations),
+		Usage: NodeInfoUsage{
+			Users: NodeInfoUsers{
+				Total:          lenientInt(users["total"]),
+				ActiveHalfyear: lenientInt(users["activeHalfyear"]),
+				ActiveMonth:    lenientInt(users["activeMonth"]),
+			},
+			LocalPosts:    lenientInt(usage["localPosts"]),
+			LocalComments: lenientInt(usage["localComments"]),
+		},
+	}
+
+	_ = json.Unmarshal(raw.Metadata, &document.Metadata)
+
+	return nil
+}
+
+// lenientString decodes a string, number, or boolean as a string.
+func lenientString(data json.RawMessage) string {
+
+	var value any
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return ""
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return strings.TrimSpace(typed)
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(typed)
+	}
+
+	return ""
+}
+
+// lenientStrings decodes a list of strings, a single string, or an object
+// whose values are lists (such as NodeInfo 1.x {"inbound": [], "outbound": []}).
+// Duplicates and empty values are removed.
+func lenientStrings(data json.RawMessage) []string {
+
+	var value any
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []string{}
+	}
+
+	result := []string{}
+	seen := map[string]bool{}
+
+	var collect func(any)
+
+	collect = func(value any) {
+		switch typed := value.(type) {
+
+		case string:
+			if typed = strings.TrimSpace(typed); (typed != "") && !seen[typed] {
+				seen[typed] = true
+				result = append(result, typed)
+			}
+
+		case []any:
+			for _, item := range typed {
+				collect(item)
+			}
+
+		case map[string]any:
+			for _, key := range []string{"inbound", "outbound"} {
+				collect(typed[key])
+			}
+		}
+	}
+
+	collect(value)
+	return result
+}
+
+// lenientBool decodes a boolean, or a string or number that represents one.
+func lenientBool(data json.RawMessage) bool {
+
+	var value any
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return false
+	}
+
+	switch typed := value.(type) {
+	case bool:
+		return typed
+	case string:
+		result, _ := strconv.ParseBool(strings.TrimSpace(typed))
+		return result
+	case float64:
+		return typed != 0
+	}
+
+	return false
+}
+
+// lenientInt decodes a number, or a string that represents one. Missing,
+// invalid, and negative values decode as zero.
+func lenientInt(data json.RawMessage) int64 {
+
+	var value any
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return 0
+	}
+
+	var result float64
+
+	switch typed := value.(type) {
+	case float64:
+		result = typed
+	case string:
+		result, _ = strconv.ParseFloat(strings.TrimSpace(typed), 64)
+	}
+
+	switch {
+	case math.IsNaN(result) || (result <= 0):
+		return 0
+	case result >= math.MaxInt64:
+		return math.MaxInt64
+	}
+
+	return int64(result)
+}
@@ -0,0 +1,146 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nodeInfoOption serves a /.well-known/nodeinfo document and the documents it links to.
+func nodeInfoOption(wellKnown string, documents map[string]string) Option {
+
+	return handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path == "/.well-known/nodeinfo" {
+			w.Header().Set(ContentType, ContentTypeJSON)
+			_, _ = w.Write([]byte(wellKnown))
+			return
+		}
+
+		if document, ok := documents[r.URL.String()]; ok {
+			w.Header().Set(ContentType, `application/json; profile="http://nodeinfo.diaspora.software/ns/schema/2.1#"`)
+			_, _ = w.Write([]byte(document))
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	})
+}
+
+func TestNodeInfo(t *testing.T) {
+
+	option := nodeInfoOption(
+		`{"links":[
+			{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.0","href":"https://example.com/nodeinfo/2.0"},
+			{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.1","href":"https://example.com/nodeinfo/2.1"}
+		]}`,
+		map[string]string{
+			"https://example.com/nodeinfo/2.0": `{"version":"2.0"}`,
+			"https://example.com/nodeinfo/2.1": `{
+				"version": "2.1",
+				"software": {"name": "Mastodon", "version": "4.3.0", "repository": "https://github.com/mastodon/mastodon", "homepage": "https://joinmastodon.org"},
+				"protocols": ["activitypub"],
+				"services": {"inbound": [], "outbound": ["rss2.0"]},
+				"openRegistrations": true,
+				"usage": {"users": {"total": 1234, "activeHalfyear": 567, "activeMonth": 89}, "localPosts": 100000},
+				"metadata": {"nodeName": "Example Social"}
+			}`,
+		},
+	)
+
+	for _, host := range []string{"example.com", "https://example.com/about"} {
+
+		result, err := NodeInfo(context.Background(), host, option)
+		require.NoError(t, err)
+
+		require.Equal(t, "2.1", result.Version)
+		require.Equal(t, NodeInfoSoftware{Name: "mastodon", Version: "4.3.0", Repository: "https://github.com/mastodon/mastodon", Homepage: "https://joinmastodon.org"}, result.Software)
+		require.Equal(t, []string{"activitypub"}, result.Protocols)
+		require.Equal(t, []string{}, result.Services.Inbound)
+		require.Equal(t, []string{"rss2.0"}, result.Services.Outbound)
+		require.True(t, result.OpenRegistrations)
+		require.Equal(t, NodeInfoUsers{Total: 1234, ActiveHalfyear: 567, ActiveMonth: 89}, result.Usage.Users)
+		require.Equal(t, int64(100000), result.Usage.LocalPosts)
+		require.Equal(t, "Example Social", result.Metadata["nodeName"])
+	}
+}
+
+func TestNodeInfo_Fallback(t *testing.T) {
+
+	// Only 2.0 is offered (with a relative link, and a trailing slash on the
+	// relation type), and the document omits its version.
+	option := nodeInfoOption(
+		`{"links":[
+			{"rel":"http://nodeinfo.diaspora.software/ns/schema/1.0","href":"https://example.com/nodeinfo/1.0"},
+			{"rel":"https://nodeinfo.diaspora.software/ns/schema/2.0/","href":"/nodeinfo/2.0"}
+		]}`,
+		map[string]string{
+			"https://example.com/nodeinfo/2.0": `{"software":{"name":"pleroma","version":2.7}}`,
+		},
+	)
+
+	result, err := NodeInfo(context.Background(), "example.com", option)
+	require.NoError(t, err)
+	require.Equal(t, "2.0", result.Version)
+	require.Equal(t, "pleroma", result.Software.Name)
+	require.Equal(t, "2.7", result.Software.Version)
+}
+
+func TestNodeInfo_OtherHost(t *testing.T) {
+
+	// The NodeInfo document must be on the same host as the well-known document.
+	option := nodeInfoOption(
+		`{"links":[{"rel":"http://nodeinfo.diaspora.software/ns/schema/2.1","href":"https://attacker.example.net/nodeinfo"}]}`,
+		map[string]string{
+			"https://attacker.example.net/nodeinfo": `{"version":"2.1"}`,
+		},
+	)
+
+	_, err := NodeInfo(context.Background(), "example.com", option)
+	require.Error(t, err)
+}
+
+func TestNodeInfo_Unsupported(t *testing.T) {
+
+	option := nodeInfoOption(`{"links":[{"rel":"http://nodeinfo.diaspora.software/ns/schema/1.0","href":"https://example.com/nodeinfo/1.0"}]}`, nil)
+
+	_, err := NodeInfo(context.Background(), "example.com", option)
+	require.Error(t, err)
+
+	_, err = NodeInfo(context.Background(), "", option)
+	require.Error(t, err)
+}
+
+func TestNodeInfoDocument_Lenient(t *testing.T) {
+
+	result := NodeInfoDocument{}
+
+	err := json.Unmarshal([]byte(`{
+		"version": 2.0,
+		"software": {"name": " Misskey ", "version": null},
+		"protocols": {"inbound": ["activitypub", "ostatus"], "outbound": ["activitypub"]},
+		"services": null,
+		"openRegistrations": "true",
+		"usage": {"users": {"total": "42", "activeMonth": -5, "activeHalfyear": 1e30}, "localPosts": "lots"},
+		"metadata": []
+	}`), &result)
+
+	require.NoError(t, err)
+	require.Equal(t, "2", result.Version)
+	require.Equal(t, "misskey", result.Software.Name)
+	require.Empty(t, result.Software.Version)
+	require.Equal(t, []string{"activitypub", "ostatus"}, result.Protocols)
+	require.Empty(t, result.Services.Inbound)
+	require.True(t, result.OpenRegistrations)
+	require.Equal(t, int64(42), result.Usage.Users.Total)
+	require.Zero(t, result.Usage.Users.ActiveMonth)
+	require.Equal(t, int64(9223372036854775807), result.Usage.Users.ActiveHalfyear)
+	require.Zero(t, result.Usage.LocalPosts)
+	require.Nil(t, result.Metadata)
+
+	// Documents that are not objects are still an error
+	require.Error(t, json.Unmarshal([]byte(`"nope"`), &result))
+}
@@ -23,6 +23,10 @@ type Option struct {
 	// AfterRequest is executed after an http.Response has been received from the remote server, but before it is
 	// parsed and returned to the calling application.
 	AfterRequest func(*Transaction, *http.Response) error
+
+	// OnComplete is called once Send has finished, with the error (if any) that Send is about
+	// to return. It observes the final outcome of the transaction, but cannot change it.
+	OnComplete func(*Transaction, error)
 }
 
 // onBeforeRequest runs each option's BeforeRequest hook, returning the first error.
@@ -72,6 +76,16 @@ func (t *Transaction) onAfterRequest(response *http.Response) error {
 	return nil
 }
 
+// onComplete runs each option's OnComplete hook with the outcome of Send.
+func (t *Transaction) onComplete(err error) {
+
+	for _, option := range t.options {
+		if option.OnComplete != nil {
+			option.OnComplete(t, err)
+		}
+	}
+}
+
 // Options extracts valid remote.Option values from a list of `any` values.
 func Options(values ...any) []Option {
 
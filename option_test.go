@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/benpate/derp"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOption(t *testing.T) {
@@ -51,3 +52,35 @@ func TestOptionErrors(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestOptionOnComplete(t *testing.T) {
+
+	server := echoBodyServer()
+	defer server.Close()
+
+	var calls int
+	var completed *Transaction
+	var completedErr error
+
+	onComplete := Option{
+		OnComplete: func(transaction *Transaction, err error) {
+			calls++
+			completed = transaction
+			completedErr = err
+		},
+	}
+
+	// A successful transaction reports a nil error.
+	txn := Get(server.URL).AllowPrivateIPs(true).With(onComplete)
+	require.NoError(t, txn.Send())
+	require.Equal(t, 1, calls)
+	require.Same(t, txn, completed)
+	require.NoError(t, completedErr)
+	require.NotNil(t, completed.Request())
+
+	// A failed transaction reports the same error that Send returns.
+	err := Get("not a valid url").With(onComplete).Send()
+	require.Error(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, err, completedErr)
+}
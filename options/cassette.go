@@ -0,0 +1,333 @@
+package options
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/remote"
+)
+
+// CassetteMode determines whether a cassette records real traffic or replays it.
+type CassetteMode int
+
+// CassetteReplay serves every request from the cassette file, and fails any
+// request that was not recorded. The network is never contacted.
+const CassetteReplay CassetteMode = 0
+
+// CassetteRecord sends every request to the network as usual, and saves each
+// request/response pair to the cassette file, replacing its previous contents.
+const CassetteRecord CassetteMode = 1
+
+// CassetteConfig configures the record/replay behavior of CassetteWith.
+type CassetteConfig struct {
+	Filename      string       // path of the cassette file to read or write
+	Mode          CassetteMode // CassetteReplay (the default) or CassetteRecord
+	MatchBody     bool         // if TRUE, requests must also match the recorded body
+	RedactHeaders []string     // additional headers to scrub, beyond the defaults (Authorization, Cookie, etc.)
+}
+
+// cassette is the file format for recorded interactions.
+type cassette struct {
+	Interactions []cassetteInteraction `json:"interactions"`
+}
+
+// cassetteInteraction is a single recorded request/response pair.
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+// cassetteRequest is the recorded part of a request that is used for matching.
+type cassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// cassetteResponse is the recorded response that is replayed.
+type cassetteResponse struct {
+	StatusCode   int         `json:"status"`
+	Header       http.Header `json:"header"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"`
+}
+
+// Cassette is a remote.Option that records real HTTP traffic to a file, or
+// replays it from that file, so tests can run against realistic responses
+// without the network. Requests are matched on method and URL (including the
+// query string). Credentials are scrubbed from recorded headers.
+func Cassette(filename string, mode CassetteMode) remote.Option {
+	return CassetteWith(CassetteConfig{
+		Filename: filename,
+		Mode:     mode,
+	})
+}
+
+// CassetteWith is a remote.Option that records or replays HTTP traffic, using
+// the provided configuration to match request bodies and to scrub additional
+// headers. In replay mode, any request that was not recorded causes Send to
+// return an error.
+func CassetteWith(config CassetteConfig) remote.Option {
+
+	if config.Mode == CassetteRecord {
+		return recordCassette(config)
+	}
+
+	return replayCassette(config)
+}
+
+// recordCassette returns an option that saves every transaction to the cassette file.
+func recordCassette(config CassetteConfig) remote.Option {
+
+	const location = "remote.options.recordCassette"
+
+	redact := newRedactor(config.RedactHeaders, nil, nil)
+
+	var mutex sync.Mutex
+	recording := cassette{Interactions: []cassetteInteraction{}}
+
+	return remote.Option{
+
+		// This is executed once the transaction is complete, whether it succeeded or not.
+		OnComplete: func(transaction *remote.Transaction, _ error) {
+
+			request := transaction.Request()
+			response := transaction.Response()
+
+			// Only complete interactions can be replayed.
+			if (request == nil) || (response == nil) {
+				return
+			}
+
+			body, err := transaction.ResponseBody()
+
+			if err != nil {
+				derp.Report(derp.Wrap(err, location, "Unable to read response body", config.Filename))
+				return
+			}
+
+			interaction := cassetteInteraction{
+				Request: cassetteRequest{
+					Method: request.Method,
+					URL:    request.URL.String(),
+					Header: redact.Header(request.Header),
+					Body:   string(requestBodyBytes(request)),
+				},
+				Response: cassetteResponse{
+					StatusCode: response.StatusCode,
+					Header:     redact.Header(response.Header),
+				},
+			}
+
+			if utf8.Valid(body) {
+				interaction.Response.Body = string(body)
+			} else {
+				interaction.Response.Body = base64.StdEncoding.EncodeToString(body)
+				interaction.Response.BodyEncoding = "base64"
+			}
+
+			// Rewrite the whole file each time, so it is always complete.
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			recording.Interactions = append(recording.Interactions, interaction)
+
+			if err := writeCassette(config.Filename, recording); err != nil {
+				derp.Report(derp.Wrap(err, location, "Unable to save cassette", config.Filename))
+			}
+		},
+	}
+}
+
+// replayCassette returns an option that serves every request from the cassette file.
+func replayCassette(config CassetteConfig) remote.Option {
+
+	const location = "remote.options.replayCassette"
+
+	recording, loadErr := readCassette(config.Filename)
+
+	// Group recorded interactions by request, preserving their order.
+	interactions := map[string][]cassetteInteraction{}
+
+	for _, interaction := range recording.Interactions {
+		if parsed, err := url.Parse(interaction.Request.URL); err == nil {
+			key := cassetteKey(config, interaction.Request.Method, parsed, interaction.Request.Body)
+			interactions[key] = append(interactions[key], interaction)
+		}
+	}
+
+	// Track how many times each request has been replayed, and which requests
+	// missed (so that AfterRequest can fail them).
+	var mutex sync.Mutex
+	counts := map[string]int{}
+	misses := map[*http.Request]error{}
+
+	miss := func(request *http.Request, err error) *http.Response {
+		mutex.Lock()
+		misses[request] = err
+		mutex.Unlock()
+		return cassetteMissResponse(request, err)
+	}
+
+	return remote.Option{
+
+		// This is executed on every Request before its sent to the server
+		ModifyRequest: func(_ *remote.Transaction, request *http.Request) *http.Response {
+
+			if loadErr != nil {
+				return miss(request, derp.Wrap(loadErr, location, "Unable to load cassette", config.Filename))
+			}
+
+			key := cassetteKey(config, request.Method, request.URL, string(requestBodyBytes(request)))
+
+			mutex.Lock()
+			matches := interactions[key]
+			index := min(counts[key], len(matches)-1)
+			counts[key]++
+			mutex.Unlock()
+
+			if len(matches) == 0 {
+				return miss(request, derp.NotFound(location, "Request not found in cassette", config.Filename, request.Method, request.URL.String()))
+			}
+
+			response, err := matches[index].Response.httpResponse(request)
+
+			if err != nil {
+				return miss(request, derp.Wrap(err, location, "Unable to replay recorded response", config.Filename))
+			}
+
+			return response
+		},
+
+		// This is executed after the (replayed) response is received, and fails unmatched requests.
+		AfterRequest: func(transaction *remote.Transaction, _ *http.Response) error {
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			err := misses[transaction.Request()]
+			delete(misses, transaction.Request())
+			return err
+		},
+	}
+}
+
+// cassetteKey identifies a request by its method, URL and (optionally) body.
+func cassetteKey(config CassetteConfig, method string, value *url.URL, body string) string {
+
+	result := harKey(method, value)
+
+	if config.MatchBody {
+		result += "\n" + body
+	}
+
+	return result
+}
+
+// httpResponse converts a recorded response into an http.Response.
+func (recorded cassetteResponse) httpResponse(request *http.Request) (*http.Response, error) {
+
+	const location = "remote.options.cassetteResponse.httpResponse"
+
+	body := []byte(recorded.Body)
+
+	if recorded.BodyEncoding == "base64" {
+
+		decoded, err := base64.StdEncoding.DecodeString(recorded.Body)
+
+		if err != nil {
+			return nil, derp.Wrap(err, location, "Unable to decode base64 response body")
+		}
+
+		body = decoded
+	}
+
+	header := recorded.Header.Clone()
+
+	if header == nil {
+		header = http.Header{}
+	}
+
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return &http.Response{
+		Status:        strconv.Itoa(recorded.StatusCode) + " " + http.StatusText(recorded.StatusCode),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+// cassetteMissResponse reports an error, and returns a placeholder response
+// for a request that could not be replayed.
+func cassetteMissResponse(request *http.Request, err error) *http.Response {
+
+	derp.Report(err)
+
+	return &http.Response{
+		Request:    request,
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(err.Error())),
+	}
+}
+
+// readCassette loads a cassette file.
+func readCassette(filename string) (cassette, error) {
+
+	const location = "remote.options.readCassette"
+
+	content, err := os.ReadFile(filename)
+
+	if err != nil {
+		return cassette{}, derp.Wrap(err, location, "Unable to read cassette file", filename)
+	}
+
+	result := cassette{}
+
+	if err := json.Unmarshal(content, &result); err != nil {
+		return cassette{}, derp.Wrap(err, location, "Unable to decode cassette file", filename)
+	}
+
+	return result, nil
+}
+
+// writeCassette saves a cassette file, creating its directory if needed.
+func writeCassette(filename string, recording cassette) error {
+
+	const location = "remote.options.writeCassette"
+
+	content, err := json.MarshalIndent(recording, "", "  ")
+
+	if err != nil {
+		return derp.Wrap(err, location, "Unable to encode cassette", filename)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return derp.Wrap(err, location, "Unable to create cassette directory", filename)
+	}
+
+	if err := os.WriteFile(filename, content, 0o600); err != nil {
+		return derp.Wrap(err, location, "Unable to write cassette file", filename)
+	}
+
+	return nil
+}
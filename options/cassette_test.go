@@ -0,0 +1,100 @@
+package options
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/benpate/remote"
+	"github.com/stretchr/testify/require"
+)
+
+// cassetteServer returns a server that echoes the request path, query and body
+// back as JSON, counting how many times it is called.
+func cassetteServer(t *testing.T, calls *int) *httptest.Server {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		_, _ = w.Write([]byte(`{"method":"` + r.Method + `","page":"` + r.URL.Query().Get("page") + `"}`))
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+
+	calls := 0
+	server := cassetteServer(t, &calls)
+	filename := filepath.Join(t.TempDir(), "cassettes", "users.json")
+
+	// Record two requests against the real server.
+	recorder := Cassette(filename, CassetteRecord)
+
+	require.NoError(t, remote.Get(server.URL+"/users").Query("page", "1").Header("Authorization", "Bearer secret-token").AllowPrivateIPs(true).With(recorder).Send())
+	require.NoError(t, remote.Post(server.URL+"/users").JSON(map[string]string{"name": "Sarah"}).AllowPrivateIPs(true).With(recorder).Send())
+	require.Equal(t, 2, calls)
+
+	// Secrets are scrubbed from the saved file.
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "secret-token")
+	require.NotContains(t, string(content), "secret-cookie")
+
+	// Replay them without contacting the server.
+	player := Cassette(filename, CassetteReplay)
+
+	getResult := map[string]string{}
+	require.NoError(t, remote.Get(server.URL+"/users").Query("page", "1").With(player).Result(&getResult).Send())
+	require.Equal(t, "GET", getResult["method"])
+	require.Equal(t, "1", getResult["page"])
+
+	postResult := map[string]string{}
+	require.NoError(t, remote.Post(server.URL+"/users").JSON(map[string]string{"name": "Somebody Else"}).With(player).Result(&postResult).Send())
+	require.Equal(t, "POST", postResult["method"])
+
+	require.Equal(t, 2, calls)
+}
+
+func TestCassette_ReplayFailsUnmatchedRequests(t *testing.T) {
+
+	calls := 0
+	server := cassetteServer(t, &calls)
+	filename := filepath.Join(t.TempDir(), "cassette.json")
+
+	require.NoError(t, remote.Get(server.URL+"/users").Query("page", "1").AllowPrivateIPs(true).With(Cassette(filename, CassetteRecord)).Send())
+
+	player := Cassette(filename, CassetteReplay)
+
+	// A different query, path or method is not in the cassette.
+	require.Error(t, remote.Get(server.URL+"/users").Query("page", "2").With(player).Send())
+	require.Error(t, remote.Get(server.URL+"/other").Query("page", "1").With(player).Send())
+	require.Error(t, remote.Delete(server.URL+"/users").Query("page", "1").With(player).Send())
+
+	require.Equal(t, 1, calls)
+}
+
+func TestCassette_MatchBody(t *testing.T) {
+
+	calls := 0
+	server := cassetteServer(t, &calls)
+	filename := filepath.Join(t.TempDir(), "cassette.json")
+
+	config := CassetteConfig{Filename: filename, Mode: CassetteRecord, MatchBody: true}
+	require.NoError(t, remote.Post(server.URL+"/inbox").Body("hello").AllowPrivateIPs(true).With(CassetteWith(config)).Send())
+
+	config.Mode = CassetteReplay
+	player := CassetteWith(config)
+
+	require.NoError(t, remote.Post(server.URL+"/inbox").Body("hello").With(player).Send())
+	require.Error(t, remote.Post(server.URL+"/inbox").Body("goodbye").With(player).Send())
+}
+
+func TestCassette_MissingFile(t *testing.T) {
+	err := remote.Get("https://example.com/").With(Cassette(filepath.Join(t.TempDir(), "missing.json"), CassetteReplay)).Send()
+	require.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package options
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/benpate/derp"
+)
+
+// HAR is an HTTP Archive, as defined by the HAR 1.2 specification:
+// http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of an HTTP Archive.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+	Comment string     `json:"comment,omitempty"`
+}
+
+// HARCreator identifies the application that created an HTTP Archive.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a single request/response pair in an HTTP Archive.
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARRequest describes the request in an HAREntry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse describes the response in an HAREntry.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie, or query string parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData describes the body of a request.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARContent describes the body of a response.
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings breaks down the time spent on an HAREntry, in milliseconds.
+// Phases that do not apply are -1.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// ReadHAR decodes an HTTP Archive from reader.
+func ReadHAR(reader io.Reader) (HAR, error) {
+
+	const location = "remote.options.ReadHAR"
+
+	result := HAR{}
+
+	if err := json.NewDecoder(reader).Decode(&result); err != nil {
+		return HAR{}, derp.Wrap(err, location, "Unable to decode HAR file")
+	}
+
+	return result, nil
+}
+
+// WriteTo encodes the HTTP Archive as JSON into writer.
+func (har HAR) WriteTo(writer io.Writer) (int64, error) {
+
+	const location = "remote.options.HAR.WriteTo"
+
+	content, err := json.MarshalIndent(har, "", "  ")
+
+	if err != nil {
+		return 0, derp.Wrap(err, location, "Unable to encode HAR file")
+	}
+
+	count, err := writer.Write(content)
+
+	if err != nil {
+		return int64(count), derp.Wrap(err, location, "Unable to write HAR file")
+	}
+
+	return int64(count), nil
+}
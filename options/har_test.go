@@ -0,0 +1,228 @@
+package options
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/benpate/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordHAR(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder(1024)
+
+	err := remote.Post(server.URL+"/inbox").
+		AllowPrivateIPs(true).
+		Query("access_token", "secret-token").
+		Header("Authorization", "Bearer secret-token").
+		JSON(map[string]string{"type": "Follow"}).
+		With(RecordHAR(recorder)).
+		Send()
+
+	require.NoError(t, err)
+
+	har := recorder.HAR()
+	require.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 1)
+
+	entry := har.Log.Entries[0]
+	require.Equal(t, "POST", entry.Request.Method)
+	require.Equal(t, `{"type":"Follow"}`, entry.Request.PostData.Text)
+	require.Equal(t, "application/json", entry.Request.PostData.MimeType)
+	require.Equal(t, http.StatusCreated, entry.Response.Status)
+	require.Equal(t, "Created", entry.Response.StatusText)
+	require.Equal(t, `{"path":"/inbox"}`, entry.Response.Content.Text)
+	require.Equal(t, "127.0.0.1", entry.ServerIPAddress)
+	require.NotEmpty(t, entry.StartedDateTime)
+
+	// Credentials are redacted from the archive.
+	var buffer bytes.Buffer
+	_, err = recorder.WriteTo(&buffer)
+	require.NoError(t, err)
+	require.NotContains(t, buffer.String(), "secret-token")
+	require.Contains(t, buffer.String(), `"version": "1.2"`)
+}
+
+func TestRecordHAR_RedactsBodies(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		_, _ = w.Write([]byte("access_token=secret-response&token_type=bearer"))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder(1024)
+
+	err := remote.Post(server.URL + "/token").
+		AllowPrivateIPs(true).
+		JSON(map[string]string{"username": "alice", "password": "secret-request"}).
+		With(RecordHAR(recorder)).
+		Send()
+
+	require.NoError(t, err)
+
+	entry := recorder.HAR().Log.Entries[0]
+	require.Equal(t, `{"password":"[REDACTED]","username":"alice"}`, entry.Request.PostData.Text)
+	require.Contains(t, entry.Response.Content.Text, "token_type=bearer")
+	require.NotContains(t, entry.Response.Content.Text, "secret-response")
+
+	var buffer bytes.Buffer
+	_, err = recorder.WriteTo(&buffer)
+	require.NoError(t, err)
+	require.NotContains(t, buffer.String(), "secret-")
+}
+
+func TestRecordHAR_BinaryAndTruncated(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/binary" {
+			_, _ = w.Write([]byte{0xff, 0xfe, 0x00})
+			return
+		}
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder(4)
+
+	require.NoError(t, remote.Get(server.URL+"/binary").AllowPrivateIPs(true).With(RecordHAR(recorder)).Send())
+	require.NoError(t, remote.Get(server.URL+"/text").AllowPrivateIPs(true).With(RecordHAR(recorder)).Send())
+
+	entries := recorder.HAR().Log.Entries
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "base64", entries[0].Response.Content.Encoding)
+	require.Equal(t, "//4A", entries[0].Response.Content.Text)
+
+	require.Equal(t, "0123", entries[1].Response.Content.Text)
+	require.Equal(t, "truncated", entries[1].Response.Content.Comment)
+	require.Equal(t, 10, entries[1].Response.Content.Size)
+}
+
+func TestRecordHAR_Failure(t *testing.T) {
+
+	recorder := NewHARRecorder(0)
+
+	err := remote.Get("http://127.0.0.1:0").AllowPrivateIPs(true).With(RecordHAR(recorder)).Send()
+	require.Error(t, err)
+
+	entries := recorder.HAR().Log.Entries
+	require.Len(t, entries, 1)
+	require.Zero(t, entries[0].Response.Status)
+	require.NotEmpty(t, entries[0].Comment)
+}
+
+func TestHARServer_RoundTrip(t *testing.T) {
+
+	// Record a conversation with a real server...
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"page":"` + r.URL.Query().Get("page") + `"}`))
+	}))
+
+	recorder := NewHARRecorder(1024)
+
+	for _, page := range []string{"1", "2"} {
+		require.NoError(t, remote.Get(server.URL+"/items").Query("page", page).AllowPrivateIPs(true).With(RecordHAR(recorder)).Send())
+	}
+
+	server.Close()
+
+	// ...then write it out, read it back in, and replay it without the server.
+	var buffer bytes.Buffer
+	_, err := recorder.WriteTo(&buffer)
+	require.NoError(t, err)
+
+	har, err := ReadHAR(&buffer)
+	require.NoError(t, err)
+
+	replay := HARServer(har)
+
+	for _, page := range []string{"2", "1"} {
+		result := map[string]string{}
+		require.NoError(t, remote.Get(server.URL+"/items").Query("page", page).With(replay).Result(&result).Send())
+		require.Equal(t, page, result["page"])
+	}
+
+	require.Equal(t, 2, calls)
+
+	// An unrecorded request for a recorded host is a 404.
+	err = remote.Get(server.URL + "/missing").With(replay).Send()
+	require.Error(t, err)
+}
+
+func TestHARServer_RedactedQuery(t *testing.T) {
+
+	// Record an authenticated call...
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"page":"` + r.URL.Query().Get("page") + `"}`))
+	}))
+
+	recorder := NewHARRecorder(1024)
+	require.NoError(t, remote.Get(server.URL+"/timeline").Query("access_token", "secret-token").Query("page", "1").AllowPrivateIPs(true).With(RecordHAR(recorder)).Send())
+	require.NoError(t, remote.Get(server.URL+"/timeline").Query("access_token", "secret-token").Query("page", "2").AllowPrivateIPs(true).With(RecordHAR(recorder)).Send())
+	server.Close()
+
+	var buffer bytes.Buffer
+	_, err := recorder.WriteTo(&buffer)
+	require.NoError(t, err)
+	require.NotContains(t, buffer.String(), "secret-token")
+
+	har, err := ReadHAR(&buffer)
+	require.NoError(t, err)
+
+	replay := HARServer(har)
+
+	// ...then replay it with any token, while other parameters must still match
+	for _, page := range []string{"2", "1"} {
+		result := map[string]string{}
+		require.NoError(t, remote.Get(server.URL+"/timeline").Query("access_token", "another-token").Query("page", page).With(replay).Result(&result).Send())
+		require.Equal(t, page, result["page"])
+	}
+
+	require.Error(t, remote.Get(server.URL+"/timeline").Query("access_token", "another-token").Query("page", "3").With(replay).Send())
+	require.Error(t, remote.Get(server.URL+"/timeline").Query("page", "1").With(replay).Send())
+}
+
+func TestHARServer_Sequence(t *testing.T) {
+
+	entry := func(status int) HAREntry {
+		return HAREntry{
+			Request:  HARRequest{Method: "GET", URL: "https://example.com/retry"},
+			Response: HARResponse{Status: status, HTTPVersion: "HTTP/1.1"},
+		}
+	}
+
+	replay := HARServer(HAR{Log: HARLog{Entries: []HAREntry{entry(503), entry(200)}}})
+
+	request, err := http.NewRequest(http.MethodGet, "https://example.com/retry", nil)
+	require.NoError(t, err)
+
+	// Responses are returned in order, and the last one repeats.
+	require.Equal(t, 503, replay.ModifyRequest(nil, request).StatusCode)
+	require.Equal(t, 200, replay.ModifyRequest(nil, request).StatusCode)
+	require.Equal(t, 200, replay.ModifyRequest(nil, request).StatusCode)
+
+	// Other hosts pass through to the network.
+	other, err := http.NewRequest(http.MethodGet, "https://other.com/retry", nil)
+	require.NoError(t, err)
+	require.Nil(t, replay.ModifyRequest(nil, other))
+}
+
+func TestReadHAR_Invalid(t *testing.T) {
+	_, err := ReadHAR(bytes.NewReader([]byte("not a har file")))
+	require.Error(t, err)
+}
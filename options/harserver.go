@@ -0,0 +1,211 @@
+package options
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/benpate/derp"
+	"github.com/benpate/remote"
+)
+
+// HARServer is a remote.Option that mocks requests by replaying the responses
+// recorded in an HTTP Archive. Requests are matched on method and full URL
+// (including the query string). A query value that was redacted when it was
+// recorded (such as "access_token=[REDACTED]") matches any value, so recorded
+// credentials do not need to be known to replay them. When the archive holds
+// several entries for the same request, they are returned in order, and the
+// last one repeats. Requests for hosts that do not appear in the archive are
+// sent to the network as usual, while unmatched requests for recorded hosts
+// receive a 404.
+func HARServer(har HAR) remote.Option {
+
+	const location = "remote.options.HARServer"
+
+	hosts := map[string]bool{}
+	entries := map[string][]HAREntry{}
+	wildcards := []harWildcard{}
+
+	for _, entry := range har.Log.Entries {
+
+		parsed, err := url.Parse(entry.Request.URL)
+
+		if err != nil {
+			continue
+		}
+
+		hosts[parsed.Hostname()] = true
+		key := harKey(entry.Request.Method, parsed)
+
+		// Remember the entries with redacted values, in the order they were recorded
+		if _, exists := entries[key]; !exists && harIsRedacted(parsed) {
+			wildcards = append(wildcards, harWildcard{key: key, method: strings.ToUpper(entry.Request.Method), url: parsed})
+		}
+
+		entries[key] = append(entries[key], entry)
+	}
+
+	// Track how many times each request has been replayed.
+	var mutex sync.Mutex
+	counts := map[string]int{}
+
+	return remote.Option{
+
+		ModifyRequest: func(_ *remote.Transaction, request *http.Request) *http.Response {
+
+			// Only match requests for hosts in the archive
+			if !hosts[request.URL.Hostname()] {
+				return nil
+			}
+
+			key := harKey(request.Method, request.URL)
+
+			// Without an exact match, look for a recording with redacted values
+			if _, exists := entries[key]; !exists {
+				for _, wildcard := range wildcards {
+					if wildcard.matches(request) {
+						key = wildcard.key
+						break
+					}
+				}
+			}
+
+			mutex.Lock()
+			matches := entries[key]
+			index := min(counts[key], len(matches)-1)
+			counts[key]++
+			mutex.Unlock()
+
+			if len(matches) == 0 {
+				return testServerError(request, derp.NotFound(location, "No recorded response for request", request.Method, request.URL.String()))
+			}
+
+			response, err := harResponse(request, matches[index].Response)
+
+			if err != nil {
+				return testServerError(request, derp.Wrap(err, location, "Unable to replay recorded response", request.URL.String()))
+			}
+
+			// Look at me, I'm a real server now.
+			return response
+		},
+	}
+}
+
+// harKey identifies a request by its method and URL. Query parameters are
+// sorted, so that their order does not affect matching.
+func harKey(method string, value *url.URL) string {
+
+	normalized := *value
+	normalized.RawQuery = normalized.Query().Encode()
+	normalized.Fragment = ""
+
+	return strings.ToUpper(method) + " " + normalized.String()
+}
+
+// harWildcard is a recorded request whose query string includes redacted values.
+type harWildcard struct {
+	key    string
+	method string
+	url    *url.URL
+}
+
+// matches reports whether a request has the same method, URL, and query
+// parameters as the recording, where redacted values match any value.
+func (wildcard harWildcard) matches(request *http.Request) bool {
+
+	if !strings.EqualFold(wildcard.method, request.Method) ||
+		(wildcard.url.Scheme != request.URL.Scheme) ||
+		!strings.EqualFold(wildcard.url.Host, request.URL.Host) ||
+		(wildcard.url.Path != request.URL.Path) {
+		return false
+	}
+
+	recorded := wildcard.url.Query()
+	received := request.URL.Query()
+
+	if len(recorded) != len(received) {
+		return false
+	}
+
+	for name, values := range recorded {
+
+		if len(values) != len(received[name]) {
+			return false
+		}
+
+		for index, value := range values {
+			if (value != redactedValue) && (value != received[name][index]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// harIsRedacted reports whether any query value in a URL was redacted.
+func harIsRedacted(value *url.URL) bool {
+
+	for _, values := range value.Query() {
+		if slices.Contains(values, redactedValue) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// harResponse converts a recorded HARResponse into an http.Response.
+func harResponse(request *http.Request, recorded HARResponse) (*http.Response, error) {
+
+	const location = "remote.options.harResponse"
+
+	body := []byte(recorded.Content.Text)
+
+	if recorded.Content.Encoding == "base64" {
+
+		decoded, err := base64.StdEncoding.DecodeString(recorded.Content.Text)
+
+		if err != nil {
+			return nil, derp.Wrap(err, location, "Unable to decode base64 response body")
+		}
+
+		body = decoded
+	}
+
+	header := http.Header{}
+
+	for _, item := range recorded.Headers {
+		header.Add(item.Name, item.Value)
+	}
+
+	// The recorded body has already been decoded and may have been truncated,
+	// so these headers no longer describe it.
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	protoMajor, protoMinor, ok := http.ParseHTTPVersion(recorded.HTTPVersion)
+
+	if !ok {
+		protoMajor, protoMinor = 1, 1
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(recorded.Status) + " " + recorded.StatusText,
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/" + strconv.Itoa(protoMajor) + "." + strconv.Itoa(protoMinor),
+		ProtoMajor:    protoMajor,
+		ProtoMinor:    protoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package options
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/benpate/remote"
+)
+
+// LogConfig configures the structured logging done by LogWith.
+type LogConfig struct {
+	Logger           *slog.Logger // logger that receives the records (nil uses slog.Default)
+	Level            slog.Level   // level for successful transactions; failures are logged at slog.LevelError or higher
+	MaxBodySize      int          // number of bytes of each body to include in the record (zero omits bodies)
+	RedactHeaders    []string     // additional headers to redact, beyond the defaults (Authorization, Cookie, etc.)
+	RedactQuery      []string     // additional query and form parameters to redact, beyond the defaults (access_token, password, etc.)
+	RedactJSONFields []string     // additional JSON fields to redact, beyond the defaults (password, token, etc.)
+}
+
+// Log is a remote.Option that writes one structured log record per transaction
+// to logger, including the method, URL, status, duration, sizes, and error.
+// Credentials in headers and query strings are redacted, and bodies are omitted.
+// Unlike Debug, it is safe to use in production.
+func Log(logger *slog.Logger, level slog.Level) remote.Option {
+	return LogWith(LogConfig{
+		Logger: logger,
+		Level:  level,
+	})
+}
+
+// LogWith is a remote.Option that writes one structured log record per
+// transaction, using the provided configuration to capture (redacted) bodies
+// and to redact additional headers, query parameters, and JSON fields.
+func LogWith(config LogConfig) remote.Option {
+
+	redact := newRedactor(config.RedactHeaders, config.RedactQuery, config.RedactJSONFields)
+
+	return remote.Option{
+
+		// This is executed once the transaction is complete, whether it succeeded or not.
+		OnComplete: func(transaction *remote.Transaction, err error) {
+
+			logger := config.Logger
+
+			if logger == nil {
+				logger = slog.Default()
+			}
+
+			level := config.Level
+
+			if (err != nil) && (level < slog.LevelError) {
+				level = slog.LevelError
+			}
+
+			if !logger.Enabled(context.Background(), level) {
+				return
+			}
+
+			logger.LogAttrs(context.Background(), level, "remote: HTTP transaction", logAttributes(transaction, err, config, redact)...)
+		},
+	}
+}
+
+// logAttributes assembles the attributes of a single log record.
+func logAttributes(transaction *remote.Transaction, err error, config LogConfig, redact redactor) []slog.Attr {
+
+	request := transaction.Request()
+	response := transaction.Response()
+
+	result := []slog.Attr{
+		slog.String("url", redact.URL(transaction.RequestURL())),
+		slog.Int("status", transaction.ResponseStatusCode()),
+		slog.Duration("duration", transaction.Timings().Total),
+	}
+
+	// Request details are only available once the request has been assembled.
+	if request != nil {
+		requestBody := requestBodyBytes(request)
+
+		result = append(result,
+			slog.String("method", request.Method),
+			slog.Int("request_size", len(requestBody)),
+			slog.Any("request_header", redact.Header(request.Header)),
+		)
+
+		if config.MaxBodySize > 0 {
+			result = append(result, slog.String("request_body", truncate(redact.Body(request.Header.Get(remote.ContentType), requestBody), config.MaxBodySize)))
+		}
+	}
+
+	// Response details are only available if the server responded.
+	if response != nil {
+		responseBody, readErr := transaction.ResponseBody()
+
+		result = append(result, slog.Any("response_header", redact.Header(response.Header)))
+
+		if readErr == nil {
+			result = append(result, slog.Int("response_size", len(responseBody)))
+
+			if config.MaxBodySize > 0 {
+				result = append(result, slog.String("response_body", truncate(redact.Body(transaction.ResponseContentType(), responseBody), config.MaxBodySize)))
+			}
+		}
+	}
+
+	if err != nil {
+		result = append(result, slog.String("error", err.Error()))
+	}
+
+	return result
+}
+
+// requestBodyBytes returns a fresh copy of the request body, without consuming
+// the body that was (or will be) sent.
+func requestBodyBytes(request *http.Request) []byte {
+
+	if request.GetBody == nil {
+		return nil
+	}
+
+	body, err := request.GetBody()
+
+	if err != nil {
+		return nil
+	}
+
+	defer func() {
+		_ = body.Close()
+	}()
+
+	result, err := io.ReadAll(body)
+
+	if err != nil {
+		return nil
+	}
+
+	return result
+}
+
+// truncate returns body as a string, cut to at most maxSize bytes.
+func truncate(body []byte, maxSize int) string {
+
+	if len(body) > maxSize {
+		return string(body[:maxSize]) + "..."
+	}
+
+	return string(body)
+}
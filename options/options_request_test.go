@@ -1,8 +1,11 @@
 package options
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -161,3 +164,143 @@ func TestTestServer_Integration(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "mocky", result["name"])
 }
+
+// rawResponse returns a raw HTTP response fixture with the given status and body.
+func rawResponse(status string, body string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte("HTTP/1.1 " + status + "\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n" +
+		"\r\n" + body)}
+}
+
+// testServerBody sends a request through a TestServer option and returns the response body.
+func testServerBody(t *testing.T, option remote.Option, method string, url string, body string) (int, string) {
+	t.Helper()
+
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+
+	response := option.ModifyRequest(nil, request)
+	require.NotNil(t, response)
+
+	content, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+
+	return response.StatusCode, string(content)
+}
+
+func TestTestServer_MethodAndQuery(t *testing.T) {
+
+	filesystem := fstest.MapFS{
+		"GET/users/1.json":          rawResponse("200 OK", "get"),
+		"POST/users/1.json":         rawResponse("201 Created", "post"),
+		"users/1.json":              rawResponse("200 OK", "any method"),
+		"GET/users/1.json%3Fpage=2": rawResponse("200 OK", "page 2"),
+		"users/1.json%3Fa=1&b=2":    rawResponse("200 OK", "sorted query"),
+		"search%3Fq=a%2Fb+c":        rawResponse("200 OK", "escaped query"),
+	}
+
+	option := TestServer("example.com", filesystem)
+
+	cases := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{http.MethodGet, "http://example.com/users/1.json", "get"},
+		{http.MethodPost, "http://example.com/users/1.json", "post"},
+		{http.MethodDelete, "http://example.com/users/1.json", "any method"},
+		{http.MethodGet, "http://example.com/users/1.json?page=2", "page 2"},
+		{http.MethodGet, "http://example.com/users/1.json?page=3", "get"},
+		{http.MethodPut, "http://example.com/users/1.json?b=2&a=1", "sorted query"},
+		{http.MethodGet, "http://example.com/search?q=a/b%20c", "escaped query"},
+	}
+
+	for _, test := range cases {
+		status, body := testServerBody(t, option, test.method, test.url, "")
+		require.Equal(t, 200, status/100*100, "method=%s url=%s", test.method, test.url)
+		require.Equal(t, test.body, body, "method=%s url=%s", test.method, test.url)
+	}
+}
+
+func TestTestServer_Sequence(t *testing.T) {
+
+	filesystem := fstest.MapFS{
+		"GET/page.json#1": rawResponse("503 Service Unavailable", "retry"),
+		"GET/page.json#2": rawResponse("200 OK", "first"),
+		"GET/page.json#3": rawResponse("200 OK", "last"),
+	}
+
+	option := TestServer("example.com", filesystem)
+
+	expected := []string{"retry", "first", "last", "last"}
+
+	for _, want := range expected {
+		_, body := testServerBody(t, option, http.MethodGet, "http://example.com/page.json", "")
+		require.Equal(t, want, body)
+	}
+}
+
+func TestTestServer_QuerySequence(t *testing.T) {
+
+	filesystem := fstest.MapFS{
+		"GET/feed%3Fpage=2#1": rawResponse("200 OK", "first"),
+		"GET/feed%3Fpage=2#2": rawResponse("200 OK", "second"),
+	}
+
+	option := TestServer("example.com", filesystem)
+
+	for _, want := range []string{"first", "second", "second"} {
+		_, body := testServerBody(t, option, http.MethodGet, "http://example.com/feed?page=2", "")
+		require.Equal(t, want, body)
+	}
+
+	// Fixture names are portable, so "?" is not used
+	status, _ := testServerBody(t, TestServer("example.com", fstest.MapFS{"GET/feed?page=2": rawResponse("200 OK", "unused")}), http.MethodGet, "http://example.com/feed?page=2", "")
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestTestServerRoutes(t *testing.T) {
+
+	filesystem := fstest.MapFS{
+		"follow.http":   rawResponse("202 Accepted", "follow"),
+		"undo-1.http":   rawResponse("500 Internal Server Error", "undo failed"),
+		"undo-2.http":   rawResponse("202 Accepted", "undo"),
+		"signed.http":   rawResponse("202 Accepted", "signed"),
+		"POST/inbox":    rawResponse("400 Bad Request", "fallback"),
+		"GET/inbox.txt": rawResponse("200 OK", "unused"),
+	}
+
+	option := TestServerRoutes("example.com", filesystem,
+		TestRoute{Method: "POST", Path: "/inbox", BodyContains: `"type":"Follow"`, Files: []string{"follow.http"}},
+		TestRoute{Method: "POST", Path: "/inbox", BodyContains: `"type":"Undo"`, Files: []string{"undo-1.http", "undo-2.http"}},
+		TestRoute{Path: "/inbox", Header: http.Header{"Signature": {"valid"}}, Files: []string{"signed.http"}},
+		TestRoute{Path: "/broken"},
+	)
+
+	_, body := testServerBody(t, option, http.MethodPost, "http://example.com/inbox", `{"type":"Follow"}`)
+	require.Equal(t, "follow", body)
+
+	// Repeated calls step through the route's files, and the last one repeats.
+	for _, want := range []string{"undo failed", "undo", "undo"} {
+		_, body = testServerBody(t, option, http.MethodPost, "http://example.com/inbox", `{"type":"Undo"}`)
+		require.Equal(t, want, body)
+	}
+
+	// Header matching.
+	request, err := http.NewRequest(http.MethodPost, "http://example.com/inbox", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	request.Header.Set("Signature", "valid")
+	response := option.ModifyRequest(nil, request)
+	require.Equal(t, http.StatusAccepted, response.StatusCode)
+
+	// Requests that match no route fall back to the filename rules.
+	status, body := testServerBody(t, option, http.MethodPost, "http://example.com/inbox", `{"type":"Like"}`)
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "fallback", body)
+
+	// A route without files is an error.
+	status, _ = testServerBody(t, option, http.MethodGet, "http://example.com/broken", "")
+	require.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,253 @@
+package options
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/benpate/remote"
+)
+
+// HARRecorder collects transactions into an HTTP Archive. It is safe to share
+// across many concurrent transactions. Add it to a transaction with RecordHAR.
+type HARRecorder struct {
+	maxBodySize int
+	redact      redactor
+	mutex       sync.Mutex
+	entries     []HAREntry
+}
+
+// NewHARRecorder returns a recorder that keeps up to maxBodySize bytes of each
+// request and response body (zero or less keeps no bodies). Credentials in
+// headers, query strings, and JSON or form-encoded bodies are redacted, so the
+// archive can be shared.
+func NewHARRecorder(maxBodySize int) *HARRecorder {
+	return &HARRecorder{
+		maxBodySize: maxBodySize,
+		redact:      newRedactor(nil, nil, nil),
+		entries:     make([]HAREntry, 0),
+	}
+}
+
+// RecordHAR is a remote.Option that adds every transaction to recorder.
+func RecordHAR(recorder *HARRecorder) remote.Option {
+
+	return remote.Option{
+
+		// This is executed once the transaction is complete, whether it succeeded or not.
+		OnComplete: func(transaction *remote.Transaction, err error) {
+			recorder.record(transaction, err)
+		},
+	}
+}
+
+// HAR returns a snapshot of every transaction recorded so far.
+func (recorder *HARRecorder) HAR() HAR {
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return HAR{
+		Log: HARLog{
+			Version: "1.2",
+			Creator: HARCreator{Name: "github.com/benpate/remote", Version: "1.0"},
+			Entries: slices.Clone(recorder.entries),
+		},
+	}
+}
+
+// WriteTo writes every transaction recorded so far to writer, as a HAR 1.2 file.
+func (recorder *HARRecorder) WriteTo(writer io.Writer) (int64, error) {
+	return recorder.HAR().WriteTo(writer)
+}
+
+// record converts a completed transaction into an HAREntry.
+func (recorder *HARRecorder) record(transaction *remote.Transaction, err error) {
+
+	request := transaction.Request()
+
+	// Nothing was sent, so there is nothing to record.
+	if request == nil {
+		return
+	}
+
+	timings := transaction.Timings()
+
+	entry := HAREntry{
+		StartedDateTime: time.Now().Add(-timings.Total).UTC().Format(time.RFC3339Nano),
+		Time:            milliseconds(timings.Total),
+		Request:         recorder.request(request),
+		Response:        recorder.response(transaction),
+		Timings:         harTimings(timings),
+		ServerIPAddress: serverIPAddress(timings.RemoteAddr),
+	}
+
+	if err != nil {
+		entry.Comment = err.Error()
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.entries = append(recorder.entries, entry)
+}
+
+// request converts an http.Request into an HARRequest.
+func (recorder *HARRecorder) request(request *http.Request) HARRequest {
+
+	result := HARRequest{
+		Method:      request.Method,
+		URL:         recorder.redact.URL(request.URL.String()),
+		HTTPVersion: request.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harNameValues(recorder.redact.Header(request.Header)),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+
+	if parsed, err := url.Parse(result.URL); err == nil {
+		result.QueryString = harNameValues(parsed.Query())
+	}
+
+	if body := requestBodyBytes(request); len(body) > 0 {
+
+		result.BodySize = len(body)
+
+		if recorder.maxBodySize > 0 {
+			text, _, comment := recorder.bodyText(recorder.redact.Body(request.Header.Get(remote.ContentType), body))
+			result.PostData = &HARPostData{
+				MimeType: request.Header.Get(remote.ContentType),
+				Text:     text,
+				Comment:  comment,
+			}
+		}
+	}
+
+	return result
+}
+
+// response converts a transaction's response into an HARResponse. A transaction
+// that failed before receiving a response is recorded with a status of zero.
+func (recorder *HARRecorder) response(transaction *remote.Transaction) HARResponse {
+
+	result := HARResponse{
+		Cookies:     []HARNameValue{},
+		Headers:     []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+
+	response := transaction.Response()
+
+	if response == nil {
+		return result
+	}
+
+	result.Status = response.StatusCode
+	result.StatusText = http.StatusText(response.StatusCode)
+	result.HTTPVersion = response.Proto
+	result.Headers = harNameValues(recorder.redact.Header(response.Header))
+	result.RedirectURL = response.Header.Get("Location")
+	result.Content.MimeType = response.Header.Get(remote.ContentType)
+
+	if body, err := transaction.ResponseBody(); err == nil {
+
+		result.BodySize = len(body)
+		result.Content.Size = len(body)
+
+		if recorder.maxBodySize > 0 {
+			body = recorder.redact.Body(response.Header.Get(remote.ContentType), body)
+			result.Content.Text, result.Content.Encoding, result.Content.Comment = recorder.bodyText(body)
+		}
+	}
+
+	return result
+}
+
+// bodyText returns the text of a body (truncated to maxBodySize), along with
+// its encoding ("base64" for binary content) and a comment if it was truncated.
+func (recorder *HARRecorder) bodyText(body []byte) (string, string, string) {
+
+	comment := ""
+
+	if len(body) > recorder.maxBodySize {
+		body = body[:recorder.maxBodySize]
+		comment = "truncated"
+	}
+
+	if utf8.Valid(body) {
+		return string(body), "", comment
+	}
+
+	return base64.StdEncoding.EncodeToString(body), "base64", comment
+}
+
+// harNameValues converts a header (or url.Values) into a sorted list of HARNameValues.
+func harNameValues(values map[string][]string) []HARNameValue {
+
+	result := make([]HARNameValue, 0, len(values))
+
+	for name, list := range values {
+		for _, value := range list {
+			result = append(result, HARNameValue{Name: name, Value: value})
+		}
+	}
+
+	slices.SortStableFunc(result, func(a HARNameValue, b HARNameValue) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return result
+}
+
+// harTimings converts remote.Timings into HARTimings.
+func harTimings(timings remote.Timings) HARTimings {
+
+	result := HARTimings{
+		Blocked: -1,
+		DNS:     optionalMilliseconds(timings.DNSLookup),
+		Connect: optionalMilliseconds(timings.Connect + timings.TLSHandshake), // HAR includes SSL in connect
+		SSL:     optionalMilliseconds(timings.TLSHandshake),
+		Receive: milliseconds(timings.BodyRead),
+	}
+
+	// Waiting is whatever part of the time to first byte was not spent connecting.
+	wait := timings.TimeToFirstByte - timings.DNSLookup - timings.Connect - timings.TLSHandshake
+	result.Wait = milliseconds(max(wait, 0))
+
+	return result
+}
+
+// serverIPAddress strips the port from a remote address.
+func serverIPAddress(remoteAddr string) string {
+
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+
+	return remoteAddr
+}
+
+// milliseconds converts a duration into fractional milliseconds.
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// optionalMilliseconds converts a duration into fractional milliseconds, or
+// -1 if the phase did not happen.
+func optionalMilliseconds(duration time.Duration) float64 {
+
+	if duration == 0 {
+		return -1
+	}
+
+	return milliseconds(duration)
+}
@@ -0,0 +1,189 @@
+package options
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// redactedValue replaces any secret that is removed from logs or recordings.
+const redactedValue = "[REDACTED]"
+
+// defaultRedactHeaders are the HTTP headers that commonly carry credentials.
+var defaultRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"Signature",
+	"X-Api-Key",
+}
+
+// defaultRedactQuery are the query string (and form) parameters that commonly carry credentials.
+var defaultRedactQuery = []string{
+	"access_token",
+	"api_key",
+	"client_secret",
+	"password",
+	"refresh_token",
+	"token",
+}
+
+// defaultRedactJSON are the JSON fields that commonly carry credentials.
+var defaultRedactJSON = []string{
+	"access_token",
+	"client_secret",
+	"password",
+	"privateKeyPem",
+	"refresh_token",
+	"secret",
+	"token",
+}
+
+// redactor removes secrets from headers, URLs and bodies. Every name is matched
+// case-insensitively, and the default lists always apply.
+type redactor struct {
+	headers []string
+	query   []string
+	json    []string
+}
+
+// newRedactor returns a redactor for the default names, plus any extras.
+func newRedactor(headers []string, query []string, fields []string) redactor {
+	return redactor{
+		headers: lowerAll(defaultRedactHeaders, headers),
+		query:   lowerAll(defaultRedactQuery, query),
+		json:    lowerAll(defaultRedactJSON, fields),
+	}
+}
+
+// Header returns a copy of header with sensitive values replaced.
+func (r redactor) Header(header http.Header) http.Header {
+
+	result := make(http.Header, len(header))
+
+	for name, values := range header {
+		if slices.Contains(r.headers, strings.ToLower(name)) {
+			result[name] = []string{redactedValue}
+			continue
+		}
+		result[name] = slices.Clone(values)
+	}
+
+	return result
+}
+
+// URL returns value with sensitive query parameters replaced.
+func (r redactor) URL(value string) string {
+
+	parsed, err := url.Parse(value)
+
+	if err != nil {
+		return value
+	}
+
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = r.Values(parsed.RawQuery)
+	}
+
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		parsed.User = url.UserPassword(parsed.User.Username(), redactedValue)
+	}
+
+	return parsed.String()
+}
+
+// Values returns an encoded query string (or form body) with sensitive parameters replaced.
+func (r redactor) Values(encoded string) string {
+
+	values, err := url.ParseQuery(encoded)
+
+	if err != nil {
+		return encoded
+	}
+
+	changed := false
+
+	for name := range values {
+		if slices.Contains(r.query, strings.ToLower(name)) {
+			values[name] = []string{redactedValue}
+			changed = true
+		}
+	}
+
+	// Leave the original encoding alone unless something was redacted.
+	if !changed {
+		return encoded
+	}
+
+	return values.Encode()
+}
+
+// Body returns a body with sensitive values replaced, based on its content type.
+// Bodies that are not JSON or form encoded are returned unchanged.
+func (r redactor) Body(contentType string, body []byte) []byte {
+
+	contentType, _, _ = strings.Cut(contentType, ";")
+	contentType = strings.TrimSpace(contentType)
+
+	if contentType == "application/x-www-form-urlencoded" {
+		return []byte(r.Values(string(body)))
+	}
+
+	if (contentType != "application/json") && !strings.HasSuffix(contentType, "+json") {
+		return body
+	}
+
+	var document any
+
+	if err := json.Unmarshal(body, &document); err != nil {
+		return body
+	}
+
+	result, err := json.Marshal(r.jsonValue(document))
+
+	if err != nil {
+		return body
+	}
+
+	return result
+}
+
+// jsonValue walks a decoded JSON document, replacing sensitive fields.
+func (r redactor) jsonValue(value any) any {
+
+	switch typed := value.(type) {
+
+	case map[string]any:
+		for name, item := range typed {
+			if slices.Contains(r.json, strings.ToLower(name)) {
+				typed[name] = redactedValue
+				continue
+			}
+			typed[name] = r.jsonValue(item)
+		}
+
+	case []any:
+		for index, item := range typed {
+			typed[index] = r.jsonValue(item)
+		}
+	}
+
+	return value
+}
+
+// lowerAll combines lists of names into a single lowercase list.
+func lowerAll(lists ...[]string) []string {
+
+	result := make([]string, 0)
+
+	for _, list := range lists {
+		for _, name := range list {
+			result = append(result, strings.ToLower(name))
+		}
+	}
+
+	return result
+}
@@ -6,26 +6,62 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/benpate/derp"
 	"github.com/benpate/remote"
 )
 
+// testServerQuerySeparator separates a fixture's path from its query string.
+// It stands in for "?", which is not allowed in file names on Windows or in
+// Go module zips. Query strings are URL-encoded, so they need no other changes.
+const testServerQuerySeparator = "%3F"
+
+// TestRoute selects a fixture file (or a sequence of them) for requests that
+// match all of its non-empty fields. It lets TestServerRoutes match on request
+// headers and bodies, which cannot be expressed in a filename.
+type TestRoute struct {
+	Method       string      // (if set) request method must match, e.g. "POST"
+	Path         string      // (if set) request path must match exactly, e.g. "/inbox"
+	Query        url.Values  // (if set) request must include each of these query values
+	Header       http.Header // (if set) request must include each of these header values
+	BodyContains string      // (if set) request body must contain this text
+	Files        []string    // fixture files to return, in order, on repeated calls (the last one repeats)
+}
+
 // TestServer is a remote.Option that mocks requests for a specific hostname.
+// Each request is answered with a raw HTTP response file from the filesystem,
+// chosen by trying these names in order (the first that exists wins):
+//
+//	GET/users/1.json%3Fpage=2   method, path and sorted query string
+//	users/1.json%3Fpage=2       path and sorted query string
+//	GET/users/1.json            method and path
+//	users/1.json                path only
+//
+// The "?" before the query string is written as "%3F", so that fixture files
+// can be checked out on any platform and published in a Go module.
+//
+// If none of these exist, but numbered files such as "GET/users/1.json#1" and
+// "GET/users/1.json#2" do, then repeated calls return them in order, and the
+// last one repeats. Requests with no matching file receive a 404.
 func TestServer(hostname string, filesystem fs.FS) remote.Option {
+	return TestServerRoutes(hostname, filesystem)
+}
 
-	// errorResponse just simplifies error handling in the actual option.
-	errorResponse := func(request *http.Request, err error) *http.Response {
-
-		derp.Report(err)
-		body := io.NopCloser(strings.NewReader(err.Error()))
+// TestServerRoutes is a remote.Option that mocks requests for a specific
+// hostname, like TestServer, but first checks each of the routes in order. The
+// first route that matches a request chooses its fixture files. Requests that
+// match no route fall back to TestServer's filename rules.
+func TestServerRoutes(hostname string, filesystem fs.FS, routes ...TestRoute) remote.Option {
 
-		return &http.Response{
-			Request:    request,
-			StatusCode: http.StatusNotFound,
-			Body:       body,
-		}
+	server := &testServer{
+		filesystem: filesystem,
+		routes:     routes,
+		counts:     map[string]int{},
 	}
 
 	// Generate the actual option
@@ -38,29 +74,178 @@ func TestServer(hostname string, filesystem fs.FS) remote.Option {
 				return nil
 			}
 
-			// Locate the file using the URL path
-			filename := strings.TrimPrefix(request.URL.Path, "/")
-			file, err := filesystem.Open(filename)
+			return server.respond(request)
+		},
+	}
+}
+
+// testServer holds the fixtures and sequence counters for a TestServer option.
+type testServer struct {
+	filesystem fs.FS
+	routes     []TestRoute
+	mutex      sync.Mutex
+	counts     map[string]int
+}
+
+// respond locates the fixture file for a request, and reads the response from it.
+func (server *testServer) respond(request *http.Request) *http.Response {
+
+	const location = "remote.options.TestServer"
+
+	filename, err := server.filename(request)
+
+	if err != nil {
+		return testServerError(request, err)
+	}
+
+	file, err := server.filesystem.Open(filename)
+
+	if err != nil {
+		return testServerError(request, derp.Wrap(err, location, "Unable to open fixture", filename))
+	}
+
+	// Read the response from the fs.File
+	response, err := http.ReadResponse(bufio.NewReader(file), request)
+
+	if err != nil {
+		_ = file.Close()
+		return testServerError(request, derp.Wrap(err, location, "Unable to read fixture", filename))
+	}
+
+	// Tie the file's lifetime to the response body so it is closed when
+	// the caller closes the body.
+	response.Body = fileBackedBody{ReadCloser: response.Body, file: file}
+
+	// I see this as a complete success!
+	return response
+}
+
+// filename chooses the fixture file for a request, from the first matching
+// route or else from the filename rules.
+func (server *testServer) filename(request *http.Request) (string, error) {
+
+	const location = "remote.options.TestServer.filename"
 
-			if err != nil {
-				return errorResponse(request, err)
+	for index, route := range server.routes {
+		if route.matches(request) {
+
+			if len(route.Files) == 0 {
+				return "", derp.Internal(location, "Route has no fixture files", route)
 			}
 
-			// Read the response from the fs.File
-			response, err := http.ReadResponse(bufio.NewReader(file), request)
+			call := server.next("route:" + strconv.Itoa(index))
+			return route.Files[min(call, len(route.Files)-1)], nil
+		}
+	}
+
+	// Locate the file using the URL path
+	path := strings.TrimPrefix(request.URL.Path, "/")
+	method := strings.ToUpper(request.Method)
+	candidates := []string{method + "/" + path, path}
+
+	if request.URL.RawQuery != "" {
+		query := testServerQuerySeparator + request.URL.Query().Encode()
+		candidates = []string{method + "/" + path + query, path + query, method + "/" + path, path}
+	}
+
+	for _, candidate := range candidates {
 
-			if err != nil {
-				_ = file.Close()
-				return errorResponse(request, err)
+		if server.exists(candidate) {
+			return candidate, nil
+		}
+
+		if count := server.sequenceLength(candidate); count > 0 {
+			call := server.next(candidate)
+			return candidate + "#" + strconv.Itoa(min(call, count-1)+1), nil
+		}
+	}
+
+	return "", derp.NotFound(location, "No fixture found for request", request.Method, request.URL.String())
+}
+
+// next returns how many times a fixture has already been served, and counts this call.
+func (server *testServer) next(key string) int {
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	result := server.counts[key]
+	server.counts[key]++
+	return result
+}
+
+// exists reports whether a fixture file is present.
+func (server *testServer) exists(filename string) bool {
+
+	if !fs.ValidPath(filename) {
+		return false
+	}
+
+	info, err := fs.Stat(server.filesystem, filename)
+	return (err == nil) && !info.IsDir()
+}
+
+// sequenceLength counts the numbered fixtures ("name#1", "name#2", ...) for a name.
+func (server *testServer) sequenceLength(filename string) int {
+
+	count := 0
+
+	for server.exists(filename + "#" + strconv.Itoa(count+1)) {
+		count++
+	}
+
+	return count
+}
+
+// matches reports whether a request satisfies every non-empty field of the route.
+func (route TestRoute) matches(request *http.Request) bool {
+
+	if (route.Method != "") && !strings.EqualFold(route.Method, request.Method) {
+		return false
+	}
+
+	if (route.Path != "") && (route.Path != request.URL.Path) {
+		return false
+	}
+
+	query := request.URL.Query()
+
+	for name, values := range route.Query {
+		for _, value := range values {
+			if !slices.Contains(query[name], value) {
+				return false
+			}
+		}
+	}
+
+	for name, values := range route.Header {
+		for _, value := range values {
+			if !slices.Contains(request.Header.Values(name), value) {
+				return false
 			}
+		}
+	}
 
-			// Tie the file's lifetime to the response body so it is closed when
-			// the caller closes the body.
-			response.Body = fileBackedBody{ReadCloser: response.Body, file: file}
+	if route.BodyContains != "" {
+		if !strings.Contains(string(requestBodyBytes(request)), route.BodyContains) {
+			return false
+		}
+	}
 
-			// I see this as a complete success!
-			return response
-		},
+	return true
+}
+
+// testServerError reports an error, and returns it as a 404 response.
+func testServerError(request *http.Request, err error) *http.Response {
+
+	derp.Report(err)
+	body := io.NopCloser(strings.NewReader(err.Error()))
+
+	return &http.Response{
+		Request:    request,
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{},
+		Body:       body,
 	}
 }
 
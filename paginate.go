@@ -0,0 +1,96 @@
+package remote
+
+import (
+	"iter"
+	"net/url"
+
+	"github.com/benpate/derp"
+)
+
+// NextPage finds the URL of the page after the one just received, given the
+// transaction that retrieved it and its decoded body. It returns an empty
+// string when there are no more pages.
+type NextPage[T any] func(transaction *Transaction, page T) string
+
+// NextLink is the default NextPage function, which follows the response's
+// `Link: <...>; rel="next"` header.
+func NextLink[T any](transaction *Transaction, _ T) string {
+	return transaction.ResponseLinks().Href("next")
+}
+
+// Paginate sends the transaction, and then follows `rel="next"` Link headers
+// to retrieve every following page. Each page is decoded into a new T and
+// yielded in order. Iteration stops after the last page, or after yielding
+// the first error. Each page is requested with a clone of the original
+// transaction, so its headers, options, and host policy apply to every page.
+func Paginate[T any](transaction *Transaction) iter.Seq2[T, error] {
+	return PaginateWith(transaction, NextLink[T])
+}
+
+// PaginateWith works like Paginate, but uses the provided function to find
+// each following page, such as one that builds a URL from a cursor in the
+// response body. Relative URLs are resolved against the current page.
+func PaginateWith[T any](transaction *Transaction, next NextPage[T]) iter.Seq2[T, error] {
+
+	const location = "remote.Paginate"
+
+	return func(yield func(T, error) bool) {
+
+		visited := map[string]bool{}
+		current := transaction.Clone()
+
+		for {
+			var page T
+
+			if err := current.Result(&page).Send(); err != nil {
+				yield(page, derp.Wrap(err, location, "Unable to retrieve page", current.RequestURL()))
+				return
+			}
+
+			visited[current.RequestURL()] = true
+
+			if !yield(page, nil) {
+				return
+			}
+
+			nextURL := next(current, page)
+
+			if nextURL == "" {
+				return
+			}
+
+			// Resolve relative URLs against the page just received.
+			if base := current.finalURL(); base != nil {
+				if resolved, err := base.Parse(nextURL); err == nil {
+					nextURL = resolved.String()
+				}
+			}
+
+			// Guard against servers that link back to a page already seen.
+			if visited[nextURL] {
+				yield(*new(T), derp.Internal(location, "Pagination loop detected", nextURL))
+				return
+			}
+
+			// The next URL includes its own query string.
+			current = transaction.Clone()
+			current.url = nextURL
+			current.query = url.Values{}
+		}
+	}
+}
+
+// finalURL returns the URL of the final response (after any redirects), or
+// of the request if there is no response.
+func (t *Transaction) finalURL() *url.URL {
+
+	if (t.response != nil) && (t.response.Request != nil) {
+		return t.response.Request.URL
+	}
+
+	if t.request != nil {
+		return t.request.URL
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pageServer returns a server with three pages of items, linked with Link
+// headers. Every request must include the "Authorization" header.
+func pageServer() *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+
+		if page == 0 {
+			page = 1
+		}
+
+		if page < 3 {
+			w.Header().Set("Link", `</items?page=`+strconv.Itoa(page+1)+`>; rel="next"`)
+		}
+
+		w.Header().Set(ContentType, ContentTypeJSON)
+		_, _ = w.Write([]byte(`{"items":["` + strconv.Itoa(page) + `a","` + strconv.Itoa(page) + `b"],"cursor":"` + strconv.Itoa(page+1) + `"}`))
+	}))
+}
+
+type testPage struct {
+	Items  []string `json:"items"`
+	Cursor string   `json:"cursor"`
+}
+
+func TestPaginate(t *testing.T) {
+
+	ts := pageServer()
+	defer ts.Close()
+
+	transaction := Get(ts.URL+"/items").AllowPrivateIPs(true).Header("Authorization", "secret")
+	items := []string{}
+
+	for page, err := range Paginate[testPage](transaction) {
+		require.NoError(t, err)
+		items = append(items, page.Items...)
+	}
+
+	require.Equal(t, []string{"1a", "1b", "2a", "2b", "3a", "3b"}, items)
+
+	// The original transaction is unchanged
+	require.Nil(t, transaction.Response())
+}
+
+func TestPaginate_Break(t *testing.T) {
+
+	ts := pageServer()
+	defer ts.Close()
+
+	count := 0
+
+	for range Paginate[testPage](Get(ts.URL+"/items").AllowPrivateIPs(true).Header("Authorization", "secret")) {
+		count++
+		break
+	}
+
+	require.Equal(t, 1, count)
+}
+
+func TestPaginate_Error(t *testing.T) {
+
+	ts := pageServer()
+	defer ts.Close()
+
+	failures := 0
+
+	for _, err := range Paginate[testPage](Get(ts.URL + "/items").AllowPrivateIPs(true)) {
+		require.Error(t, err)
+		failures++
+	}
+
+	require.Equal(t, 1, failures)
+}
+
+func TestPaginate_HostPolicy(t *testing.T) {
+
+	// Links to another host are refused by the original allow-list.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Link", `<https://evil.example.com/steal>; rel="next"`)
+		w.Header().Set(ContentType, ContentTypeJSON)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	transaction := Get(ts.URL).AllowPrivateIPs(true).AllowHosts("127.0.0.1")
+	results := []error{}
+
+	for _, err := range Paginate[testPage](transaction) {
+		results = append(results, err)
+	}
+
+	require.Len(t, results, 2)
+	require.NoError(t, results[0])
+	require.Error(t, results[1])
+}
+
+func TestPaginate_Loop(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Link", `</items>; rel="next"`)
+		w.Header().Set(ContentType, ContentTypeJSON)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	results := []error{}
+
+	for _, err := range Paginate[testPage](Get(ts.URL + "/items").AllowPrivateIPs(true)) {
+		results = append(results, err)
+	}
+
+	require.Len(t, results, 2)
+	require.NoError(t, results[0])
+	require.Error(t, results[1])
+}
+
+func TestPaginateWith_Cursor(t *testing.T) {
+
+	ts := pageServer()
+	defer ts.Close()
+
+	// Follow a cursor in the response body, instead of the Link header.
+	next := func(_ *Transaction, page testPage) string {
+		if page.Cursor == "3" {
+			return ""
+		}
+		return "/items?page=" + page.Cursor
+	}
+
+	items := []string{}
+
+	for page, err := range PaginateWith(Get(ts.URL+"/items").AllowPrivateIPs(true).Header("Authorization", "secret"), next) {
+		require.NoError(t, err)
+		items = append(items, page.Items...)
+	}
+
+	require.Equal(t, []string{"1a", "1b", "2a", "2b"}, items)
+}
@@ -0,0 +1,189 @@
+package remote
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultProgressInterval is the shortest time between two progress reports,
+// unless the ProgressInterval method sets another.
+const defaultProgressInterval = 100 * time.Millisecond
+
+// ProgressFunc receives the number of bytes transferred so far, and the total
+// number of bytes to transfer (-1 if unknown).
+type ProgressFunc func(transferred int64, total int64)
+
+// OnUploadProgress sets a function that reports how much of the request body
+// has been sent. It is called at most once per ProgressInterval, and always
+// once the whole body is sent. It may be called from another goroutine.
+func (t *Transaction) OnUploadProgress(callback ProgressFunc) *Transaction {
+	t.uploadProgress = callback
+	return t
+}
+
+// OnDownloadProgress sets a function that reports how much of the response
+// body has been received, whether it is read into memory by Send or streamed
+// to a file by Download. Bytes are counted as they arrive, before they are
+// decompressed, so the total is the response's Content-Length (if any). It is
+// called at most once per ProgressInterval, and always once the whole body
+// is received.
+func (t *Transaction) OnDownloadProgress(callback ProgressFunc) *Transaction {
+	t.downloadProgress = callback
+	return t
+}
+
+// ProgressInterval sets the shortest time between two progress reports. The
+// default is 100ms. A value of zero or less restores the default.
+func (t *Transaction) ProgressInterval(interval time.Duration) *Transaction {
+	t.progressInterval = interval
+	return t
+}
+
+// newProgressReporter returns a reporter for the callback, throttled to the
+// transaction's ProgressInterval. It returns nil if the callback is nil.
+func (t *Transaction) newProgressReporter(callback ProgressFunc) *progressReporter {
+
+	if callback == nil {
+		return nil
+	}
+
+	interval := t.progressInterval
+
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+
+	return &progressReporter{
+		callback: callback,
+		interval: interval,
+	}
+}
+
+// watchUploadProgress wraps the request body so that it reports upload progress.
+func (t *Transaction) watchUploadProgress(request *http.Request) {
+
+	reporter := t.newProgressReporter(t.uploadProgress)
+
+	if (reporter == nil) || (request.Body == nil) || (request.Body == http.NoBody) {
+		return
+	}
+
+	request.Body = newProgressReader(request.Body, reporter, request.ContentLength)
+
+	// Redirects that resend the body start counting again
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+
+			body, err := getBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			return newProgressReader(body, reporter, request.ContentLength), nil
+		}
+	}
+}
+
+// watchDownloadProgress wraps the response body so that it reports download progress.
+func (t *Transaction) watchDownloadProgress(response *http.Response) {
+
+	reporter := t.newProgressReporter(t.downloadProgress)
+
+	if (reporter == nil) || (response.Body == nil) || (response.Body == http.NoBody) {
+		return
+	}
+
+	response.Body = newProgressReader(response.Body, reporter, response.ContentLength)
+}
+
+/******************************************
+ * Progress Reporter
+ ******************************************/
+
+// progressReporter calls a progress callback at most once per interval, and
+// always for the final report. It is safe to use from several goroutines.
+type progressReporter struct {
+	callback ProgressFunc
+	interval time.Duration
+	mutex    sync.Mutex
+	last     time.Time // time of the last report
+	done     bool      // TRUE once the final report has been made
+}
+
+// report calls the callback, unless it was called less than one interval ago.
+// The final report is always made, and only once.
+func (reporter *progressReporter) report(transferred int64, total int64, final bool) {
+
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if reporter.done {
+		return
+	}
+
+	now := time.Now()
+
+	if !final && (now.Sub(reporter.last) < reporter.interval) {
+		return
+	}
+
+	reporter.last = now
+	reporter.done = final
+	reporter.callback(transferred, total)
+}
+
+// restart allows a reporter to report again, after a body is sent again.
+func (reporter *progressReporter) restart() {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.done = false
+}
+
+// progressReader reports the number of bytes read through it.
+type progressReader struct {
+	io.ReadCloser
+	reporter    *progressReporter
+	transferred int64
+	total       int64
+}
+
+func newProgressReader(body io.ReadCloser, reporter *progressReporter, total int64) *progressReader {
+
+	if total < 0 {
+		total = -1
+	}
+
+	reporter.restart()
+
+	return &progressReader{
+		ReadCloser: body,
+		reporter:   reporter,
+		total:      total,
+	}
+}
+
+// Read reads from the underlying body, and reports progress. The final
+// report is made at the end of the body, or once the total has been read.
+func (reader *progressReader) Read(p []byte) (int, error) {
+
+	count, err := reader.ReadCloser.Read(p)
+	reader.transferred += int64(count)
+
+	final := (err == io.EOF) || ((reader.total >= 0) && (reader.transferred >= reader.total))
+
+	// Once the body is complete, its total is known
+	total := reader.total
+
+	if final && (total < 0) {
+		total = reader.transferred
+	}
+
+	if (count > 0) || final {
+		reader.reporter.report(reader.transferred, total, final)
+	}
+
+	return count, err
+}
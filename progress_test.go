@@ -0,0 +1,278 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// progressRecorder collects progress reports.
+type progressRecorder struct {
+	mutex   sync.Mutex
+	reports [][2]int64
+}
+
+func (recorder *progressRecorder) record(transferred int64, total int64) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.reports = append(recorder.reports, [2]int64{transferred, total})
+}
+
+// last returns the most recent report.
+func (recorder *progressRecorder) last() [2]int64 {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return recorder.reports[len(recorder.reports)-1]
+}
+
+// requireIncreasing confirms that the reports never go backwards.
+func (recorder *progressRecorder) requireIncreasing(t *testing.T) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	for index := 1; index < len(recorder.reports); index++ {
+		require.GreaterOrEqual(t, recorder.reports[index][0], recorder.reports[index-1][0])
+	}
+}
+
+func TestProgress_Upload(t *testing.T) {
+
+	body := strings.Repeat("upload ", 100_000)
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		received, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, body, string(received))
+	})
+
+	// Buffered body
+	recorder := &progressRecorder{}
+	require.NoError(t, Post("https://example.com/upload").Body(body).OnUploadProgress(recorder.record).ProgressInterval(time.Nanosecond).With(option).Send())
+	require.Greater(t, len(recorder.reports), 1)
+	require.Equal(t, [2]int64{int64(len(body)), int64(len(body))}, recorder.last())
+	recorder.requireIncreasing(t)
+
+	// Streamed body
+	recorder = &progressRecorder{}
+	require.NoError(t, Post("https://example.com/upload").JSON(strings.NewReader(body)).OnUploadProgress(recorder.record).With(option).Send())
+	require.Equal(t, [2]int64{int64(len(body)), int64(len(body))}, recorder.last())
+
+	// Requests without a body report nothing
+	recorder = &progressRecorder{}
+	require.NoError(t, Get("https://example.com/upload").OnUploadProgress(recorder.record).With(handlerOption(func(http.ResponseWriter, *http.Request) {})).Send())
+	require.Empty(t, recorder.reports)
+}
+
+func TestProgress_Download(t *testing.T) {
+
+	document := strings.Repeat("download ", 100_000)
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentType, ContentTypePlain)
+		w.Header().Set("Content-Length", "900000")
+		_, _ = w.Write([]byte(document))
+	})
+
+	recorder := &progressRecorder{}
+	body := ""
+
+	require.NoError(t, Get("https://example.com/file.txt").Result(&body).OnDownloadProgress(recorder.record).ProgressInterval(time.Nanosecond).With(option).Send())
+	require.Equal(t, document, body)
+	require.Equal(t, [2]int64{900_000, 900_000}, recorder.last())
+	recorder.requireIncreasing(t)
+}
+
+func TestProgress_DownloadUnknownLength(t *testing.T) {
+
+	option := Option{
+		ModifyRequest: func(_ *Transaction, request *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        http.Header{ContentType: {ContentTypePlain}},
+				Body:          io.NopCloser(strings.NewReader("Hello, World!")),
+				ContentLength: -1,
+				Request:       request,
+			}
+		},
+	}
+
+	recorder := &progressRecorder{}
+	require.NoError(t, Get("https://example.com/").OnDownloadProgress(recorder.record).With(option).Send())
+
+	// The total is unknown until the body is complete
+	require.Equal(t, [2]int64{13, 13}, recorder.last())
+
+	for _, report := range recorder.reports[:len(recorder.reports)-1] {
+		require.Equal(t, int64(-1), report[1])
+	}
+}
+
+func TestProgress_DownloadCompressed(t *testing.T) {
+
+	document := strings.Repeat("compressible ", 10_000)
+	compressed := compressForTest(t, EncodingGzip, []byte(document))
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", EncodingGzip)
+		w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+		_, _ = w.Write(compressed)
+	})
+
+	// Progress counts the bytes that arrive, before they are decompressed
+	recorder := &progressRecorder{}
+	body := ""
+	require.NoError(t, Get("https://example.com/").Result(&body).OnDownloadProgress(recorder.record).With(option).Send())
+	require.Equal(t, document, body)
+	require.Equal(t, [2]int64{int64(len(compressed)), int64(len(compressed))}, recorder.last())
+}
+
+func TestProgress_Throttled(t *testing.T) {
+
+	document := strings.Repeat("x", 1<<20)
+
+	// Servers send the body in small pieces
+	option := Option{
+		ModifyRequest: func(_ *Transaction, request *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Body:          io.NopCloser(&smallReader{reader: strings.NewReader(document)}),
+				ContentLength: int64(len(document)),
+				Request:       request,
+			}
+		},
+	}
+
+	// Only the first and final reports are made within the interval
+	recorder := &progressRecorder{}
+	require.NoError(t, Get("https://example.com/").OnDownloadProgress(recorder.record).ProgressInterval(time.Hour).With(option).Send())
+	require.Len(t, recorder.reports, 2)
+	require.LessOrEqual(t, recorder.reports[0][0], int64(1024))
+	require.Equal(t, [2]int64{1 << 20, 1 << 20}, recorder.reports[1])
+}
+
+// smallReader reads at most 1KB at a time.
+type smallReader struct {
+	reader io.Reader
+}
+
+func (reader *smallReader) Read(p []byte) (int, error) {
+	return reader.reader.Read(p[:min(len(p), 1024)])
+}
+
+func TestProgress_Network(t *testing.T) {
+
+	upload := bytes.Repeat([]byte("u"), 4<<20)
+	download := bytes.Repeat([]byte("d"), 4<<20)
+
+	// The handler runs on another goroutine, so it only records what it received
+	var received atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count, _ := io.Copy(io.Discard, r.Body)
+		received.Store(count)
+		w.Header().Set(ContentType, "application/octet-stream")
+		_, _ = w.Write(download)
+	}))
+	defer server.Close()
+
+	uploads := &progressRecorder{}
+	downloads := &progressRecorder{}
+	body := []byte{}
+
+	transaction := Post(server.URL).
+		AllowPrivateIPs(true).
+		ContentType("application/octet-stream").
+		JSON(bytes.NewReader(upload)).
+		Compression(Compression{Disabled: true}).
+		OnUploadProgress(uploads.record).
+		OnDownloadProgress(downloads.record).
+		ProgressInterval(time.Millisecond).
+		Result(&body)
+
+	require.NoError(t, transaction.Send())
+	require.Equal(t, int64(len(upload)), received.Load())
+	require.Equal(t, download, body)
+
+	require.Equal(t, [2]int64{4 << 20, 4 << 20}, uploads.last())
+	require.Equal(t, [2]int64{4 << 20, 4 << 20}, downloads.last())
+	uploads.requireIncreasing(t)
+	downloads.requireIncreasing(t)
+}
+
+func TestProgress_DownloadFile(t *testing.T) {
+
+	ranges := []string{}
+	dst := filepath.Join(t.TempDir(), "file.bin")
+
+	// Resuming reports the bytes in the response, while the download policy reports the whole file
+	require.NoError(t, os.WriteFile(dst+downloadPartSuffix, downloadContent[:1000], 0o644))
+	require.NoError(t, os.WriteFile(dst+downloadETagSuffix, []byte(`"v1"`), 0o644))
+
+	responseProgress := &progressRecorder{}
+	fileProgress := &progressRecorder{}
+
+	transaction := Get("https://example.com/file.bin").
+		With(downloadOption(`"v1"`, &ranges)).
+		OnDownloadProgress(responseProgress.record).
+		Downloads(DownloadPolicy{Progress: fileProgress.record})
+
+	require.NoError(t, transaction.Download(context.Background(), dst))
+
+	remaining := int64(len(downloadContent) - 1000)
+	require.Equal(t, [2]int64{remaining, remaining}, responseProgress.last())
+	require.Equal(t, [2]int64{int64(len(downloadContent)), int64(len(downloadContent))}, fileProgress.last())
+}
+
+func TestProgress_ParallelDownload(t *testing.T) {
+
+	requests := []string{}
+	dst := filepath.Join(t.TempDir(), "export.bin")
+	recorder := &progressRecorder{}
+
+	transaction := Get("https://example.com/export.bin").
+		With(parallelOption(`"v1"`, &requests)).
+		OnDownloadProgress(recorder.record).
+		Downloads(DownloadPolicy{Parallel: 3})
+
+	// Every range counts toward the whole file
+	require.NoError(t, transaction.Download(context.Background(), dst))
+	require.Equal(t, [2]int64{int64(len(parallelContent)), int64(len(parallelContent))}, recorder.last())
+	recorder.requireIncreasing(t)
+}
+
+func TestProgress_Clone(t *testing.T) {
+
+	recorder := &progressRecorder{}
+	original := Get("https://example.com/").OnUploadProgress(recorder.record).OnDownloadProgress(recorder.record).ProgressInterval(time.Second)
+	clone := original.Clone()
+
+	require.NotNil(t, clone.uploadProgress)
+	require.NotNil(t, clone.downloadProgress)
+	require.Equal(t, time.Second, clone.progressInterval)
+}
+
+func TestProgressReporter(t *testing.T) {
+
+	recorder := &progressRecorder{}
+	reporter := (&Transaction{progressInterval: time.Hour}).newProgressReporter(recorder.record)
+
+	reporter.report(1, 10, false)
+	reporter.report(2, 10, false)
+	reporter.report(10, 10, true)
+	reporter.report(10, 10, true)
+
+	require.Equal(t, [][2]int64{{1, 10}, {10, 10}}, recorder.reports)
+	require.Nil(t, (&Transaction{}).newProgressReporter(nil))
+}
@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+// RedirectPolicy controls how a request follows HTTP redirects. The zero value
+// is the default policy: follow up to maxRedirects redirects to any host,
+// allowing scheme downgrades and stripping only the headers Go itself strips.
+type RedirectPolicy struct {
+	MaxHops          int      // maximum number of redirects to follow, not counting the first request (zero or less uses the default of 5; use NoFollow to follow none)
+	NoFollow         bool     // if TRUE, redirects are not followed, and the 3xx response is returned as-is
+	SameHostOnly     bool     // if TRUE, refuse to follow a redirect to a different host
+	ForbidDowngrade  bool     // if TRUE, refuse to follow a redirect from https to http
+	SensitiveHeaders []string // headers to remove when a redirect crosses to a different origin
+}
+
+// RedirectHop is a single step in a redirect chain: the URL that was requested,
+// and the status code that the server returned for it.
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+}
+
+// CheckRedirect implements the http.Client CheckRedirect signature, so a policy
+// can be used directly by any *http.Client (such as one from NewHTTPClient).
+// The private-IP guard is not part of the policy; it re-runs on every hop
+// because it lives in the dialer.
+func (policy RedirectPolicy) CheckRedirect(request *http.Request, via []*http.Request) error {
+
+	const location = "remote.RedirectPolicy.CheckRedirect"
+
+	// NoFollow returns the redirect response to the caller instead of following it.
+	if policy.NoFollow {
+		return http.ErrUseLastResponse
+	}
+
+	// via holds every request made so far, so its length is the number of this redirect.
+	if len(via) > policy.maxHops() {
+		return derp.BadRequest(location, "Too many redirects")
+	}
+
+	// Nothing else to check without previous requests to compare against.
+	if (len(via) == 0) || (request.URL == nil) {
+		return nil
+	}
+
+	original, previous := via[0], via[len(via)-1]
+
+	if (original == nil) || (original.URL == nil) || (previous == nil) || (previous.URL == nil) {
+		return nil
+	}
+
+	if policy.SameHostOnly && !strings.EqualFold(request.URL.Hostname(), original.URL.Hostname()) {
+		return derp.Forbidden(location, "Redirect to a different host is not allowed", request.URL.Hostname())
+	}
+
+	if policy.ForbidDowngrade && (previous.URL.Scheme == "https") && (request.URL.Scheme != "https") {
+		return derp.Forbidden(location, "Redirect from https to http is not allowed", request.URL.String())
+	}
+
+	// Go copies the ORIGINAL request's headers onto every hop, so the origin
+	// comparison is made against the first request, not the previous one.
+	if !sameOrigin(request.URL, original.URL) {
+		for _, header := range policy.SensitiveHeaders {
+			request.Header.Del(header)
+		}
+	}
+
+	return nil
+}
+
+// maxHops returns the policy's cap on the redirect chain, falling back to the default.
+func (policy RedirectPolicy) maxHops() int {
+
+	if policy.MaxHops <= 0 {
+		return maxRedirects
+	}
+
+	return policy.MaxHops
+}
+
+// sameOrigin reports whether two URLs share a scheme, host, and port.
+func sameOrigin(a *url.URL, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host)
+}
+
+// RedirectChain reconstructs the redirects that led to a response, using the
+// links that net/http keeps between each request and the redirect response that
+// caused it. Every requested URL is included in order, and the last hop is the
+// final response itself. It works for any *http.Response, including those from
+// a client made by NewHTTPClient.
+func RedirectChain(response *http.Response) []RedirectHop {
+
+	result := make([]RedirectHop, 0, 1)
+
+	for response != nil && response.Request != nil {
+		result = append(result, RedirectHop{
+			URL:        response.Request.URL.String(),
+			StatusCode: response.StatusCode,
+		})
+		response = response.Request.Response
+	}
+
+	// Hops were collected from last to first.
+	slices.Reverse(result)
+	return result
+}
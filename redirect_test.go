@@ -0,0 +1,193 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// redirectServer returns a server that redirects /hop/N to /hop/N-1, and
+// answers /hop/0 with a 200. The headers received on each path are recorded.
+func redirectServer(t *testing.T, received map[string]http.Header) *httptest.Server {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if received != nil {
+			received[r.URL.Path] = r.Header.Clone()
+		}
+
+		hops, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+
+		if hops > 0 {
+			http.Redirect(w, r, "/hop/"+strconv.Itoa(hops-1), http.StatusFound)
+			return
+		}
+
+		_, _ = w.Write([]byte("done"))
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRedirectChain_RecordsEveryHop(t *testing.T) {
+
+	server := redirectServer(t, nil)
+
+	txn := Get(server.URL + "/hop/2").AllowPrivateIPs(true)
+	require.NoError(t, txn.Send())
+
+	require.Equal(t, []RedirectHop{
+		{URL: server.URL + "/hop/2", StatusCode: http.StatusFound},
+		{URL: server.URL + "/hop/1", StatusCode: http.StatusFound},
+		{URL: server.URL + "/hop/0", StatusCode: http.StatusOK},
+	}, txn.RedirectChain())
+}
+
+func TestRedirectChain_NoRedirect(t *testing.T) {
+
+	server := redirectServer(t, nil)
+
+	txn := Get(server.URL + "/hop/0").AllowPrivateIPs(true)
+	require.NoError(t, txn.Send())
+
+	require.Equal(t, []RedirectHop{{URL: server.URL + "/hop/0", StatusCode: http.StatusOK}}, txn.RedirectChain())
+}
+
+func TestRedirectChain_BeforeSend(t *testing.T) {
+	require.Empty(t, Get("http://example.com").RedirectChain())
+	require.Empty(t, RedirectChain(nil))
+}
+
+func TestRedirectChain_NewHTTPClient(t *testing.T) {
+
+	server := redirectServer(t, nil)
+
+	response, err := NewHTTPClient(true).Get(server.URL + "/hop/1")
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+
+	chain := RedirectChain(response)
+	require.Len(t, chain, 2)
+	require.Equal(t, server.URL+"/hop/0", chain[1].URL)
+}
+
+func TestRedirects_MaxHops(t *testing.T) {
+
+	server := redirectServer(t, nil)
+
+	// Three redirects exceed a cap of two hops...
+	err := Get(server.URL + "/hop/3").AllowPrivateIPs(true).Redirects(RedirectPolicy{MaxHops: 2}).Send()
+	require.Error(t, err)
+
+	// ...but two redirects are fine.
+	err = Get(server.URL + "/hop/2").AllowPrivateIPs(true).Redirects(RedirectPolicy{MaxHops: 2}).Send()
+	require.NoError(t, err)
+}
+
+func TestRedirects_MaxHopsOne(t *testing.T) {
+
+	server := redirectServer(t, nil)
+
+	// A single redirect is followed...
+	txn := Get(server.URL + "/hop/1").AllowPrivateIPs(true).Redirects(RedirectPolicy{MaxHops: 1})
+	require.NoError(t, txn.Send())
+	require.Len(t, txn.RedirectChain(), 2)
+
+	// ...but not a second one
+	err := Get(server.URL + "/hop/2").AllowPrivateIPs(true).Redirects(RedirectPolicy{MaxHops: 1}).Send()
+	require.Error(t, err)
+}
+
+func TestRedirects_DefaultMaxHops(t *testing.T) {
+
+	server := redirectServer(t, nil)
+
+	require.NoError(t, Get(server.URL+"/hop/"+strconv.Itoa(maxRedirects)).AllowPrivateIPs(true).Send())
+	require.Error(t, Get(server.URL+"/hop/"+strconv.Itoa(maxRedirects+1)).AllowPrivateIPs(true).Send())
+}
+
+func TestRedirects_NoFollow(t *testing.T) {
+
+	server := redirectServer(t, nil)
+
+	txn := Get(server.URL + "/hop/1").AllowPrivateIPs(true).Redirects(RedirectPolicy{NoFollow: true})
+
+	// The 3xx response is returned as-is, which Send reports as a non-2xx error.
+	require.Error(t, txn.Send())
+	require.Equal(t, http.StatusFound, txn.ResponseStatusCode())
+	require.Equal(t, "/hop/0", txn.ResponseHeader().Get("Location"))
+	require.Len(t, txn.RedirectChain(), 1)
+}
+
+func TestRedirectPolicy_SameHostOnly(t *testing.T) {
+
+	policy := RedirectPolicy{SameHostOnly: true}
+
+	original := redirectRequest(t, "https://example.com/a")
+
+	require.NoError(t, policy.CheckRedirect(redirectRequest(t, "https://EXAMPLE.com/b"), []*http.Request{original}))
+	require.Error(t, policy.CheckRedirect(redirectRequest(t, "https://other.com/b"), []*http.Request{original}))
+}
+
+func TestRedirectPolicy_ForbidDowngrade(t *testing.T) {
+
+	policy := RedirectPolicy{ForbidDowngrade: true}
+
+	secure := redirectRequest(t, "https://example.com/a")
+	insecure := redirectRequest(t, "http://example.com/a")
+
+	require.Error(t, policy.CheckRedirect(redirectRequest(t, "http://example.com/b"), []*http.Request{secure}))
+	require.NoError(t, policy.CheckRedirect(redirectRequest(t, "https://example.com/b"), []*http.Request{secure}))
+	require.NoError(t, policy.CheckRedirect(redirectRequest(t, "http://example.com/b"), []*http.Request{insecure}))
+
+	// Downgrades are allowed by default.
+	require.NoError(t, RedirectPolicy{}.CheckRedirect(redirectRequest(t, "http://example.com/b"), []*http.Request{secure}))
+}
+
+func TestRedirectPolicy_SensitiveHeaders(t *testing.T) {
+
+	policy := RedirectPolicy{SensitiveHeaders: []string{"Signature", "X-Api-Key"}}
+	original := redirectRequest(t, "https://example.com/a")
+
+	// Same-origin hops keep the headers.
+	sameOrigin := redirectRequest(t, "https://example.com/b")
+	sameOrigin.Header.Set("Signature", "abc")
+	require.NoError(t, policy.CheckRedirect(sameOrigin, []*http.Request{original}))
+	require.Equal(t, "abc", sameOrigin.Header.Get("Signature"))
+
+	// Cross-origin hops (including a change of scheme or port) strip them.
+	for _, target := range []string{"https://other.com/b", "http://example.com/b", "https://example.com:8443/b"} {
+		request := redirectRequest(t, target)
+		request.Header.Set("Signature", "abc")
+		request.Header.Set("X-Api-Key", "secret")
+		request.Header.Set("Accept", "text/plain")
+
+		require.NoError(t, policy.CheckRedirect(request, []*http.Request{original}))
+		require.Empty(t, request.Header.Get("Signature"), "target=%s", target)
+		require.Empty(t, request.Header.Get("X-Api-Key"), "target=%s", target)
+		require.Equal(t, "text/plain", request.Header.Get("Accept"), "target=%s", target)
+	}
+}
+
+func TestRedirects_AllowHostsStillApplies(t *testing.T) {
+
+	// A permissive policy does not bypass the host allow-list.
+	txn := Get("https://example.com").AllowHosts("example.com").Redirects(RedirectPolicy{MaxHops: 10})
+	original := redirectRequest(t, "https://example.com/a")
+
+	require.Error(t, txn.checkRedirect(redirectRequest(t, "https://other.com/b"), []*http.Request{original}))
+	require.NoError(t, txn.checkRedirect(redirectRequest(t, "https://example.com/b"), []*http.Request{original}))
+}
+
+// redirectRequest builds a bare request for exercising CheckRedirect directly.
+func redirectRequest(t *testing.T, value string) *http.Request {
+	parsed, err := url.Parse(value)
+	require.NoError(t, err)
+	return &http.Request{URL: parsed, Header: http.Header{}}
+}
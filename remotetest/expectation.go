@@ -0,0 +1,303 @@
+package remotetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benpate/remote"
+)
+
+// Expectation describes a call that the code under test must make, and the
+// response it receives. Build one with Server.Expect and its chainable methods.
+type Expectation struct {
+	tb       testing.TB // (if set) receives errors made while building the expectation
+	method   string
+	target   string // path (e.g. "/inbox") or full URL (e.g. "https://example.com/inbox")
+	header   http.Header
+	query    url.Values
+	body     *string
+	jsonBody any
+	times    int // number of calls required (-1 means any number, including none)
+	calls    int
+
+	statusCode     int
+	responseHeader http.Header
+	responseBody   []byte
+}
+
+// newExpectation returns an Expectation for any request, once, answered with a 200 OK.
+func newExpectation() *Expectation {
+	return &Expectation{
+		header:         http.Header{},
+		query:          url.Values{},
+		times:          1,
+		statusCode:     http.StatusOK,
+		responseHeader: http.Header{},
+	}
+}
+
+/******************************************
+ * Request matching
+ ******************************************/
+
+// Method expects a request with the given method and target. The target is
+// either a path (such as "/inbox"), which matches any host, or a full URL.
+func (e *Expectation) Method(method string, target string) *Expectation {
+	e.method = strings.ToUpper(method)
+	e.target = target
+	return e
+}
+
+// Get expects a GET request to the given path or URL.
+func (e *Expectation) Get(target string) *Expectation {
+	return e.Method(http.MethodGet, target)
+}
+
+// Post expects a POST request to the given path or URL.
+func (e *Expectation) Post(target string) *Expectation {
+	return e.Method(http.MethodPost, target)
+}
+
+// Put expects a PUT request to the given path or URL.
+func (e *Expectation) Put(target string) *Expectation {
+	return e.Method(http.MethodPut, target)
+}
+
+// Patch expects a PATCH request to the given path or URL.
+func (e *Expectation) Patch(target string) *Expectation {
+	return e.Method(http.MethodPatch, target)
+}
+
+// Delete expects a DELETE request to the given path or URL.
+func (e *Expectation) Delete(target string) *Expectation {
+	return e.Method(http.MethodDelete, target)
+}
+
+// WithHeader expects the request to include this header value.
+func (e *Expectation) WithHeader(name string, value string) *Expectation {
+	e.header.Add(name, value)
+	return e
+}
+
+// WithQuery expects the request's query string to include this value.
+func (e *Expectation) WithQuery(name string, value string) *Expectation {
+	e.query.Add(name, value)
+	return e
+}
+
+// WithBody expects the request body to be exactly this text.
+func (e *Expectation) WithBody(body string) *Expectation {
+	e.body = &body
+	return e
+}
+
+// WithJSONBody expects the request body to be JSON that is equivalent to
+// value, ignoring formatting and the order of object keys.
+func (e *Expectation) WithJSONBody(value any) *Expectation {
+	e.jsonBody = value
+	return e
+}
+
+// Times expects the request to be made exactly this many times.
+func (e *Expectation) Times(times int) *Expectation {
+	e.times = max(times, 0)
+	return e
+}
+
+// AnyTimes allows the request to be made any number of times, including none.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = -1
+	return e
+}
+
+/******************************************
+ * Response
+ ******************************************/
+
+// Respond sets the status code of the response.
+func (e *Expectation) Respond(statusCode int) *Expectation {
+	e.statusCode = statusCode
+	return e
+}
+
+// RespondHeader adds a header to the response.
+func (e *Expectation) RespondHeader(name string, value string) *Expectation {
+	e.responseHeader.Add(name, value)
+	return e
+}
+
+// RespondBody sets the status code, content type, and body of the response.
+func (e *Expectation) RespondBody(statusCode int, contentType string, body string) *Expectation {
+	e.statusCode = statusCode
+	e.responseHeader.Set(remote.ContentType, contentType)
+	e.responseBody = []byte(body)
+	return e
+}
+
+// RespondJSON sets the status code of the response, and encodes value as its
+// JSON body. If value cannot be encoded, the error is reported to the test,
+// and the request is answered with a 500 Internal Server Error instead.
+func (e *Expectation) RespondJSON(statusCode int, value any) *Expectation {
+
+	body, err := json.Marshal(value)
+
+	if err != nil {
+
+		if e.tb != nil {
+			e.tb.Helper()
+			e.tb.Errorf("remotetest: unable to encode JSON response for %s: %v", e, err)
+		}
+
+		return e.RespondBody(http.StatusInternalServerError, "text/plain", "remotetest: unable to encode JSON response: "+err.Error())
+	}
+
+	return e.RespondBody(statusCode, remote.ContentTypeJSON, string(body))
+}
+
+/******************************************
+ * Internals
+ ******************************************/
+
+// String describes the expected request, for error messages.
+func (e *Expectation) String() string {
+
+	result := e.method
+
+	if result == "" {
+		result = "ANY"
+	}
+
+	if e.target == "" {
+		return result + " *"
+	}
+
+	return result + " " + e.target
+}
+
+// available reports whether the expectation can accept another call.
+func (e *Expectation) available() bool {
+	return (e.times < 0) || (e.calls < e.times)
+}
+
+// unmet reports whether the expectation is still waiting for calls.
+func (e *Expectation) unmet() bool {
+	return (e.times >= 0) && (e.calls != e.times)
+}
+
+// matches reports whether a received request satisfies the expectation.
+func (e *Expectation) matches(request Request) bool {
+
+	if (e.method != "") && (e.method != request.Method) {
+		return false
+	}
+
+	parsed, err := url.Parse(request.URL)
+
+	if err != nil {
+		return false
+	}
+
+	if !e.matchesTarget(parsed) {
+		return false
+	}
+
+	query := parsed.Query()
+
+	for name, values := range e.query {
+		for _, value := range values {
+			if !slices.Contains(query[name], value) {
+				return false
+			}
+		}
+	}
+
+	for name, values := range e.header {
+		for _, value := range values {
+			if !slices.Contains(request.Header.Values(name), value) {
+				return false
+			}
+		}
+	}
+
+	if (e.body != nil) && (*e.body != string(request.Body)) {
+		return false
+	}
+
+	if (e.jsonBody != nil) && !jsonEqual(e.jsonBody, request.Body) {
+		return false
+	}
+
+	return true
+}
+
+// matchesTarget reports whether a request URL matches the expected path or URL.
+// The query string is matched separately, via WithQuery.
+func (e *Expectation) matchesTarget(requestURL *url.URL) bool {
+
+	if e.target == "" {
+		return true
+	}
+
+	expected, err := url.Parse(e.target)
+
+	if err != nil {
+		return false
+	}
+
+	if (expected.Host != "") && (!strings.EqualFold(expected.Scheme, requestURL.Scheme) || !strings.EqualFold(expected.Host, requestURL.Host)) {
+		return false
+	}
+
+	return expected.Path == requestURL.Path
+}
+
+// response builds the http.Response for a matched request.
+func (e *Expectation) response(request *http.Request) *http.Response {
+
+	header := e.responseHeader.Clone()
+	header.Set("Content-Length", strconv.Itoa(len(e.responseBody)))
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.statusCode) + " " + http.StatusText(e.statusCode),
+		StatusCode:    e.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.responseBody)),
+		ContentLength: int64(len(e.responseBody)),
+		Request:       request,
+	}
+}
+
+// jsonEqual reports whether body is JSON that is equivalent to expected.
+func jsonEqual(expected any, body []byte) bool {
+
+	// Normalize the expected value by round-tripping it through JSON.
+	expectedJSON, err := json.Marshal(expected)
+
+	if err != nil {
+		return false
+	}
+
+	var want any
+	var got any
+
+	if err := json.Unmarshal(expectedJSON, &want); err != nil {
+		return false
+	}
+
+	if err := json.Unmarshal(body, &got); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(want, got)
+}
@@ -0,0 +1,179 @@
+// Package remotetest provides a fake remote server for testing code that uses
+// remote.Transaction. It records every outgoing request, checks them against
+// declared expectations, and reports unmet or unexpected calls via testing.TB.
+package remotetest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/benpate/remote"
+)
+
+// Server is a fake remote server. Add it to the transactions under test with
+// Option (or WithRoundTripper and RoundTripper), declare the calls you expect
+// with Expect, and every call is answered from those expectations instead of
+// the network. Unmet expectations are reported when the test finishes.
+type Server struct {
+	tb           testing.TB
+	mutex        sync.Mutex
+	expectations []*Expectation
+	requests     []Request
+}
+
+// Request is a copy of an outgoing request that was received by a Server.
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// New returns a fake remote server that reports to tb. Its expectations are
+// checked automatically when the test finishes.
+func New(tb testing.TB) *Server {
+
+	tb.Helper()
+
+	server := &Server{
+		tb:           tb,
+		expectations: make([]*Expectation, 0),
+		requests:     make([]Request, 0),
+	}
+
+	tb.Cleanup(server.AssertExpectations)
+
+	return server
+}
+
+// Expect declares a call that the code under test must make. By default the
+// call is expected exactly once, and answered with a 200 OK.
+func (server *Server) Expect() *Expectation {
+
+	expectation := newExpectation()
+	expectation.tb = server.tb
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.expectations = append(server.expectations, expectation)
+	return expectation
+}
+
+// Option returns a remote.Option that answers every request from this server,
+// so the network is never contacted.
+func (server *Server) Option() remote.Option {
+
+	return remote.Option{
+
+		// This is executed on every Request before its sent to the server
+		ModifyRequest: func(_ *remote.Transaction, request *http.Request) *http.Response {
+			return server.serve(request)
+		},
+	}
+}
+
+// RoundTripper returns middleware for Transaction.WithRoundTripper that answers
+// every request from this server, instead of delegating to the real transport.
+func (server *Server) RoundTripper() func(http.RoundTripper) http.RoundTripper {
+
+	return func(http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			return server.serve(request), nil
+		})
+	}
+}
+
+// Requests returns a copy of every request received so far, in order.
+func (server *Server) Requests() []Request {
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return slices.Clone(server.requests)
+}
+
+// AssertExpectations reports every expectation that has not been called as
+// many times as required. It runs automatically when the test finishes.
+func (server *Server) AssertExpectations() {
+
+	server.tb.Helper()
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for _, expectation := range server.expectations {
+		if expectation.unmet() {
+			server.tb.Errorf("remotetest: expected %s to be called %d time(s), but it was called %d time(s)", expectation, expectation.times, expectation.calls)
+		}
+	}
+}
+
+// serve records a request, and answers it from the first matching expectation.
+func (server *Server) serve(request *http.Request) *http.Response {
+
+	server.tb.Helper()
+
+	received := copyRequest(request)
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requests = append(server.requests, received)
+
+	for _, expectation := range server.expectations {
+		if expectation.available() && expectation.matches(received) {
+			expectation.calls++
+			return expectation.response(request)
+		}
+	}
+
+	server.tb.Errorf("remotetest: unexpected request %s %s", received.Method, received.URL)
+
+	return &http.Response{
+		Request:    request,
+		StatusCode: http.StatusNotImplemented,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("remotetest: unexpected request")),
+	}
+}
+
+// copyRequest copies a request's method, URL, headers, and body, without
+// consuming the body of the original request.
+func copyRequest(request *http.Request) Request {
+
+	result := Request{
+		Method: request.Method,
+		URL:    request.URL.String(),
+		Header: request.Header.Clone(),
+	}
+
+	switch {
+
+	case request.GetBody != nil:
+		if body, err := request.GetBody(); err == nil {
+			result.Body, _ = io.ReadAll(body)
+			_ = body.Close()
+		}
+
+	case request.Body != nil:
+		result.Body, _ = io.ReadAll(request.Body)
+		_ = request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(result.Body))
+	}
+
+	return result
+}
+
+// roundTripperFunc adapts a function to the http.RoundTripper interface.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
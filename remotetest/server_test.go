@@ -0,0 +1,180 @@
+package remotetest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/benpate/remote"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTB records failures and cleanup functions, so that tests can verify
+// what a Server reports without failing themselves.
+type fakeTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+// finish runs the cleanup functions, as the testing package does when a test ends.
+func (tb *fakeTB) finish() {
+	for index := len(tb.cleanups) - 1; index >= 0; index-- {
+		tb.cleanups[index]()
+	}
+}
+
+func TestServer_Option(t *testing.T) {
+
+	server := New(t)
+
+	server.Expect().
+		Post("/inbox").
+		WithHeader("Content-Type", "application/json").
+		WithJSONBody(map[string]any{"type": "Follow", "actor": "https://example.com/alice"}).
+		Respond(http.StatusAccepted)
+
+	transaction := remote.Post("https://example.com/inbox").
+		JSON(map[string]string{"actor": "https://example.com/alice", "type": "Follow"}).
+		With(server.Option())
+
+	require.NoError(t, transaction.Send())
+	require.Equal(t, http.StatusAccepted, transaction.ResponseStatusCode())
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, http.MethodPost, requests[0].Method)
+	require.Equal(t, "https://example.com/inbox", requests[0].URL)
+	require.JSONEq(t, `{"type":"Follow","actor":"https://example.com/alice"}`, string(requests[0].Body))
+}
+
+func TestServer_RoundTripper(t *testing.T) {
+
+	server := New(t)
+
+	server.Expect().
+		Get("https://example.com/users/1").
+		WithQuery("fields", "name").
+		RespondJSON(http.StatusOK, map[string]string{"name": "Alice"})
+
+	result := map[string]string{}
+
+	transaction := remote.Get("https://example.com/users/1").
+		Query("fields", "name").
+		WithRoundTripper(server.RoundTripper()).
+		Result(&result)
+
+	require.NoError(t, transaction.Send())
+	require.Equal(t, "Alice", result["name"])
+}
+
+func TestServer_Sequence(t *testing.T) {
+
+	server := New(t)
+	server.Expect().Get("/status").Times(2).RespondBody(http.StatusOK, "text/plain", "first")
+	server.Expect().Get("/status").RespondBody(http.StatusOK, "text/plain", "second")
+
+	for _, expected := range []string{"first", "first", "second"} {
+		var result string
+		require.NoError(t, remote.Get("https://example.com/status").With(server.Option()).Result(&result).Send())
+		require.Equal(t, expected, result)
+	}
+}
+
+func TestServer_Unexpected(t *testing.T) {
+
+	tb := &fakeTB{}
+	server := New(tb)
+	server.Expect().Post("/inbox").WithBody("hello")
+
+	// Wrong body, so the expectation does not match
+	transaction := remote.Post("https://example.com/inbox").Body("goodbye").With(server.Option())
+	require.Error(t, transaction.Send())
+	require.Equal(t, http.StatusNotImplemented, transaction.ResponseStatusCode())
+
+	require.Len(t, tb.errors, 1)
+	require.Contains(t, tb.errors[0], "unexpected request POST https://example.com/inbox")
+
+	// The unmatched expectation is reported when the test finishes
+	tb.finish()
+	require.Len(t, tb.errors, 2)
+	require.Contains(t, tb.errors[1], "expected POST /inbox to be called 1 time(s), but it was called 0 time(s)")
+}
+
+func TestServer_TooManyCalls(t *testing.T) {
+
+	tb := &fakeTB{}
+	server := New(tb)
+	server.Expect().Delete("/items/1")
+
+	require.NoError(t, remote.Delete("https://example.com/items/1").With(server.Option()).Send())
+	require.Error(t, remote.Delete("https://example.com/items/1").With(server.Option()).Send())
+
+	tb.finish()
+	require.Len(t, tb.errors, 1)
+	require.Contains(t, tb.errors[0], "unexpected request DELETE")
+}
+
+func TestServer_RespondJSONError(t *testing.T) {
+
+	tb := &fakeTB{}
+	server := New(tb)
+
+	// Channels cannot be encoded as JSON
+	server.Expect().Get("/items/1").RespondJSON(http.StatusOK, make(chan int))
+	require.Len(t, tb.errors, 1)
+	require.Contains(t, tb.errors[0], "unable to encode JSON response for GET /items/1")
+
+	transaction := remote.Get("https://example.com/items/1").With(server.Option())
+	require.Error(t, transaction.Send())
+	require.Equal(t, http.StatusInternalServerError, transaction.ResponseStatusCode())
+
+	tb.finish()
+	require.Len(t, tb.errors, 1)
+}
+
+func TestServer_AnyTimes(t *testing.T) {
+
+	tb := &fakeTB{}
+	server := New(tb)
+	server.Expect().Get("/optional").AnyTimes()
+	server.Expect().Get("/never").Times(0)
+
+	tb.finish()
+	require.Empty(t, tb.errors)
+}
+
+func TestExpectation_Matches(t *testing.T) {
+
+	request := Request{
+		Method: http.MethodPut,
+		URL:    "https://example.com/items/1?draft=true",
+		Header: http.Header{"Authorization": []string{"Bearer token"}},
+		Body:   []byte(`{"a": 1, "b": [1, 2]}`),
+	}
+
+	require.True(t, newExpectation().matches(request))
+	require.True(t, newExpectation().Put("/items/1").matches(request))
+	require.True(t, newExpectation().Put("https://example.com/items/1").matches(request))
+	require.True(t, newExpectation().WithQuery("draft", "true").matches(request))
+	require.True(t, newExpectation().WithHeader("Authorization", "Bearer token").matches(request))
+	require.True(t, newExpectation().WithJSONBody(map[string]any{"b": []int{1, 2}, "a": 1}).matches(request))
+
+	require.False(t, newExpectation().Get("/items/1").matches(request))
+	require.False(t, newExpectation().Put("/items/2").matches(request))
+	require.False(t, newExpectation().Put("https://other.com/items/1").matches(request))
+	require.False(t, newExpectation().WithQuery("draft", "false").matches(request))
+	require.False(t, newExpectation().WithHeader("Authorization", "Bearer other").matches(request))
+	require.False(t, newExpectation().WithJSONBody(map[string]any{"a": 2}).matches(request))
+	require.False(t, newExpectation().WithBody("nope").matches(request))
+}
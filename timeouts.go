@@ -0,0 +1,206 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/benpate/derp"
+)
+
+// Timeouts sets time limits on the individual phases of a request. A zero value
+// for any phase means that phase has no limit of its own (Total falls back to
+// the one-minute default). Phase limits are enforced per request by canceling
+// its context, so they work with the shared, pooled base transport.
+type Timeouts struct {
+	Dial           time.Duration // time allowed to resolve the host and connect to it
+	TLSHandshake   time.Duration // time allowed for the TLS handshake
+	ResponseHeader time.Duration // time allowed, after the request is written, for the response headers to arrive
+	BodyIdle       time.Duration // longest pause allowed between bytes while reading the response body
+	Total          time.Duration // time allowed for the entire transaction, including reading the body
+}
+
+// timeoutGuard cancels a request when one of its phases runs past its limit,
+// and remembers which phase it was so Send can report a meaningful error.
+type timeoutGuard struct {
+	timeouts Timeouts
+	cancel   context.CancelCauseFunc
+	mutex    sync.Mutex
+	timers   map[string]*time.Timer
+	expired  error
+}
+
+// newTimeoutGuard wraps ctx so that it is canceled if any phase in timeouts is
+// exceeded. The returned guard must be stopped when the request is complete.
+func newTimeoutGuard(ctx context.Context, timeouts Timeouts) (context.Context, *timeoutGuard) {
+
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	guard := &timeoutGuard{
+		timeouts: timeouts,
+		cancel:   cancel,
+		timers:   map[string]*time.Timer{},
+	}
+
+	// Phase timers are driven by the request's trace events.
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			guard.start("Dial", timeouts.Dial)
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			if err == nil {
+				guard.stop("Dial")
+			}
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			guard.stop("Dial")
+		},
+		TLSHandshakeStart: func() {
+			guard.start("TLSHandshake", timeouts.TLSHandshake)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			guard.stop("TLSHandshake")
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			guard.start("ResponseHeader", timeouts.ResponseHeader)
+		},
+		GotFirstResponseByte: func() {
+			guard.stop("ResponseHeader")
+		},
+	}
+
+	return httptrace.WithClientTrace(ctx, trace), guard
+}
+
+// start begins timing a phase. Phases with no limit are ignored.
+func (guard *timeoutGuard) start(phase string, limit time.Duration) {
+
+	if limit <= 0 {
+		return
+	}
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	// A phase that starts again (e.g. on a redirect hop) gets its full limit again.
+	if timer, exists := guard.timers[phase]; exists {
+		timer.Reset(limit)
+		return
+	}
+
+	guard.timers[phase] = time.AfterFunc(limit, func() {
+		guard.expire(phase, limit)
+	})
+}
+
+// stop ends timing a phase, if it is being timed.
+func (guard *timeoutGuard) stop(phase string) {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	if timer, exists := guard.timers[phase]; exists {
+		timer.Stop()
+	}
+}
+
+// restart resets a phase's timer so that it starts counting again from now.
+func (guard *timeoutGuard) restart(phase string, limit time.Duration) {
+
+	guard.mutex.Lock()
+	timer, exists := guard.timers[phase]
+	guard.mutex.Unlock()
+
+	if exists {
+		timer.Reset(limit)
+		return
+	}
+
+	guard.start(phase, limit)
+}
+
+// expire cancels the request because a phase ran too long.
+func (guard *timeoutGuard) expire(phase string, limit time.Duration) {
+
+	const location = "remote.timeoutGuard.expire"
+
+	err := derp.Wrap(context.DeadlineExceeded, location, phase+" timeout exceeded", limit.String())
+
+	guard.mutex.Lock()
+	if guard.expired == nil {
+		guard.expired = err
+	}
+	guard.mutex.Unlock()
+
+	guard.cancel(err)
+}
+
+// explain adds the phase timeout that canceled the request (if any) to err,
+// so callers see which phase ran long instead of a bare "context canceled".
+func (guard *timeoutGuard) explain(err error) error {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	if (guard.expired == nil) || errors.Is(err, guard.expired) {
+		return err
+	}
+
+	return errors.Join(guard.expired, err)
+}
+
+// close stops every timer and releases the guarded context.
+func (guard *timeoutGuard) close() {
+
+	guard.mutex.Lock()
+	for _, timer := range guard.timers {
+		timer.Stop()
+	}
+	guard.mutex.Unlock()
+
+	guard.cancel(nil)
+}
+
+// watchBody wraps the response body so that reading it fails if no bytes
+// arrive within the BodyIdle limit.
+func (guard *timeoutGuard) watchBody(response *http.Response) {
+
+	if (guard.timeouts.BodyIdle <= 0) || (response == nil) || (response.Body == nil) {
+		return
+	}
+
+	guard.start("BodyIdle", guard.timeouts.BodyIdle)
+	response.Body = &idleReader{ReadCloser: response.Body, guard: guard}
+}
+
+// idleReader restarts the BodyIdle timer every time bytes are read, and stops
+// it when the body is finished or closed.
+type idleReader struct {
+	io.ReadCloser
+	guard *timeoutGuard
+}
+
+// Read reads from the underlying body, restarting the idle timer on progress.
+func (reader *idleReader) Read(buffer []byte) (int, error) {
+
+	count, err := reader.ReadCloser.Read(buffer)
+
+	if err != nil {
+		reader.guard.stop("BodyIdle")
+	} else if count > 0 {
+		reader.guard.restart("BodyIdle", reader.guard.timeouts.BodyIdle)
+	}
+
+	return count, err
+}
+
+// Close stops the idle timer and closes the underlying body.
+func (reader *idleReader) Close() error {
+	reader.guard.stop("BodyIdle")
+	return reader.ReadCloser.Close()
+}
@@ -0,0 +1,154 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowServer returns a server that waits headerDelay before sending headers,
+// then writes the body one byte at a time, pausing byteDelay between bytes.
+func slowServer(t *testing.T, headerDelay time.Duration, byteDelay time.Duration, body string) *httptest.Server {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		select {
+		case <-time.After(headerDelay):
+		case <-r.Context().Done():
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for index := range len(body) {
+
+			select {
+			case <-time.After(byteDelay):
+			case <-r.Context().Done():
+				return
+			}
+
+			_, _ = w.Write([]byte{body[index]})
+			w.(http.Flusher).Flush()
+		}
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTimeouts_ResponseHeader(t *testing.T) {
+
+	server := slowServer(t, time.Second, 0, "ok")
+
+	err := Get(server.URL).AllowPrivateIPs(true).Timeouts(Timeouts{ResponseHeader: 50 * time.Millisecond}).Send()
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "ResponseHeader")
+}
+
+func TestTimeouts_ResponseHeaderOnRedirect(t *testing.T) {
+
+	// The first hop redirects at once, and the second stalls before sending headers
+	stalled := slowServer(t, 5*time.Second, 0, "ok")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, stalled.URL, http.StatusFound)
+	}))
+	t.Cleanup(server.Close)
+
+	start := time.Now()
+	err := Get(server.URL).AllowPrivateIPs(true).Timeouts(Timeouts{ResponseHeader: 100 * time.Millisecond, Total: 10 * time.Second}).Send()
+
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "ResponseHeader")
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestTimeouts_BodyIdle(t *testing.T) {
+
+	server := slowServer(t, 0, time.Second, "ok")
+
+	var result string
+	err := Get(server.URL).AllowPrivateIPs(true).Timeouts(Timeouts{BodyIdle: 50 * time.Millisecond}).Result(&result).Send()
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "BodyIdle")
+}
+
+func TestTimeouts_BodyIdleResetsOnProgress(t *testing.T) {
+
+	// The whole body takes longer than BodyIdle, but no single pause does.
+	server := slowServer(t, 0, 20*time.Millisecond, "slow drip")
+
+	var result string
+	err := Get(server.URL).AllowPrivateIPs(true).Timeouts(Timeouts{BodyIdle: 150 * time.Millisecond}).Result(&result).Send()
+	require.NoError(t, err)
+	require.Equal(t, "slow drip", result)
+}
+
+func TestTimeouts_Total(t *testing.T) {
+
+	server := slowServer(t, 0, 20*time.Millisecond, "too long to wait for")
+
+	var result string
+	err := Get(server.URL).AllowPrivateIPs(true).Timeouts(Timeouts{Total: 100 * time.Millisecond}).Result(&result).Send()
+	require.Error(t, err)
+}
+
+func TestTimeouts_Dial(t *testing.T) {
+
+	// 10.255.255.1 is non-routable, so a connection attempt hangs (or fails fast
+	// in sandboxed networks). Either way, the request must not take long.
+	start := time.Now()
+	err := Get("http://10.255.255.1/").AllowPrivateIPs(true).Timeouts(Timeouts{Dial: 50 * time.Millisecond}).Send()
+
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestTimeouts_WithinLimits(t *testing.T) {
+
+	server := slowServer(t, 0, 0, "fast")
+
+	var result string
+	err := Get(server.URL).AllowPrivateIPs(true).
+		Timeouts(Timeouts{Dial: time.Second, TLSHandshake: time.Second, ResponseHeader: time.Second, BodyIdle: time.Second, Total: 5 * time.Second}).
+		Result(&result).
+		Send()
+
+	require.NoError(t, err)
+	require.Equal(t, "fast", result)
+}
+
+func TestTimeouts_KeepsPooledTransport(t *testing.T) {
+
+	// Phase timeouts must not require a dedicated (unpooled) transport.
+	client := New().Timeouts(Timeouts{Dial: time.Second, ResponseHeader: time.Second, Total: 2 * time.Minute}).buildClient()
+
+	require.True(t, client.Transport == safeTransport)
+	require.Equal(t, 2*time.Minute, client.Timeout)
+}
+
+func TestTimeouts_TotalBoundsRequestContext(t *testing.T) {
+
+	ctx, cancel := New().Timeouts(Timeouts{Total: 5 * time.Second}).requestContext()
+	t.Cleanup(cancel)
+
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(5*time.Second), deadline, time.Second)
+
+	// A Total timeout also bounds a caller-supplied context.
+	ctx, cancel = New().WithContext(context.Background()).Timeouts(Timeouts{Total: 5 * time.Second}).requestContext()
+	t.Cleanup(cancel)
+
+	_, ok = ctx.Deadline()
+	require.True(t, ok)
+}
@@ -0,0 +1,123 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings breaks down how long each phase of a request took. Phases that did
+// not happen (such as DNS and TLS on a reused connection) are zero. When a
+// request is redirected, durations are summed across every hop, while
+// ConnectionReused and RemoteAddr describe the final hop.
+type Timings struct {
+	DNSLookup        time.Duration // time spent resolving the host name
+	Connect          time.Duration // time spent establishing the TCP connection
+	TLSHandshake     time.Duration // time spent on the TLS handshake
+	TimeToFirstByte  time.Duration // time from the start of the request until the first byte of the response arrived
+	BodyRead         time.Duration // time spent reading the response body
+	Total            time.Duration // time from the start of the request until the body was read
+	ConnectionReused bool          // TRUE if the request was sent on a pooled connection
+	RemoteAddr       string        // IP address and port that was actually connected to
+}
+
+// timingRecorder collects Timings from a request's trace events. Events may
+// arrive from the transport's own goroutines, so every field is guarded.
+type timingRecorder struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	bodyStart    time.Time
+	timings      Timings
+}
+
+// newTimingRecorder returns a recorder that starts timing immediately, along
+// with a context that reports the request's trace events to it.
+func newTimingRecorder(ctx context.Context) (context.Context, *timingRecorder) {
+
+	recorder := &timingRecorder{
+		start: time.Now(),
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			recorder.mark(&recorder.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			recorder.add(&recorder.timings.DNSLookup, &recorder.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			recorder.mark(&recorder.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			recorder.add(&recorder.timings.Connect, &recorder.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			recorder.mark(&recorder.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			recorder.add(&recorder.timings.TLSHandshake, &recorder.tlsStart)
+		},
+		GotConn: recorder.gotConn,
+		GotFirstResponseByte: func() {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			recorder.timings.TimeToFirstByte = time.Since(recorder.start)
+		},
+	}
+
+	return httptrace.WithClientTrace(ctx, trace), recorder
+}
+
+// mark records the current time into one of the recorder's start fields.
+func (recorder *timingRecorder) mark(field *time.Time) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	*field = time.Now()
+}
+
+// add accumulates the time since one of the recorder's start fields into one
+// of its durations.
+func (recorder *timingRecorder) add(field *time.Duration, start *time.Time) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if !start.IsZero() {
+		*field += time.Since(*start)
+	}
+}
+
+// gotConn records which connection the request was sent on.
+func (recorder *timingRecorder) gotConn(info httptrace.GotConnInfo) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.timings.ConnectionReused = info.Reused
+
+	if info.Conn != nil {
+		recorder.timings.RemoteAddr = info.Conn.RemoteAddr().String()
+	}
+}
+
+// startBody marks the moment that the response body begins to be read.
+func (recorder *timingRecorder) startBody() {
+	recorder.mark(&recorder.bodyStart)
+}
+
+// endBody records how long the response body took to read.
+func (recorder *timingRecorder) endBody() {
+	recorder.add(&recorder.timings.BodyRead, &recorder.bodyStart)
+}
+
+// finish completes the recording and returns the collected Timings.
+func (recorder *timingRecorder) finish() Timings {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.timings.Total = time.Since(recorder.start)
+	return recorder.timings
+}
@@ -0,0 +1,70 @@
+package remote
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimings_RecordsPhases(t *testing.T) {
+
+	server := slowServer(t, 50*time.Millisecond, 10*time.Millisecond, "timed")
+
+	txn := Get(server.URL).AllowPrivateIPs(true)
+	require.NoError(t, txn.Send())
+
+	timings := txn.Timings()
+
+	require.GreaterOrEqual(t, timings.TimeToFirstByte, 50*time.Millisecond)
+	require.GreaterOrEqual(t, timings.BodyRead, 40*time.Millisecond)
+	require.GreaterOrEqual(t, timings.Total, timings.TimeToFirstByte+timings.BodyRead)
+	require.Zero(t, timings.TLSHandshake)
+
+	// The remote address is the IP that was actually dialed.
+	host, _, err := net.SplitHostPort(timings.RemoteAddr)
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", host)
+}
+
+func TestTimings_ConnectionReused(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	// The first request opens a new connection...
+	first := Get(server.URL).AllowPrivateIPs(true)
+	require.NoError(t, first.Send())
+	require.False(t, first.Timings().ConnectionReused)
+	require.Positive(t, first.Timings().Connect)
+
+	// ...which the second request reuses from the pool, skipping the connect.
+	second := Get(server.URL).AllowPrivateIPs(true)
+	require.NoError(t, second.Send())
+	require.True(t, second.Timings().ConnectionReused)
+	require.Zero(t, second.Timings().Connect)
+}
+
+func TestTimings_TLS(t *testing.T) {
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("secure"))
+	}))
+	t.Cleanup(server.Close)
+
+	txn := Get(server.URL).WithRoundTripper(func(http.RoundTripper) http.RoundTripper {
+		return server.Client().Transport
+	})
+
+	require.NoError(t, txn.Send())
+	require.Positive(t, txn.Timings().TLSHandshake)
+}
+
+func TestTimings_BeforeSend(t *testing.T) {
+	require.Equal(t, Timings{}, New().Timings())
+}
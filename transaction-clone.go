@@ -0,0 +1,82 @@
+package remote
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+)
+
+// Clone returns a deep copy of the transaction's builder state (method, URL,
+// headers, query, form, body, options, and settings) so that a prepared
+// transaction can be used as a template, and each copy can be modified and
+// sent independently, even from different goroutines. The request, response,
+// and timings of a previous Send are not copied.
+//
+// A streaming (io.Reader) body is read into memory once, the first time any
+// of the transactions needs it, and then shared by all of them. Clone does not
+// modify the original transaction, so many goroutines can clone the same
+// template at once. Other body values, such as structs to be encoded as JSON,
+// are shared, and must not be modified while either transaction is in use.
+// Result and Error targets are also shared, so set new ones on each clone that
+// is sent concurrently.
+func (t *Transaction) Clone() *Transaction {
+
+	result := &Transaction{
+		method:           t.method,
+		url:              t.url,
+		header:           maps.Clone(t.header),
+		query:            cloneValues(t.query),
+		form:             cloneValues(t.form),
+		body:             t.body,
+		success:          t.success,
+		failure:          t.failure,
+		options:          slices.Clone(t.options),
+		allowedHosts:     slices.Clone(t.allowedHosts),
+		allowPrivateIPs:  t.allowPrivateIPs,
+		maxResponseSize:  t.maxResponseSize,
+		redirectPolicy:   t.redirectPolicy,
+		timeouts:         t.timeouts,
+		compression:      t.compression,
+		downloadPolicy:   t.downloadPolicy,
+		progressInterval: t.progressInterval,
+		bandwidth:        t.bandwidth,
+		ctx:              t.ctx,
+		roundTripper:     t.roundTripper,
+		uploadProgress:   t.uploadProgress,
+		downloadProgress: t.downloadProgress,
+	}
+
+	result.redirectPolicy.SensitiveHeaders = slices.Clone(t.redirectPolicy.SensitiveHeaders)
+	result.compression.Encodings = slices.Clone(t.compression.Encodings)
+
+	// Ensure that the maps are never nil, even if this transaction was not created with New()
+	if result.header == nil {
+		result.header = map[string]string{}
+	}
+
+	if body, ok := t.body.([]byte); ok {
+		result.body = bytes.Clone(body)
+	}
+
+	return result
+}
+
+// Reset clears the transaction, including the request and response from any
+// previous Send, and restores the default settings of New(), so that the
+// transaction can be reused for a different request.
+func (t *Transaction) Reset() *Transaction {
+	*t = *New()
+	return t
+}
+
+// cloneValues returns a deep copy of a url.Values map, which is never nil.
+func cloneValues(values map[string][]string) map[string][]string {
+
+	result := make(map[string][]string, len(values))
+
+	for name, items := range values {
+		result[name] = slices.Clone(items)
+	}
+
+	return result
+}
@@ -0,0 +1,176 @@
+package remote
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClone_DeepCopy(t *testing.T) {
+
+	original := Post("https://example.com/inbox").
+		Header("X-Original", "true").
+		Query("page", "1").
+		Form("name", "alice").
+		AllowHosts("example.com").
+		Redirects(RedirectPolicy{SensitiveHeaders: []string{"X-Secret"}}).
+		With(Option{})
+
+	clone := original.Clone()
+	require.Equal(t, original.MarshalMap(), clone.MarshalMap())
+
+	// Modifying the clone does not affect the original
+	clone.Header("X-Clone", "true").Query("page", "2").Form("name", "bob").AllowHosts("other.com").With(Option{})
+	clone.redirectPolicy.SensitiveHeaders[0] = "X-Changed"
+
+	require.Empty(t, original.header["X-Clone"])
+	require.Equal(t, []string{"1"}, original.query["page"])
+	require.Equal(t, []string{"alice"}, original.form["name"])
+	require.Equal(t, []string{"example.com"}, original.allowedHosts)
+	require.Len(t, original.options, 1)
+	require.Equal(t, "X-Secret", original.redirectPolicy.SensitiveHeaders[0])
+}
+
+func TestClone_ResponseState(t *testing.T) {
+
+	ts := jsonServer(200, `{"ok":true}`)
+	defer ts.Close()
+
+	original := Get(ts.URL).AllowPrivateIPs(true)
+	require.NoError(t, original.Send())
+	require.NotNil(t, original.Response())
+
+	clone := original.Clone()
+	require.Nil(t, clone.Request())
+	require.Nil(t, clone.Response())
+	require.Equal(t, Timings{}, clone.Timings())
+	require.True(t, clone.allowPrivateIPs)
+}
+
+func TestClone_ReaderBody(t *testing.T) {
+
+	original := Post("https://example.com/upload").ContentType(ContentTypePlain).JSON(strings.NewReader("streamed"))
+	clone := original.Clone()
+
+	// Both transactions can read the (formerly streaming) body
+	for _, transaction := range []*Transaction{original, clone, original} {
+		body, err := transaction.RequestBody()
+		require.NoError(t, err)
+		require.Equal(t, "streamed", string(body))
+	}
+}
+
+func TestClone_BytesBody(t *testing.T) {
+
+	body := []byte("original")
+	original := Post("https://example.com/upload").ContentType(ContentTypePlain)
+	original.body = body
+
+	clone := original.Clone()
+	body[0] = 'O'
+
+	result, err := clone.RequestBody()
+	require.NoError(t, err)
+	require.Equal(t, "original", string(result))
+}
+
+func TestClone_Concurrent(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set(ContentType, ContentTypePlain)
+		_, _ = w.Write([]byte(r.Header.Get("X-Worker") + ":" + r.URL.Query().Get("worker") + ":" + string(body)))
+	}))
+	defer ts.Close()
+
+	templates := map[string]*Transaction{
+		"string": Post(ts.URL).AllowPrivateIPs(true).Header("X-Template", "true").Body("payload"),
+		"reader": Post(ts.URL).AllowPrivateIPs(true).Header("X-Template", "true").ContentType(ContentTypePlain).JSON(strings.NewReader("payload")),
+	}
+
+	for name, template := range templates {
+
+		// Errors are collected, and checked on the test goroutine
+		var wg sync.WaitGroup
+		errs := make([]error, 20)
+		results := make([]string, 20)
+
+		for index := range 20 {
+			wg.Go(func() {
+
+				worker := strconv.Itoa(index)
+
+				transaction := template.Clone().
+					Header("X-Worker", worker).
+					Query("worker", worker).
+					Result(&results[index])
+
+				errs[index] = transaction.Send()
+			})
+		}
+
+		wg.Wait()
+
+		for index := range 20 {
+			worker := strconv.Itoa(index)
+			require.NoError(t, errs[index], name)
+			require.Equal(t, worker+":"+worker+":payload", results[index], name)
+		}
+
+		// The template is unchanged, and can still be used
+		require.Empty(t, template.header["X-Worker"], name)
+		require.Empty(t, template.query, name)
+		require.Nil(t, template.Response(), name)
+
+		body, err := template.RequestBody()
+		require.NoError(t, err, name)
+		require.Equal(t, "payload", string(body), name)
+	}
+}
+
+func TestClone_ConcurrentReaderBody(t *testing.T) {
+
+	// Clones made at the same time share a single read of the stream, so none of them receives an empty body
+	template := Post("https://example.com/upload").ContentType(ContentTypePlain).JSON(strings.NewReader("streamed"))
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 50)
+	errs := make([]error, 50)
+
+	for index := range 50 {
+		wg.Go(func() {
+			body, err := template.Clone().RequestBody()
+			bodies[index], errs[index] = string(body), err
+		})
+	}
+
+	wg.Wait()
+
+	for index := range 50 {
+		require.NoError(t, errs[index])
+		require.Equal(t, "streamed", bodies[index])
+	}
+}
+
+func TestReset(t *testing.T) {
+
+	ts := jsonServer(200, `{"ok":true}`)
+	defer ts.Close()
+
+	transaction := Get(ts.URL).AllowPrivateIPs(true).Header("X-Test", "true").Query("a", "b").MaxResponseSize(1024)
+	require.NoError(t, transaction.Send())
+
+	transaction.Reset()
+	require.Equal(t, New(), transaction)
+
+	// The transaction can be reused for a different request
+	result := map[string]any{}
+	require.NoError(t, transaction.Get(ts.URL).AllowPrivateIPs(true).Result(&result).Send())
+	require.Equal(t, true, result["ok"])
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"sync"
 
 	"github.com/benpate/derp"
 )
@@ -27,6 +28,15 @@ func (t *Transaction) RequestBody() ([]byte, error) {
 	// If we already have a reader for the Body, then just return that.
 	switch typedValue := t.body.(type) {
 
+	case *readerBody:
+		result, err := typedValue.bytes()
+
+		if err != nil {
+			return nil, derp.Wrap(err, location, "Reading request body from io.Reader")
+		}
+
+		return bytes.Clone(result), nil
+
 	case io.Reader:
 		result, err := io.ReadAll(typedValue)
 
@@ -80,3 +90,33 @@ func (t *Transaction) RequestBody() ([]byte, error) {
 	err = derp.Wrap(err, location, "Unsupported Content-Type", contentType, derp.WithInternalError())
 	return []byte{}, err
 }
+
+// readerBody holds a streaming (io.Reader) request body, which is read into
+// memory the first time it is needed. The same bytes are then used every time
+// the body is sent, including by clones on other goroutines.
+type readerBody struct {
+	reader io.Reader
+	once   sync.Once
+	data   []byte
+	err    error
+}
+
+// newBody wraps a streaming body in a readerBody. Other values are returned as they are.
+func newBody(value any) any {
+
+	if reader, ok := value.(io.Reader); ok {
+		return &readerBody{reader: reader}
+	}
+
+	return value
+}
+
+// bytes returns the contents of the body, reading it on the first call.
+func (body *readerBody) bytes() ([]byte, error) {
+
+	body.once.Do(func() {
+		body.data, body.err = io.ReadAll(body.reader)
+	})
+
+	return body.data, body.err
+}
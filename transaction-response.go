@@ -11,11 +11,43 @@ import (
 	"github.com/benpate/derp"
 )
 
+// Request returns the HTTP request that was sent to the remote server. It is nil
+// until Send has assembled the request.
+func (t *Transaction) Request() *http.Request {
+	return t.request
+}
+
 // Response returns the original HTTP response object.
 func (t *Transaction) Response() *http.Response {
 	return t.response
 }
 
+// RedirectChain returns every URL visited while sending the request, in order,
+// along with the status code each one returned. The last hop is the final
+// response. It is empty if no response has been received.
+func (t *Transaction) RedirectChain() []RedirectHop {
+
+	result := RedirectChain(t.response)
+
+	// Responses substituted by a ModifyRequest option may not link back to a
+	// request, so fall back to the request that this transaction sent.
+	if (len(result) == 0) && (t.response != nil) && (t.request != nil) {
+		result = append(result, RedirectHop{
+			URL:        t.request.URL.String(),
+			StatusCode: t.response.StatusCode,
+		})
+	}
+
+	return result
+}
+
+// Timings returns how long each phase of the most recent Send took, including
+// the DNS lookup, connection, TLS handshake, time to first byte and body read,
+// along with whether the connection was reused and the IP address dialed.
+func (t *Transaction) Timings() Timings {
+	return t.timings
+}
+
 // ResponseHeader returns the HTTP response header.
 func (t *Transaction) ResponseHeader() http.Header {
 
@@ -130,12 +162,40 @@ func (t *Transaction) decodeResponseBody(body []byte, result any) error {
 		return nil
 
 	case *string:
-		*result = string(body)
+		text, err := DecodeCharset(body, t.response.Header.Get(ContentType))
+
+		if err != nil {
+			err = derp.WrapHTTPError(err, t.request, t.response)
+			err = derp.Wrap(err, location, "Unable to decode response body", derp.WithInternalError())
+			return err
+		}
+
+		*result = string(text)
+		return nil
+
+	case *Feed:
+		feed, err := ParseFeed(body, t.response.Header.Get(ContentType), t.finalURL())
+
+		if err != nil {
+			err = derp.WrapHTTPError(err, t.request, t.response)
+			err = derp.Wrap(err, location, "Unable to parse feed", derp.WithInternalError())
+			return err
+		}
+
+		*result = feed
 		return nil
 	}
 
-	// Otherwise, try to use the content type to pick an unmarshaller
+	// Otherwise, convert the body to UTF-8 and use the content type to pick an unmarshaller
 	contentType := t.response.Header.Get(ContentType) // Get the content type from the header
+	body, err := DecodeCharset(body, contentType)
+
+	if err != nil {
+		err = derp.WrapHTTPError(err, t.request, t.response)
+		err = derp.Wrap(err, location, "Unable to decode response body", contentType, derp.WithInternalError())
+		return err
+	}
+
 	contentType, _, _ = strings.Cut(contentType, ";") // Strip out suffixes, such as "; charset=utf-8"
 
 	switch contentType {
@@ -152,8 +212,12 @@ func (t *Transaction) decodeResponseBody(body []byte, result any) error {
 		ContentTypeRSSXML,
 		ContentTypeAtomXML:
 
-		// Parse the result and return to the caller.
-		if err := xml.Unmarshal(body, result); err != nil {
+		// Parse the result and return to the caller. The body is already UTF-8,
+		// whatever encoding its XML declaration names.
+		decoder := xml.NewDecoder(bytes.NewReader(body))
+		decoder.CharsetReader = utf8CharsetReader
+
+		if err := decoder.Decode(result); err != nil {
 			err = derp.WrapHTTPError(err, t.request, t.response)
 			err = derp.Wrap(err, location, "Unable to unmarshal XML Response", string(body), result, derp.WithInternalError())
 			return err
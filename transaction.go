@@ -18,24 +18,35 @@ import (
 
 // Transaction represents a single HTTP request/response to a remote HTTP server.
 type Transaction struct {
-	method          string            // HTTP method to use when sending the request
-	url             string            // URL of the remote server to call
-	header          map[string]string // HTTP Header values to send in the request
-	query           url.Values        // Query String to append to the URL
-	form            url.Values        // (if set) Form data to pass to the remote server as x-www-form-urlencoded
-	body            any               // Other data to send in the body.  Encoding determined by header["Content-Type"]
-	success         any               // Object to parse the response into -- IF the status code is successful
-	failure         any               // Object to parse the response into -- IF the status code is NOT successful
-	options         []Option          // options to execute on the request/response
-	allowedHosts    []string          // (if set) request URL host must match one of these values
-	allowPrivateIPs bool              // if FALSE (the default), refuse to connect to non-public (private/internal) IP addresses
-	maxResponseSize int64             // maximum number of bytes to read from the response body
-	ctx             context.Context   // NOSONAR(S8242): request-scoped builder
+	method           string            // HTTP method to use when sending the request
+	url              string            // URL of the remote server to call
+	header           map[string]string // HTTP Header values to send in the request
+	query            url.Values        // Query String to append to the URL
+	form             url.Values        // (if set) Form data to pass to the remote server as x-www-form-urlencoded
+	body             any               // Other data to send in the body.  Encoding determined by header["Content-Type"]
+	success          any               // Object to parse the response into -- IF the status code is successful
+	failure          any               // Object to parse the response into -- IF the status code is NOT successful
+	options          []Option          // options to execute on the request/response
+	allowedHosts     []string          // (if set) request URL host must match one of these values
+	allowPrivateIPs  bool              // if FALSE (the default), refuse to connect to non-public (private/internal) IP addresses
+	maxResponseSize  int64             // maximum number of bytes to read from the response body
+	redirectPolicy   RedirectPolicy    // rules for following HTTP redirects
+	timeouts         Timeouts          // time limits for the individual phases of the request
+	compression      Compression       // how the request is compressed, and the response negotiated and decompressed
+	downloadPolicy   DownloadPolicy    // how Download verifies and saves the response
+	progressInterval time.Duration     // shortest time between two progress reports
+	bandwidth        Bandwidth         // limits on how fast the request and response bodies are transferred
+	ctx              context.Context   // NOSONAR(S8242): request-scoped builder
 
 	request  *http.Request  // HTTP request that is delivered to the remote server
 	response *http.Response // HTTP response that is returned from the remote server
+	timings  Timings        // how long each phase of the most recent Send took
 
 	roundTripper func(http.RoundTripper) http.RoundTripper // (if set) wraps the base transport with caller-supplied middleware
+	streamBody   func(*http.Response) error                // (if set) consumes the response body in place of ResponseBody and processResponse (used by Download)
+
+	uploadProgress   ProgressFunc // (if set) reports how much of the request body has been sent
+	downloadProgress ProgressFunc // (if set) reports how much of the response body has been received
 }
 
 /******************************************
@@ -175,7 +186,7 @@ func (t *Transaction) Body(value string) *Transaction {
 
 // JSON sets the request body, to be encoded as JSON.
 func (t *Transaction) JSON(value any) *Transaction {
-	t.body = value
+	t.body = newBody(value)
 
 	if t.isContentTypeEmpty() {
 		t.ContentType(ContentTypeJSON)
@@ -185,7 +196,7 @@ func (t *Transaction) JSON(value any) *Transaction {
 
 // XML sets the request body, to be encoded as XML.
 func (t *Transaction) XML(value any) *Transaction {
-	t.body = value
+	t.body = newBody(value)
 
 	if t.isContentTypeEmpty() {
 		t.ContentType(ContentTypeXML)
@@ -226,6 +237,15 @@ func (t *Transaction) AllowPrivateIPs(value bool) *Transaction {
 	return t
 }
 
+// Redirects sets the policy for following HTTP redirects: how many hops to
+// follow (if any), whether they must stay on the same host, whether https may
+// be downgraded to http, and which headers to strip on cross-origin hops. The
+// host allow-list (AllowHosts) is applied to every hop in addition to the policy.
+func (t *Transaction) Redirects(policy RedirectPolicy) *Transaction {
+	t.redirectPolicy = policy
+	return t
+}
+
 // MaxResponseSize sets the maximum number of bytes that will be read from the
 // response body. A response larger than this causes Send to return an error,
 // preventing an untrusted server from exhausting memory. The default is 1GB.
@@ -243,6 +263,15 @@ func (t *Transaction) WithContext(ctx context.Context) *Transaction {
 	return t
 }
 
+// Timeouts sets time limits on the individual phases of the request: dialing,
+// the TLS handshake, waiting for response headers, pauses while reading the
+// response body, and the transaction as a whole. Phases left at zero have no
+// limit of their own, and are still bounded by the total timeout.
+func (t *Transaction) Timeouts(timeouts Timeouts) *Transaction {
+	t.timeouts = timeouts
+	return t
+}
+
 // Result sets the object for parsing HTTP success responses
 func (t *Transaction) Result(object any) *Transaction {
 	t.success = object
@@ -258,6 +287,17 @@ func (t *Transaction) Error(object any) *Transaction {
 // Send executes the transaction, sending the request to the remote server.
 func (t *Transaction) Send() error {
 
+	err := t.send()
+
+	// onComplete reports the final outcome to any options that are watching.
+	t.onComplete(err)
+
+	return err
+}
+
+// send does the work of Send, so that every outcome passes through onComplete.
+func (t *Transaction) send() error {
+
 	const location = "remote.Transaction.Send"
 
 	// onBeforeRequest modifies the transaction before an http.Request is created
@@ -269,6 +309,16 @@ func (t *Transaction) Send() error {
 	ctx, cancel := t.requestContext()
 	defer cancel()
 
+	// Enforce per-phase timeouts by canceling the context when a phase runs long.
+	ctx, guard := newTimeoutGuard(ctx, t.timeouts)
+	defer guard.close()
+
+	// Record how long each phase takes, even if the request fails.
+	ctx, recorder := newTimingRecorder(ctx)
+	defer func() {
+		t.timings = recorder.finish()
+	}()
+
 	// Assemble the HTTP request from the transaction data
 	request, err := t.assembleRequest(ctx)
 
@@ -280,7 +330,7 @@ func (t *Transaction) Send() error {
 
 	// Send the request (or use a response substituted by a ModifyRequest option).
 	if err := t.executeRequest(); err != nil {
-		return derp.Wrap(err, location, "Sending request")
+		return derp.Wrap(guard.explain(err), location, "Sending request")
 	}
 
 	// A response must exist past this point; guard so we never dereference a nil.
@@ -288,6 +338,18 @@ func (t *Transaction) Send() error {
 		return derp.Internal(location, "No response received from server")
 	}
 
+	// Abort reading the body if the server stalls for longer than BodyIdle.
+	guard.watchBody(t.response)
+
+	// Read the body no faster than the bandwidth limit allows.
+	t.throttleResponse(t.response)
+
+	// Report download progress as the (still compressed) body arrives.
+	t.watchDownloadProgress(t.response)
+
+	// Decompress the body as it is read, so that MaxResponseSize limits the decompressed size.
+	t.decompressResponse()
+
 	// Close the response body when we're done, to release the underlying
 	// connection. ResponseBody (below) buffers the body in memory and swaps in a
 	// re-readable NopCloser, so closing the original here does not prevent
@@ -305,11 +367,26 @@ func (t *Transaction) Send() error {
 		return err
 	}
 
+	// Stream the body to its destination, if something else consumes it.
+	if t.streamBody != nil {
+		recorder.startBody()
+		err := t.streamBody(t.response)
+		recorder.endBody()
+
+		if err != nil {
+			return derp.Wrap(guard.explain(err), location, "Streaming response body")
+		}
+
+		return nil
+	}
+
 	// read the body of the response
+	recorder.startBody()
 	body, err := t.ResponseBody()
+	recorder.endBody()
 
 	if err != nil {
-		err = derp.WrapHTTPError(err, t.request, t.response)
+		err = derp.WrapHTTPError(guard.explain(err), t.request, t.response)
 		err = derp.Wrap(err, location, "Reading response body", derp.WithInternalError())
 		return err
 	}
@@ -330,6 +407,12 @@ func (t *Transaction) processResponse(body []byte) error {
 
 	const location = "remote.Transaction.processResponse"
 
+	// 304 Not Modified answers a conditional request: the caller's copy is still
+	// current, so there is nothing to decode and the Result is left untouched.
+	if t.NotModified() {
+		return nil
+	}
+
 	// A non-2xx status is an error; decode the body into the failure object if one is set.
 	if statusCode := t.statusCode(); (statusCode < 200) || (statusCode > 299) {
 
@@ -384,12 +467,22 @@ const defaultRequestTimeout = 1 * time.Minute
 // requestContext returns the context for this request and a cancel function that
 // must always be called. A caller-supplied context (via WithContext) is used as
 // is; otherwise a background context bounded by defaultRequestTimeout is used.
+// Either way, a Total timeout (via Timeouts) further bounds the request.
 func (t *Transaction) requestContext() (context.Context, context.CancelFunc) {
 
 	if t.ctx != nil {
+
+		if t.timeouts.Total > 0 {
+			return context.WithTimeout(t.ctx, t.timeouts.Total)
+		}
+
 		return context.WithCancel(t.ctx)
 	}
 
+	if t.timeouts.Total > 0 {
+		return context.WithTimeout(context.Background(), t.timeouts.Total)
+	}
+
 	return context.WithTimeout(context.Background(), defaultRequestTimeout)
 }
 
@@ -406,23 +499,32 @@ func (t *Transaction) buildClient() *http.Client {
 		transport = t.roundTripper(transport)
 	}
 
+	// A Total timeout replaces the client's default time limit. Streamed
+	// bodies (downloads) are only limited by their context and Timeouts.
+	timeout := defaultTimeout
+
+	if (t.timeouts.Total > 0) || (t.streamBody != nil) {
+		timeout = t.timeouts.Total
+	}
+
 	return &http.Client{
-		Timeout:       defaultTimeout,
+		Timeout:       timeout,
 		Transport:     transport,
 		CheckRedirect: t.checkRedirect,
 	}
 }
 
-// checkRedirect is the http.Client CheckRedirect policy. It caps the redirect
-// chain and re-applies the host allow-list to each redirect target, so an
-// allow-listed server cannot redirect the request to a host that is not on the
-// list. (The private-IP guard re-runs automatically, since it lives in the dialer.)
+// checkRedirect is the http.Client CheckRedirect policy. It applies the
+// transaction's RedirectPolicy and re-applies the host allow-list to each
+// redirect target, so an allow-listed server cannot redirect the request to a
+// host that is not on the list. (The private-IP guard re-runs automatically,
+// since it lives in the dialer.)
 func (t *Transaction) checkRedirect(request *http.Request, via []*http.Request) error {
 
 	const location = "remote.Transaction.checkRedirect"
 
-	if len(via) >= maxRedirects {
-		return derp.BadRequest(location, "Too many redirects")
+	if err := t.redirectPolicy.CheckRedirect(request, via); err != nil {
+		return err
 	}
 
 	if !t.hostIsAllowed(request.URL.Hostname()) {
@@ -463,6 +565,14 @@ func (t *Transaction) assembleRequest(ctx context.Context) (*http.Request, error
 			return nil, derp.Wrap(err, location, "Creating Request Body", t.body, derp.WithInternalError())
 		}
 
+		// Compress the body, if requested.
+		if (t.compression.RequestEncoding != "") && (len(body) > 0) {
+
+			if body, err = t.compression.compressRequestBody(body); err != nil {
+				return nil, derp.Wrap(err, location, "Compressing Request Body", derp.WithInternalError())
+			}
+		}
+
 		bodyReader = bytes.NewReader(body)
 	}
 
@@ -478,6 +588,21 @@ func (t *Transaction) assembleRequest(ctx context.Context) (*http.Request, error
 		result.Header.Add(key, value)
 	}
 
+	// Negotiate compression, unless the caller already has. Setting this header
+	// also stops the standard library from decompressing gzip responses itself,
+	// without the compression ratio limit.
+	if result.Header.Get("Accept-Encoding") == "" {
+		result.Header.Set("Accept-Encoding", t.compression.acceptEncoding())
+	}
+
+	if (bodyReader != nil) && (t.compression.RequestEncoding != "") && (result.ContentLength > 0) {
+		result.Header.Set("Content-Encoding", strings.ToLower(t.compression.RequestEncoding))
+	}
+
+	// Send the body no faster than the bandwidth limit allows, and report its progress.
+	t.throttleRequest(result)
+	t.watchUploadProgress(result)
+
 	return result, nil
 }
 
@@ -0,0 +1,313 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+// webFingerMaxResponseSize caps the size of WebFinger and host-meta documents,
+// which are small in practice.
+const webFingerMaxResponseSize = 256 << 10
+
+// JRD is a JSON Resource Descriptor (RFC 7033), the document returned by a
+// WebFinger server to describe a resource such as "acct:alice@example.com".
+type JRD struct {
+	Subject    string             `json:"subject,omitempty"`
+	Aliases    []string           `json:"aliases,omitempty"`
+	Properties map[string]*string `json:"properties,omitempty"`
+	Links      []JRDLink          `json:"links,omitempty"`
+}
+
+// JRDLink is a single link in a JSON Resource Descriptor.
+type JRDLink struct {
+	Rel        string             `json:"rel"`
+	Type       string             `json:"type,omitempty"`
+	Href       string             `json:"href,omitempty"`
+	Template   string             `json:"template,omitempty"`
+	Titles     map[string]string  `json:"titles,omitempty"`
+	Properties map[string]*string `json:"properties,omitempty"`
+}
+
+// Link returns the first link with the relation type and (if any are
+// provided) one of the media types, and whether one was found. For example,
+// Link("self", ContentTypeActivityPub) finds a fediverse account's actor.
+func (jrd JRD) Link(rel string, mediaTypes ...string) (JRDLink, bool) {
+
+	for _, link := range jrd.Links {
+
+		if link.Rel != rel {
+			continue
+		}
+
+		if len(mediaTypes) == 0 {
+			return link, true
+		}
+
+		linkType, _, _ := strings.Cut(link.Type, ";")
+
+		for _, mediaType := range mediaTypes {
+			if strings.EqualFold(strings.TrimSpace(linkType), mediaType) {
+				return link, true
+			}
+		}
+	}
+
+	return JRDLink{}, false
+}
+
+// WebFinger looks up a resource (RFC 7033), such as "acct:alice@example.com",
+// "alice@example.com", "@alice@example.com", or an https URL, and returns its
+// JSON Resource Descriptor. If the host does not answer the standard
+// /.well-known/webfinger request, the LRDD template from its
+// /.well-known/host-meta document is used instead. Requests are protected by
+// the usual SSRF guard, and responses are limited to 256KB. Options are
+// applied to every request.
+func WebFinger(ctx context.Context, resource string, options ...Option) (JRD, error) {
+
+	const location = "remote.WebFinger"
+
+	resource, host, err := webFingerResource(resource)
+
+	if err != nil {
+		return JRD{}, derp.Wrap(err, location, "Invalid resource", resource)
+	}
+
+	// Try the standard WebFinger endpoint first
+	endpoint := "https://" + host + "/.well-known/webfinger?resource=" + url.QueryEscape(resource)
+	result, err := webFingerFetch(ctx, endpoint, options)
+
+	if err == nil {
+		return result, nil
+	}
+
+	// Fall back to the (older) host-meta LRDD template
+	template, hostMetaErr := webFingerHostMeta(ctx, host, options)
+
+	if hostMetaErr != nil {
+		return JRD{}, derp.Wrap(err, location, "Unable to retrieve WebFinger document", resource)
+	}
+
+	endpoint = strings.ReplaceAll(template, "{uri}", url.QueryEscape(resource))
+	result, lrddErr := webFingerFetch(ctx, endpoint, options)
+
+	if lrddErr != nil {
+		return JRD{}, derp.Wrap(lrddErr, location, "Unable to retrieve WebFinger document from host-meta template", resource, endpoint)
+	}
+
+	return result, nil
+}
+
+// webFingerResource normalizes a resource into a URI, and returns the host to query.
+func webFingerResource(resource string) (string, string, error) {
+
+	const location = "remote.webFingerResource"
+
+	resource = strings.TrimSpace(resource)
+
+	// Bare account names, such as "alice@example.com" or "@alice@example.com"
+	if scheme, _, found := strings.Cut(resource, ":"); !found || strings.Contains(scheme, "@") {
+		resource = "acct:" + strings.TrimPrefix(resource, "@")
+	}
+
+	parsed, err := url.Parse(resource)
+
+	if err != nil {
+		return resource, "", derp.Wrap(err, location, "Unable to parse resource")
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+
+	case "acct":
+		_, host, found := strings.Cut(parsed.Opaque, "@")
+
+		if !found || (host == "") || strings.ContainsAny(host, "/?#@") {
+			return resource, "", derp.BadRequest(location, "Account must be in the form user@host", resource)
+		}
+
+		return resource, host, nil
+
+	case "http", "https":
+		if parsed.Host == "" {
+			return resource, "", derp.BadRequest(location, "URL must include a host", resource)
+		}
+
+		return resource, parsed.Host, nil
+	}
+
+	return resource, "", derp.BadRequest(location, "Unsupported resource scheme", resource)
+}
+
+// webFingerFetch retrieves a descriptor, which may be a JRD (JSON) or an XRD (XML).
+func webFingerFetch(ctx context.Context, endpoint string, options []Option) (JRD, error) {
+
+	const location = "remote.webFingerFetch"
+
+	var body []byte
+
+	err := Get(endpoint).
+		WithContext(ctx).
+		Accept(ContentTypeJSONResourceDescriptor, ContentTypeJSON, contentTypeXRD).
+		MaxResponseSize(webFingerMaxResponseSize).
+		With(options...).
+		Result(&body).
+		Send()
+
+	if err != nil {
+		return JRD{}, derp.Wrap(err, location, "Unable to retrieve descriptor", endpoint)
+	}
+
+	result, err := parseDescriptor(body)
+
+	if err != nil {
+		return JRD{}, derp.Wrap(err, location, "Unable to parse descriptor", endpoint)
+	}
+
+	return result, nil
+}
+
+// webFingerHostMeta returns the LRDD template from a host's host-meta document.
+func webFingerHostMeta(ctx context.Context, host string, options []Option) (string, error) {
+
+	const location = "remote.webFingerHostMeta"
+
+	hostMeta, err := webFingerFetch(ctx, "https://"+host+"/.well-known/host-meta", options)
+
+	if err != nil {
+		return "", derp.Wrap(err, location, "Unable to retrieve host-meta", host)
+	}
+
+	link, found := hostMeta.Link("lrdd")
+
+	if !found || !strings.Contains(link.Template, "{uri}") {
+		return "", derp.NotFound(location, "host-meta does not include an LRDD template", host)
+	}
+
+	return link.Template, nil
+}
+
+/******************************************
+ * XRD (Extensible Resource Descriptor)
+ ******************************************/
+
+// contentTypeXRD is the MIME type for XRD documents, used by host-meta.
+const contentTypeXRD = "application/xrd+xml"
+
+// xrd is the XML form of a resource descriptor, which is used by host-meta.
+type xrd struct {
+	Subject    string        `xml:"Subject"`
+	Aliases    []string      `xml:"Alias"`
+	Properties []xrdProperty `xml:"Property"`
+	Links      []xrdLink     `xml:"Link"`
+}
+
+type xrdProperty struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+	Nil   string `xml:"http://www.w3.org/2001/XMLSchema-instance nil,attr"`
+}
+
+type xrdLink struct {
+	Rel        string        `xml:"rel,attr"`
+	Type       string        `xml:"type,attr"`
+	Href       string        `xml:"href,attr"`
+	Template   string        `xml:"template,attr"`
+	Titles     []xrdTitle    `xml:"Title"`
+	Properties []xrdProperty `xml:"Property"`
+}
+
+type xrdTitle struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+// parseDescriptor decodes a JRD (JSON) or XRD (XML) document into a JRD.
+func parseDescriptor(body []byte) (JRD, error) {
+
+	const location = "remote.parseDescriptor"
+
+	body = bytes.TrimSpace(body)
+
+	// JSON Resource Descriptor
+	if !bytes.HasPrefix(body, []byte("<")) {
+
+		result := JRD{}
+
+		if err := json.Unmarshal(body, &result); err != nil {
+			return JRD{}, derp.Wrap(err, location, "Unable to decode JRD")
+		}
+
+		return result, nil
+	}
+
+	// Extensible Resource Descriptor
+	document := xrd{}
+
+	if err := xml.Unmarshal(body, &document); err != nil {
+		return JRD{}, derp.Wrap(err, location, "Unable to decode XRD")
+	}
+
+	result := JRD{
+		Subject:    document.Subject,
+		Aliases:    document.Aliases,
+		Properties: xrdProperties(document.Properties),
+	}
+
+	for _, link := range document.Links {
+
+		jrdLink := JRDLink{
+			Rel:        link.Rel,
+			Type:       link.Type,
+			Href:       link.Href,
+			Template:   link.Template,
+			Properties: xrdProperties(link.Properties),
+		}
+
+		if len(link.Titles) > 0 {
+			jrdLink.Titles = map[string]string{}
+
+			for _, title := range link.Titles {
+
+				lang := title.Lang
+
+				if lang == "" {
+					lang = "und"
+				}
+
+				jrdLink.Titles[lang] = title.Value
+			}
+		}
+
+		result.Links = append(result.Links, jrdLink)
+	}
+
+	return result, nil
+}
+
+// xrdProperties converts XRD properties into JRD properties (which may be null).
+func xrdProperties(properties []xrdProperty) map[string]*string {
+
+	if len(properties) == 0 {
+		return nil
+	}
+
+	result := make(map[string]*string, len(properties))
+
+	for _, property := range properties {
+
+		if property.Nil == "true" {
+			result[property.Type] = nil
+			continue
+		}
+
+		value := property.Value
+		result[property.Type] = &value
+	}
+
+	return result
+}
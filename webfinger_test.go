@@ -0,0 +1,179 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// handlerOption answers every request with the handler, without using the
+// network, so tests can use real (https) hostnames.
+func handlerOption(handler http.HandlerFunc) Option {
+	return Option{
+		ModifyRequest: func(_ *Transaction, request *http.Request) *http.Response {
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+			result := recorder.Result()
+			result.Request = request
+			return result
+		},
+	}
+}
+
+func TestWebFinger(t *testing.T) {
+
+	requests := []string{}
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+		requests = append(requests, r.URL.String())
+		require.Contains(t, r.Header.Get(Accept), ContentTypeJSONResourceDescriptor)
+
+		if r.URL.Path != "/.well-known/webfinger" || r.URL.Query().Get("resource") != "acct:alice@example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set(ContentType, ContentTypeJSONResourceDescriptor)
+		_, _ = w.Write([]byte(`{
+			"subject": "acct:alice@example.com",
+			"aliases": ["https://example.com/@alice"],
+			"properties": {"http://example.com/ns/role": "admin", "http://example.com/ns/empty": null},
+			"links": [
+				{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": "https://example.com/@alice"},
+				{"rel": "self", "type": "application/activity+json", "href": "https://example.com/users/alice"},
+				{"rel": "http://ostatus.org/schema/1.0/subscribe", "template": "https://example.com/authorize_interaction?uri={uri}"}
+			]
+		}`))
+	})
+
+	for _, resource := range []string{"acct:alice@example.com", "alice@example.com", "@alice@example.com"} {
+
+		requests = requests[:0]
+		jrd, err := WebFinger(context.Background(), resource, option)
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://example.com/.well-known/webfinger?resource=acct%3Aalice%40example.com"}, requests)
+		require.Equal(t, "acct:alice@example.com", jrd.Subject)
+		require.Equal(t, []string{"https://example.com/@alice"}, jrd.Aliases)
+		require.Equal(t, "admin", *jrd.Properties["http://example.com/ns/role"])
+		require.Nil(t, jrd.Properties["http://example.com/ns/empty"])
+
+		self, found := jrd.Link("self", ContentTypeActivityPub, ContentTypeJSONLD)
+		require.True(t, found)
+		require.Equal(t, "https://example.com/users/alice", self.Href)
+
+		subscribe, found := jrd.Link("http://ostatus.org/schema/1.0/subscribe")
+		require.True(t, found)
+		require.Equal(t, "https://example.com/authorize_interaction?uri={uri}", subscribe.Template)
+
+		_, found = jrd.Link("self", ContentTypeHTML)
+		require.False(t, found)
+	}
+}
+
+func TestWebFinger_HostMeta(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, r *http.Request) {
+
+		switch r.URL.Path {
+
+		case "/.well-known/host-meta":
+			w.Header().Set(ContentType, contentTypeXRD)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+				<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0">
+					<Link rel="lrdd" type="application/xrd+xml" template="https://social.example.com/lookup?q={uri}"/>
+				</XRD>`))
+
+		case "/lookup":
+			require.Equal(t, "social.example.com", r.URL.Host)
+			require.Equal(t, "acct:bob@example.com", r.URL.Query().Get("q"))
+
+			w.Header().Set(ContentType, contentTypeXRD)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+				<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+					<Subject>acct:bob@example.com</Subject>
+					<Alias>https://social.example.com/bob</Alias>
+					<Property type="http://example.com/ns/missing" xsi:nil="true"/>
+					<Link rel="self" type="application/activity+json" href="https://social.example.com/users/bob">
+						<Title xml:lang="en">Bob</Title>
+						<Title>Bob (default)</Title>
+					</Link>
+				</XRD>`))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	jrd, err := WebFinger(context.Background(), "bob@example.com", option)
+	require.NoError(t, err)
+	require.Equal(t, "acct:bob@example.com", jrd.Subject)
+	require.Equal(t, []string{"https://social.example.com/bob"}, jrd.Aliases)
+	require.Contains(t, jrd.Properties, "http://example.com/ns/missing")
+	require.Nil(t, jrd.Properties["http://example.com/ns/missing"])
+
+	self, found := jrd.Link("self", ContentTypeActivityPub)
+	require.True(t, found)
+	require.Equal(t, "https://social.example.com/users/bob", self.Href)
+	require.Equal(t, map[string]string{"en": "Bob", "und": "Bob (default)"}, self.Titles)
+}
+
+func TestWebFinger_NotFound(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := WebFinger(context.Background(), "nobody@example.com", option)
+	require.Error(t, err)
+}
+
+func TestWebFinger_SizeLimit(t *testing.T) {
+
+	option := handlerOption(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(ContentType, ContentTypeJSONResourceDescriptor)
+		_, _ = w.Write([]byte(`{"subject":"` + strings.Repeat("x", webFingerMaxResponseSize) + `"}`))
+	})
+
+	_, err := WebFinger(context.Background(), "alice@example.com", option)
+	require.Error(t, err)
+}
+
+func TestWebFinger_PrivateAddress(t *testing.T) {
+
+	// The SSRF guard applies to WebFinger hosts
+	_, err := WebFinger(context.Background(), "alice@127.0.0.1")
+	require.Error(t, err)
+}
+
+func TestWebFingerResource(t *testing.T) {
+
+	tests := []struct {
+		resource string
+		uri      string
+		host     string
+	}{
+		{"acct:alice@example.com", "acct:alice@example.com", "example.com"},
+		{"alice@example.com", "acct:alice@example.com", "example.com"},
+		{"@alice@example.com", "acct:alice@example.com", "example.com"},
+		{" alice@example.com:8080 ", "acct:alice@example.com:8080", "example.com:8080"},
+		{"https://example.com/users/alice", "https://example.com/users/alice", "example.com"},
+	}
+
+	for _, test := range tests {
+		uri, host, err := webFingerResource(test.resource)
+		require.NoError(t, err, test.resource)
+		require.Equal(t, test.uri, uri)
+		require.Equal(t, test.host, host)
+	}
+
+	for _, resource := range []string{"", "alice", "acct:alice", "acct:alice@", "mailto:alice@example.com", "https:///path"} {
+		_, _, err := webFingerResource(resource)
+		require.Error(t, err, resource)
+	}
+}